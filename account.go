@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// userAccount returns true if -organization is a personal account rather
+// than an organization.
+func userAccount() bool {
+	return *ownerTypeFl == "user"
+}
+
+// searchOwner returns the search qualifier restricting results to the
+// organization or the personal account.
+func searchOwner() string {
+	if userAccount() {
+		return "user:" + *ghOrgFl
+	}
+	return "org:" + *ghOrgFl
+}
+
+// explicitRepos returns repositories given by -repos.
+func explicitRepos() []string {
+	var repos []string
+	for _, name := range strings.Split(*reposFl, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			repos = append(repos, name)
+		}
+	}
+	return repos
+}
+
+// collaborators returns members of a personal account given by
+// -collaborators.
+func collaborators() []User {
+	var users []User
+	for _, login := range strings.Split(*collaboratorsFl, ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			users = append(users, User{Login: login})
+		}
+	}
+	return users
+}
+
+// ownerRepositories returns repositories of the organization or the
+// personal account, or those given by -repos, without skipped
+// repositories.
+func ownerRepositories() ([]Repository, error) {
+	var repos []Repository
+	if names := explicitRepos(); len(names) > 0 {
+		for _, name := range names {
+			var repo Repository
+			if err := githubGet(fmt.Sprintf("%s/repos/%s/%s", *ghAPIFl, *ghOrgFl, name), &repo); err != nil {
+				return nil, fmt.Errorf("cannot get repository %s: %s", name, err)
+			}
+			repos = append(repos, repo)
+		}
+		return repos, nil
+	}
+
+	url := fmt.Sprintf("%s/orgs/%s/repos?per_page=100", *ghAPIFl, *ghOrgFl)
+	if userAccount() {
+		// lists private repositories too, unlike /users/<login>/repos
+		url = *ghAPIFl + "/user/repos?affiliation=owner,collaborator&per_page=100"
+	}
+	err := githubGetPages(url, func(raw json.RawMessage) error {
+		var page []Repository
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		for i := range page {
+			if page[i].Owner != nil && !strings.EqualFold(page[i].Owner.Login, *ghOrgFl) {
+				continue
+			}
+			if !skipRepository(&page[i]) {
+				repos = append(repos, page[i])
+			}
+		}
+		return nil
+	})
+	return repos, err
+}
+
+// repositoryIssues returns issues and pull requests matching given query
+// parameters of repositories of the personal account or given by -repos,
+// for which the organization issues feed cannot be used.
+func repositoryIssues(query string) ([]Issue, error) {
+	repos, err := ownerRepositories()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list repositories: %s", err)
+	}
+	var all []Issue
+	for i := range repos {
+		repo := &repos[i]
+		if skipRepository(repo) {
+			continue
+		}
+		url := fmt.Sprintf("%s/repos/%s/%s/issues?%s&per_page=100", *ghAPIFl, *ghOrgFl, repo.Name, query)
+		var issues []Issue
+		if err := githubGetAll(url, &issues); err != nil {
+			return nil, fmt.Errorf("failed to load issues of %s: %s", repo.Name, err)
+		}
+		for j := range issues {
+			issues[j].Repository = repo
+		}
+		all = append(all, issues...)
+	}
+	return all, nil
+}
+
+// errOrganizationRequired is returned by features that only work with
+// organizations.
+var errOrganizationRequired = errors.New("not supported for personal accounts")
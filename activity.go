@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timelineEvent is an event of the issue timeline. Depending on the event
+// type, different fields are set.
+type timelineEvent struct {
+	Event       string     `json:"event"`
+	Actor       *User      `json:"actor"`
+	User        *User      `json:"user"`
+	CreatedAt   *time.Time `json:"created_at"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+	Author      *struct {
+		Date time.Time `json:"date"`
+	} `json:"author"`
+}
+
+// actor returns user that caused the event, nil if not known.
+func (e *timelineEvent) actor() *User {
+	if e.Actor != nil {
+		return e.Actor
+	}
+	return e.User
+}
+
+// time returns time when event happened.
+func (e *timelineEvent) time() time.Time {
+	switch {
+	case e.CreatedAt != nil:
+		return *e.CreatedAt
+	case e.SubmittedAt != nil:
+		return *e.SubmittedAt
+	case e.Author != nil:
+		return e.Author.Date
+	}
+	return time.Time{}
+}
+
+// botLoginRetry is the time after which resolving the login of the bot is
+// tried again after it failed.
+const botLoginRetry = 10 * time.Minute
+
+// resolvedLogin is the login the bot acts as with some credentials.
+type resolvedLogin struct {
+	login string
+	// failedAt is the time resolving the login failed, zero if it did
+	// not.
+	failedAt time.Time
+}
+
+var (
+	botLoginsMu sync.Mutex
+	// botLogins maps credentials, identified by githubCredentials, to the
+	// logins they act as, so that tenants do not share them.
+	botLogins = map[string]resolvedLogin{}
+)
+
+// githubCredentials returns key identifying the configured github
+// credentials.
+func githubCredentials() string {
+	if appEnabled() {
+		return "app|" + appInstallation()
+	}
+	sum := sha256.Sum256([]byte(*ghUserFl + "|" + *ghPassFl + "|" + *ghAuthKey))
+	return *ghAPIFl + "|" + hex.EncodeToString(sum[:])
+}
+
+// botLogin returns github login of the bot: the owner of the token, or
+// "<slug>[bot]" of the github App. It is resolved once per credentials,
+// empty if it cannot be resolved or the provider is not github.
+func botLogin() string {
+	if githubOnly() != nil {
+		return ""
+	}
+	botLoginsMu.Lock()
+	defer botLoginsMu.Unlock()
+
+	key := githubCredentials()
+	if r, ok := botLogins[key]; ok && (r.failedAt.IsZero() || time.Since(r.failedAt) < botLoginRetry) {
+		return r.login
+	}
+	var r resolvedLogin
+	if appEnabled() {
+		slug, err := appSlug()
+		if err == nil {
+			r.login = slug + "[bot]"
+		} else {
+			log.Printf("cannot get github App: %s", err)
+			r.failedAt = time.Now()
+		}
+	} else {
+		var user User
+		if err := githubGet(*ghAPIFl+"/user", &user); err == nil {
+			r.login = user.Login
+		} else {
+			log.Printf("cannot get github user of the token: %s", err)
+			r.failedAt = time.Now()
+		}
+	}
+	botLogins[key] = r
+	return r.login
+}
+
+// isBot returns true if given login is the login of the bot itself.
+func isBot(login string) bool {
+	bot := botLogin()
+	return bot != "" && strings.EqualFold(bot, login)
+}
+
+// ignoredActivity returns true if activity of given user should not be
+// counted when computing staleness. Comments, assignments and labels of the
+// bot itself never count.
+func ignoredActivity(u *User) bool {
+	if u == nil {
+		return false
+	}
+	if isBot(u.Login) {
+		return true
+	}
+	for _, login := range strings.Split(*ignoreActivityFromFl, ",") {
+		if login != "" && strings.EqualFold(login, u.Login) {
+			return true
+		}
+	}
+	for _, t := range strings.Split(*ignoreActivityTypesFl, ",") {
+		if t != "" && strings.EqualFold(t, u.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// timeline return all timeline events of given issue.
+func timeline(issue *Issue) ([]timelineEvent, error) {
+	if err := githubOnly(); err != nil {
+		return nil, err
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var events []timelineEvent
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/timeline?per_page=100",
+		*ghAPIFl, *ghOrgFl, repo, issue.Number)
+	if err := githubGetAll(url, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+var (
+	activityMu    sync.Mutex
+	activityCache = map[int64]time.Time{}
+)
+
+// lastActivity returns time of the most recent activity on given issue that
+// was not caused by ignored users. Creation time is returned if there was no
+// such activity. Globally cached.
+func lastActivity(issue *Issue) (time.Time, error) {
+	activityMu.Lock()
+	last, ok := activityCache[issue.ID]
+	activityMu.Unlock()
+	if ok {
+		return last, nil
+	}
+
+	events, err := timeline(issue)
+	if err != nil {
+		return time.Time{}, err
+	}
+	last = issue.CreatedAt
+	for _, e := range events {
+		if ignoredActivity(e.actor()) {
+			continue
+		}
+		if t := e.time(); t.After(last) {
+			last = t
+		}
+	}
+
+	activityMu.Lock()
+	activityCache[issue.ID] = last
+	activityMu.Unlock()
+	return last, nil
+}
+
+// staleSince returns time from which staleness of given issue is measured.
+// Holidays of -holiday-region are not counted.
+func staleSince(issue *Issue) time.Time {
+	if *staleFromFl != "activity" {
+		return skipHolidays(issue.CreatedAt, time.Now())
+	}
+	last, err := lastActivity(issue)
+	if err != nil {
+		log.Printf("cannot get last activity of #%d: %s", issue.Number, err)
+		return skipHolidays(issue.CreatedAt, time.Now())
+	}
+	return skipHolidays(last, time.Now())
+}
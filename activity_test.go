@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIgnoredActivity(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" || r.Header.Get("Authorization") != "token secret" {
+			http.NotFound(w, r)
+			return
+		}
+		calls++
+		w.Write([]byte(`{"login": "acme-bot", "type": "User"}`))
+	}))
+	defer srv.Close()
+	defer func(api, key, from, types, provider string) {
+		*ghAPIFl, *ghAuthKey, *ignoreActivityFromFl, *ignoreActivityTypesFl, *providerFl = api, key, from, types, provider
+	}(*ghAPIFl, *ghAuthKey, *ignoreActivityFromFl, *ignoreActivityTypesFl, *providerFl)
+	*ghAPIFl, *ghAuthKey, *ignoreActivityFromFl, *ignoreActivityTypesFl, *providerFl = srv.URL, "secret", "ci-helper", "Bot", "github"
+
+	tests := []struct {
+		user *User
+		want bool
+	}{
+		{user: nil, want: false},
+		{user: &User{Login: "alice", Type: "User"}, want: false},
+		{user: &User{Login: "acme-bot", Type: "User"}, want: true},
+		{user: &User{Login: "ACME-BOT", Type: "User"}, want: true},
+		{user: &User{Login: "ci-helper", Type: "User"}, want: true},
+		{user: &User{Login: "dependabot[bot]", Type: "Bot"}, want: true},
+	}
+	for _, tt := range tests {
+		if got := ignoredActivity(tt.user); got != tt.want {
+			t.Errorf("ignoredActivity(%+v) = %v, want %v", tt.user, got, tt.want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("login of the token resolved %d times, want once", calls)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// apiAuthenticated returns true if the request carries the API token as a
+// bearer token. Without configured token the API is disabled.
+func apiAuthenticated(r *http.Request) bool {
+	apiToken := currentAuth().apiToken
+	if apiToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) == 1
+}
+
+// readHandler wraps handler of a read-only endpoint showing pull requests,
+// the dashboard, /lifecycle and /metrics. Unless -public-dashboard is set,
+// it requires the API token, as a bearer token or as the password of basic
+// authentication, so that browsers can prompt for it.
+func readHandler(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := currentAuth()
+		if auth.publicDashboard || apiAuthenticated(r) {
+			h(w, r)
+			return
+		}
+		if _, password, ok := r.BasicAuth(); ok && auth.apiToken != "" && subtle.ConstantTimeCompare([]byte(password), []byte(auth.apiToken)) == 1 {
+			h(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="github-stale-pr-bot"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}
+}
+
+// apiHandler wraps handler of an API endpoint, checking authentication and
+// request method.
+func apiHandler(method string, h func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !apiAuthenticated(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		if r.Method != method {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+// writeJSON writes v as JSON response with given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("cannot write response: %s", err)
+	}
+}
+
+// handleAPIStalePRs serves stale pull requests of the last scan, optionally
+// filtered by repo and assignee query parameters.
+func handleAPIStalePRs(w http.ResponseWriter, r *http.Request) {
+	v := currentView()
+	repo := r.URL.Query().Get("repo")
+	assignee := r.URL.Query().Get("assignee")
+	prs := []StalePR{}
+	for _, pr := range v.PRs {
+		if repo != "" && pr.Repo != repo {
+			continue
+		}
+		if assignee != "" && pr.Assignee != assignee {
+			continue
+		}
+		prs = append(prs, pr)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scanned_at":    v.At,
+		"pull_requests": prs,
+	})
+}
+
+// handleAPIAssignments serves stale pull requests of the last scan grouped
+// by assignee.
+func handleAPIAssignments(w http.ResponseWriter, r *http.Request) {
+	type assignment struct {
+		Login        string   `json:"login"`
+		Count        int      `json:"count"`
+		PullRequests []string `json:"pull_requests"`
+	}
+	v := currentView()
+	byLogin := map[string]*assignment{}
+	for login := range v.assignmentCounts() {
+		byLogin[login] = &assignment{Login: login, PullRequests: []string{}}
+	}
+	for _, pr := range v.PRs {
+		a, ok := byLogin[pr.Assignee]
+		if !ok {
+			continue
+		}
+		a.Count++
+		a.PullRequests = append(a.PullRequests, pr.URL)
+	}
+	list := []*assignment{}
+	for _, a := range byLogin {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Login < list[j].Login
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scanned_at":  v.At,
+		"assignments": list,
+	})
+}
+
+// handleAPIScan triggers a scan. The scan runs in the background, unless
+// wait=true is given, in which case response is sent when it finishes.
+func handleAPIScan(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("wait") == "true" {
+		if err := runScan(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "finished"})
+		return
+	}
+	goTracked(func() {
+		if err := runScan(); err != nil {
+			log.Printf("scan failed: %s", err)
+		}
+	})
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
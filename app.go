@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// appJWT returns JSON Web Token authenticating as the github App.
+func appJWT() (string, error) {
+	b, err := ioutil.ReadFile(*appKeyFl)
+	if err != nil {
+		return "", fmt.Errorf("cannot read private key: %s", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return "", errors.New("private key is not PEM encoded")
+	}
+	var key *rsa.PrivateKey
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = k
+	} else if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		var ok bool
+		if key, ok = k.(*rsa.PrivateKey); !ok {
+			return "", errors.New("private key is not RSA key")
+		}
+	} else {
+		return "", fmt.Errorf("cannot parse private key: %s", err)
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		// allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": *appIDFl,
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("cannot sign token: %s", err)
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// appToken is an access token of a github App installation.
+type appToken struct {
+	token   string
+	expires time.Time
+	// permissions are permissions of the token, for example
+	// "checks": "write".
+	permissions map[string]string
+}
+
+var (
+	appTokensMu sync.Mutex
+	// appTokens maps installations, identified by appInstallation, to
+	// their tokens, so that tenants do not share them.
+	appTokens = map[string]*appToken{}
+)
+
+// appInstallation returns key identifying the configured installation.
+func appInstallation() string {
+	return *ghAPIFl + "|" + *appIDFl + "|" + *appInstallationFl
+}
+
+// installationToken returns access token of the github App installation.
+// Token is cached until shortly before it expires.
+func installationToken() (string, error) {
+	appTokensMu.Lock()
+	defer appTokensMu.Unlock()
+
+	if t := appTokens[appInstallation()]; t != nil && time.Now().Add(time.Minute).Before(t.expires) {
+		return t.token, nil
+	}
+	jwt, err := appJWT()
+	if err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", *ghAPIFl, *appInstallationFl)
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create POST request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var result struct {
+		Token       string            `json:"token"`
+		ExpiresAt   time.Time         `json:"expires_at"`
+		Permissions map[string]string `json:"permissions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("cannot decode response: %s", err)
+	}
+	appTokens[appInstallation()] = &appToken{token: result.Token, expires: result.ExpiresAt, permissions: result.Permissions}
+	return result.Token, nil
+}
+
+// installationPermissions returns permissions of the github App
+// installation.
+func installationPermissions() (map[string]string, error) {
+	if _, err := installationToken(); err != nil {
+		return nil, err
+	}
+	appTokensMu.Lock()
+	defer appTokensMu.Unlock()
+	return appTokens[appInstallation()].permissions, nil
+}
+
+// appSlug returns slug of the github App, which its installations act as
+// "<slug>[bot]".
+func appSlug() (string, error) {
+	jwt, err := appJWT()
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("GET", *ghAPIFl+"/app", nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create GET request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var app struct {
+		Slug string `json:"slug"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&app); err != nil {
+		return "", fmt.Errorf("cannot decode response: %s", err)
+	}
+	return app.Slug, nil
+}
+
+// appEnabled returns true if the bot authenticates as a github App.
+func appEnabled() bool {
+	return *appIDFl != "" && *appInstallationFl != "" && *appKeyFl != ""
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+var (
+	requiredMu    sync.Mutex
+	requiredCache = map[string]int{}
+)
+
+// requiredApprovals returns number of approving reviews required to merge
+// pull requests into given branch of given repository, as the maximum of
+// branch protection and repository rulesets.
+func requiredApprovals(repo, branch string) (int, error) {
+	key := repo + "/" + branch
+	requiredMu.Lock()
+	n, ok := requiredCache[key]
+	requiredMu.Unlock()
+	if ok {
+		return n, nil
+	}
+
+	base := fmt.Sprintf("%s/repos/%s/%s", *ghAPIFl, *ghOrgFl, repo)
+	var rules []struct {
+		Type       string `json:"type"`
+		Parameters struct {
+			RequiredApprovingReviewCount int `json:"required_approving_review_count"`
+		} `json:"parameters"`
+	}
+	if err := githubGetAll(base+"/rules/branches/"+url.PathEscape(branch)+"?per_page=100", &rules); err != nil {
+		return 0, fmt.Errorf("cannot list rules: %s", err)
+	}
+	for _, r := range rules {
+		if r.Type == "pull_request" && r.Parameters.RequiredApprovingReviewCount > n {
+			n = r.Parameters.RequiredApprovingReviewCount
+		}
+	}
+
+	req, err := http.NewRequest("GET", base+"/branches/"+url.PathEscape(branch)+"/protection/required_pull_request_reviews", nil)
+	if err != nil {
+		return 0, fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var protection struct {
+			RequiredApprovingReviewCount int `json:"required_approving_review_count"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&protection); err != nil {
+			return 0, fmt.Errorf("cannot decode response: %s", err)
+		}
+		if protection.RequiredApprovingReviewCount > n {
+			n = protection.RequiredApprovingReviewCount
+		}
+	case http.StatusNotFound, http.StatusForbidden:
+		// branch is not protected, or the token cannot read protection
+		// settings which requires admin access
+	default:
+		return 0, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+
+	requiredMu.Lock()
+	requiredCache[key] = n
+	requiredMu.Unlock()
+	return n, nil
+}
+
+// latestReviews returns state of the latest review of every reviewer of
+// given pull request, by reviewer login. Comments are ignored.
+func latestReviews(issue *Issue) (map[string]string, error) {
+	reviewsMu.Lock()
+	latest, ok := reviewsCache[issue.ID]
+	reviewsMu.Unlock()
+	if ok {
+		return latest, nil
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var reviews []struct {
+		User  *User  `json:"user"`
+		State string `json:"state"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100", *ghAPIFl, *ghOrgFl, repo, issue.Number)
+	if err := githubGetAll(url, &reviews); err != nil {
+		return nil, err
+	}
+	latest = map[string]string{}
+	for _, r := range reviews {
+		// comments do not change approval or requested changes
+		if r.User == nil || r.State == "COMMENTED" {
+			continue
+		}
+		latest[r.User.Login] = r.State
+	}
+	reviewsMu.Lock()
+	reviewsCache[issue.ID] = latest
+	reviewsMu.Unlock()
+	return latest, nil
+}
+
+// approvalCount returns number of reviewers whose latest review of given
+// pull request approves it.
+func approvalCount(issue *Issue) (int, error) {
+	latest, err := latestReviews(issue)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, state := range latest {
+		if state == "APPROVED" {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// errNoRequiredApprovals is returned by missingApprovals for pull requests
+// whose base branch requires no approvals, so that they are not mistaken
+// for approved ones.
+var errNoRequiredApprovals = errors.New("no approvals required")
+
+// missingApprovals returns number of approvals given pull request needs
+// before it can be merged, errNoRequiredApprovals if the base branch does
+// not require any.
+func missingApprovals(issue *Issue) (int, error) {
+	if p, ok := forge.(approvalsProvider); ok {
+		return p.MissingApprovals(issue)
+	}
+	if err := githubOnly(); err != nil {
+		return 0, err
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return 0, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	branch, err := baseBranch(issue)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get base branch: %s", err)
+	}
+	required, err := requiredApprovals(repo, branch)
+	if err != nil {
+		return 0, err
+	}
+	if required == 0 {
+		return 0, errNoRequiredApprovals
+	}
+	approved, err := approvalCount(issue)
+	if err != nil {
+		return 0, fmt.Errorf("cannot count approvals: %s", err)
+	}
+	if approved >= required {
+		return 0, nil
+	}
+	return required - approved, nil
+}
+
+// approvalsText returns human readable number of missing approvals of given
+// pull request.
+func approvalsText(issue *Issue, missing int) string {
+	if missing == 1 {
+		return tr(issue, "approvals_one")
+	}
+	return tr(issue, "approvals_many", missing)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReviewState(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/repos/acme/api/pulls/1/reviews":
+			w.Write([]byte(`[
+				{"user": {"login": "alice"}, "state": "CHANGES_REQUESTED"},
+				{"user": {"login": "alice"}, "state": "APPROVED"},
+				{"user": {"login": "bob"}, "state": "COMMENTED"}
+			]`))
+		case "/repos/acme/api/pulls/2/reviews":
+			w.Write([]byte(`[
+				{"user": {"login": "alice"}, "state": "APPROVED"},
+				{"user": {"login": "bob"}, "state": "CHANGES_REQUESTED"}
+			]`))
+		case "/repos/acme/api/pulls/3/reviews":
+			w.Write([]byte(`[{"user": {"login": "bob"}, "state": "COMMENTED"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(api, org, provider string) {
+		*ghAPIFl, *ghOrgFl, *providerFl = api, org, provider
+	}(*ghAPIFl, *ghOrgFl, *providerFl)
+	defer func() { reviewsCache = map[int64]map[string]string{} }()
+	*ghAPIFl, *ghOrgFl, *providerFl = srv.URL, "acme", "github"
+	reviewsCache = map[int64]map[string]string{}
+
+	tests := []struct {
+		number int64
+		want   string
+	}{
+		// later approval replaces requested changes of the same reviewer
+		{number: 1, want: "APPROVED"},
+		{number: 2, want: "CHANGES_REQUESTED"},
+		{number: 3, want: ""},
+	}
+	for _, tt := range tests {
+		issue := &Issue{ID: tt.number, Number: tt.number, Repo: "api"}
+		// counting approvals fetches the reviews the export reuses
+		if _, err := approvalCount(issue); err != nil {
+			t.Fatalf("#%d approvalCount() error = %v", tt.number, err)
+		}
+		got, err := reviewState(issue)
+		if err != nil {
+			t.Fatalf("#%d reviewState() error = %v", tt.number, err)
+		}
+		if got != tt.want {
+			t.Errorf("#%d reviewState() = %q, want %q", tt.number, got, tt.want)
+		}
+	}
+	if calls != len(tests) {
+		t.Errorf("reviews fetched %d times, want %d", calls, len(tests))
+	}
+}
+
+func TestMissingApprovals(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/api/pulls/1", "/repos/acme/api/pulls/2":
+			w.Write([]byte(`{"base": {"ref": "main"}}`))
+		case "/repos/acme/api/pulls/3":
+			w.Write([]byte(`{"base": {"ref": "dev"}}`))
+		case "/repos/acme/api/pulls/4":
+			w.Write([]byte(`{"base": {"ref": "release"}}`))
+		case "/repos/acme/api/pulls/1/reviews":
+			w.Write([]byte(`[{"user": {"login": "bob"}, "state": "APPROVED"}, {"user": {"login": "carol"}, "state": "COMMENTED"}]`))
+		case "/repos/acme/api/pulls/2/reviews":
+			w.Write([]byte(`[{"user": {"login": "bob"}, "state": "APPROVED"}, {"user": {"login": "carol"}, "state": "APPROVED"}]`))
+		case "/repos/acme/api/rules/branches/main":
+			w.Write([]byte(`[{"type": "pull_request", "parameters": {"required_approving_review_count": 1}}]`))
+		case "/repos/acme/api/branches/main/protection/required_pull_request_reviews":
+			w.Write([]byte(`{"required_approving_review_count": 2}`))
+		case "/repos/acme/api/rules/branches/dev", "/repos/acme/api/rules/branches/release":
+			w.Write([]byte(`[]`))
+		case "/repos/acme/api/branches/release/protection/required_pull_request_reviews":
+			// reading protection needs admin access
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(api, org, provider string) {
+		*ghAPIFl, *ghOrgFl, *providerFl = api, org, provider
+	}(*ghAPIFl, *ghOrgFl, *providerFl)
+	defer func() {
+		pullsCache, reviewsCache, requiredCache = map[int64]*Pull{}, map[int64]map[string]string{}, map[string]int{}
+	}()
+	*ghAPIFl, *ghOrgFl, *providerFl = srv.URL, "acme", "github"
+	pullsCache, reviewsCache, requiredCache = map[int64]*Pull{}, map[int64]map[string]string{}, map[string]int{}
+
+	tests := []struct {
+		number int64
+		want   int
+		err    error
+	}{
+		// branch protection requires more than the ruleset
+		{number: 1, want: 1},
+		{number: 2, want: 0},
+		{number: 3, err: errNoRequiredApprovals},
+		{number: 4, err: errNoRequiredApprovals},
+	}
+	for _, tt := range tests {
+		got, err := missingApprovals(&Issue{ID: tt.number, Number: tt.number, Repo: "api"})
+		if err != tt.err {
+			t.Errorf("#%d missingApprovals() error = %v, want %v", tt.number, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("#%d missingApprovals() = %d, want %d", tt.number, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+var (
+	assignmentsMu sync.Mutex
+	// assignedPRs maps member login to set of open pull request IDs that
+	// member is assigned to.
+	assignedPRs = map[string]map[int64]bool{}
+	// requestsLoaded tells for which members pending review requests were
+	// already fetched.
+	requestsLoaded = map[string]bool{}
+	// allRequestsLoaded tells if pending review requests of all members
+	// were fetched together with the pull requests.
+	allRequestsLoaded = false
+)
+
+// loadAssignments fills assignments cache using given list of open issues.
+func loadAssignments(issues []Issue) {
+	assignmentsMu.Lock()
+	defer assignmentsMu.Unlock()
+
+	for _, issue := range issues {
+		if !issue.isPullRequest() || issue.Assignee == nil {
+			continue
+		}
+		addAssignment(issue.Assignee.Login, issue.ID)
+	}
+}
+
+// loadReviewRequests fills assignments cache using given pending review
+// requests, mapping member logins to pull request IDs. It must be given
+// review requests of all open pull requests.
+func loadReviewRequests(requests map[string][]int64) {
+	assignmentsMu.Lock()
+	defer assignmentsMu.Unlock()
+
+	for login, ids := range requests {
+		for _, id := range ids {
+			addAssignment(login, id)
+		}
+	}
+	allRequestsLoaded = true
+}
+
+func addAssignment(login string, issueID int64) {
+	if assignedPRs[login] == nil {
+		assignedPRs[login] = map[int64]bool{}
+	}
+	assignedPRs[login][issueID] = true
+}
+
+// assignmentLimit returns maximum number of open assignments of member with
+// given login, -max-assignments-per-user scaled by the member's capacity.
+func assignmentLimit(login string) int {
+	limit := int(math.Ceil(float64(*maxAssignmentsFl) * memberCapacity(login)))
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
+
+// loadMemberRequests adds pull requests given member is requested to review
+// to assignments cache, unless they are known already. The search runs
+// without holding assignmentsMu, so that other workers do not wait for it.
+func loadMemberRequests(login string) error {
+	assignmentsMu.Lock()
+	loaded := requestsLoaded[login] || allRequestsLoaded || githubOnly() != nil
+	assignmentsMu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	q := fmt.Sprintf("%s is:pr is:open review-requested:%s", searchOwner(), login)
+	requested, err := searchIssues(q)
+	if err != nil {
+		return fmt.Errorf("cannot search review requests: %s", err)
+	}
+	assignmentsMu.Lock()
+	defer assignmentsMu.Unlock()
+	for _, issue := range requested {
+		addAssignment(login, issue.ID)
+	}
+	requestsLoaded[login] = true
+	return nil
+}
+
+// reservations counts reserved assignment slots, giving them unique IDs.
+var reservations int64
+
+// reserveAssignment returns true if given member did not reach the maximum
+// number of open assignments yet. If so, assignment slot is reserved for
+// the caller, who must release it with releaseAssignment if the assignment
+// fails.
+func reserveAssignment(login string) (bool, error) {
+	if *maxAssignmentsFl <= 0 {
+		return true, nil
+	}
+	if err := loadMemberRequests(login); err != nil {
+		return false, err
+	}
+
+	assignmentsMu.Lock()
+	defer assignmentsMu.Unlock()
+	if len(assignedPRs[login]) >= assignmentLimit(login) {
+		return false, nil
+	}
+	// reserved slot is not bound to any real pull request, so use
+	// negative, unique identifiers
+	reservations++
+	addAssignment(login, -reservations)
+	return true, nil
+}
+
+// releaseAssignment releases an assignment slot reserved for given member
+// by reserveAssignment, after the assignment failed.
+func releaseAssignment(login string) {
+	if *maxAssignmentsFl <= 0 {
+		return
+	}
+	assignmentsMu.Lock()
+	defer assignmentsMu.Unlock()
+	for id := range assignedPRs[login] {
+		if id < 0 {
+			delete(assignedPRs[login], id)
+			return
+		}
+	}
+}
+
+// searchIssues return all issues matching given search query.
+func searchIssues(query string) ([]Issue, error) {
+	if err := githubOnly(); err != nil {
+		return nil, err
+	}
+	var issues []Issue
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/search/issues?q=%s&per_page=100&page=%d",
+			*ghAPIFl, url.QueryEscape(query), page)
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create GET request: %s", err)
+		}
+		addAuthentication(req)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch response: %s", err)
+		}
+		var result struct {
+			TotalCount int     `json:"total_count"`
+			Items      []Issue `json:"items"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode response: %s", err)
+		}
+		issues = append(issues, result.Items...)
+		if len(result.Items) == 0 || len(issues) >= result.TotalCount {
+			return issues, nil
+		}
+	}
+}
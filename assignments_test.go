@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReserveAssignment(t *testing.T) {
+	defer func(max int, cfg Config) { *maxAssignmentsFl, config = max, cfg }(*maxAssignmentsFl, config)
+	defer func() {
+		assignedPRs, requestsLoaded, allRequestsLoaded = map[string]map[int64]bool{}, map[string]bool{}, false
+	}()
+	*maxAssignmentsFl = 2
+	config = Config{Members: map[string]Member{"bob": {Capacity: 0.5}, "carol": {Capacity: 2}}}
+	assignedPRs = map[string]map[int64]bool{}
+	loadAssignments([]Issue{
+		{ID: 1, PullRequest: &PullRequest{}, Assignee: &User{Login: "alice"}},
+	})
+	loadReviewRequests(map[string][]int64{"alice": {2}, "carol": {3, 4}})
+
+	tests := []struct {
+		login string
+		want  bool
+	}{
+		// alice is assigned one and requested to review another
+		{login: "alice", want: false},
+		// half capacity rounds up to one assignment
+		{login: "bob", want: true},
+		{login: "bob", want: false},
+		// double capacity allows four
+		{login: "carol", want: true},
+		{login: "carol", want: true},
+		{login: "carol", want: false},
+		{login: "dave", want: true},
+	}
+	for i, tt := range tests {
+		got, err := reserveAssignment(tt.login)
+		if err != nil {
+			t.Fatalf("#%d reserveAssignment(%s) error = %v", i, tt.login, err)
+		}
+		if got != tt.want {
+			t.Errorf("#%d reserveAssignment(%s) = %v, want %v", i, tt.login, got, tt.want)
+		}
+	}
+}
+
+func TestReleaseAssignment(t *testing.T) {
+	defer func(max int) { *maxAssignmentsFl = max }(*maxAssignmentsFl)
+	defer func() {
+		assignedPRs, requestsLoaded, allRequestsLoaded = map[string]map[int64]bool{}, map[string]bool{}, false
+	}()
+	*maxAssignmentsFl = 1
+	assignedPRs = map[string]map[int64]bool{}
+	loadReviewRequests(map[string][]int64{})
+
+	if ok, err := reserveAssignment("alice"); !ok || err != nil {
+		t.Fatalf("reserveAssignment(alice) = %v, %v, want true", ok, err)
+	}
+	if ok, _ := reserveAssignment("alice"); ok {
+		t.Fatalf("reserveAssignment(alice) = true over the limit")
+	}
+	releaseAssignment("alice")
+	if ok, err := reserveAssignment("alice"); !ok || err != nil {
+		t.Errorf("reserveAssignment(alice) after release = %v, %v, want true", ok, err)
+	}
+}
+
+func TestMemberRequestsQuery(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("q")
+		w.Write([]byte(`{"total_count": 0, "items": []}`))
+	}))
+	defer srv.Close()
+	defer func(api, org, owner, provider string) {
+		*ghAPIFl, *ghOrgFl, *ownerTypeFl, *providerFl = api, org, owner, provider
+	}(*ghAPIFl, *ghOrgFl, *ownerTypeFl, *providerFl)
+	defer func() {
+		assignedPRs, requestsLoaded, allRequestsLoaded = map[string]map[int64]bool{}, map[string]bool{}, false
+	}()
+	*ghAPIFl, *ghOrgFl, *providerFl = srv.URL, "alice", "github"
+
+	tests := []struct {
+		ownerType string
+		want      string
+	}{
+		{ownerType: "org", want: "org:alice is:pr is:open review-requested:bob"},
+		{ownerType: "user", want: "user:alice is:pr is:open review-requested:bob"},
+	}
+	for _, tt := range tests {
+		*ownerTypeFl = tt.ownerType
+		requestsLoaded, allRequestsLoaded = map[string]bool{}, false
+		if err := loadMemberRequests("bob"); err != nil {
+			t.Fatalf("loadMemberRequests(bob) error = %v", err)
+		}
+		if query != tt.want {
+			t.Errorf("owner type %s: query = %q, want %q", tt.ownerType, query, tt.want)
+		}
+	}
+}
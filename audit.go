@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditKey is the key of the document holding the assignment audit log.
+const auditKey = "audit/assignments"
+
+// AuditEntry records a single action of the bot on a pull request.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Key is the key of the pull request.
+	Key   string `json:"key"`
+	Login string `json:"login,omitempty"`
+	// Action is assigned, reassigned, requested, for additional
+	// reviewers, or shadow, for optional reviewers learning the code.
+	// Other actions are reminded and escalated, with the login of the
+	// assignee, labeled and commented.
+	Action string `json:"action"`
+	// Detail is the label added, or the message of the comment posted.
+	Detail string `json:"detail,omitempty"`
+}
+
+// assignment returns true if the entry made a member responsible for the
+// pull request. Shadow reviews are optional and other actions only tell
+// about it.
+func (e AuditEntry) assignment() bool {
+	switch e.Action {
+	case "assigned", "reassigned", "requested":
+		return true
+	}
+	return false
+}
+
+var auditMu sync.Mutex
+
+// recordAssignment appends assignment to the audit log. Errors are logged.
+func recordAssignment(key, login, action string, now time.Time) {
+	recordAction(AuditEntry{Time: now, Key: key, Login: login, Action: action})
+}
+
+// recordAction appends given entry to the audit log, dropping entries older
+// than -audit-retention. Errors are logged.
+func recordAction(entry AuditEntry) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	entries, err := loadAudit()
+	if err != nil {
+		log.Printf("cannot load audit log: %s", err)
+		return
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if *auditRetentionFl == 0 || e.Time.After(entry.Time.Add(-*auditRetentionFl)) {
+			kept = append(kept, e)
+		}
+	}
+	kept = append(kept, entry)
+	if err := saveDocument(auditKey, kept); err != nil {
+		log.Printf("cannot save audit log: %s", err)
+	}
+}
+
+// loadAudit returns all entries of the audit log, oldest first.
+func loadAudit() ([]AuditEntry, error) {
+	var entries []AuditEntry
+	_, err := loadDocument(auditKey, &entries)
+	return entries, err
+}
+
+// parseSince returns time given by -since of the history subcommand, either
+// a date like 2006-01-02 in -timezone or a duration before now like 720h.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, timezone); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -since %q, expected date like 2006-01-02 or duration like 720h", s)
+	}
+	return now.Add(-d), nil
+}
+
+// repoMatches returns true if repository of pull request with given key
+// matches given glob, or the glob is empty.
+func repoMatches(glob, key string) bool {
+	if glob == "" {
+		return true
+	}
+	if i := strings.LastIndex(key, "#"); i >= 0 {
+		key = key[:i]
+	}
+	return globMatch(glob, key)
+}
+
+// runHistory runs the history subcommand with given arguments, writing
+// entries of the audit log matching given filters and the number of
+// distinct pull requests among them. With -snapshots, it writes stale pull
+// requests of past scans instead.
+func runHistory(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	repo := fs.String("repo", "", "Only pull requests of repositories matching given glob")
+	assignee := fs.String("assignee", "", "Only actions concerning given login, or with -snapshots pull requests assigned to it")
+	since := fs.String("since", "", "Only entries since given date, 2006-01-02, or duration before now, 720h")
+	action := fs.String("action", "", "Only entries with given action, assigned, reassigned, requested, shadow, reminded, escalated, labeled or commented")
+	snapshots := fs.Bool("snapshots", false, "List stale pull requests of every scan instead of actions")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var from time.Time
+	if *since != "" {
+		var err error
+		if from, err = parseSince(*since, time.Now()); err != nil {
+			return err
+		}
+	}
+	if *snapshots {
+		return writeSnapshots(w, from, *repo, *assignee)
+	}
+	entries, err := loadAudit()
+	if err != nil {
+		return fmt.Errorf("cannot load audit log: %s", err)
+	}
+	prs := map[string]bool{}
+	for _, e := range entries {
+		if e.Time.Before(from) ||
+			(*assignee != "" && !strings.EqualFold(e.Login, *assignee)) ||
+			(*action != "" && e.Action != *action) ||
+			!repoMatches(*repo, e.Key) {
+			continue
+		}
+		prs[e.Key] = true
+		login := e.Login
+		if login == "" {
+			login = "-"
+		}
+		line := fmt.Sprintf("%s  %-10s  %-20s  %s", e.Time.In(timezone).Format("2006-01-02 15:04"), e.Action, login, e.Key)
+		if e.Detail != "" {
+			line += "  " + e.Detail
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "%d pull requests\n", len(prs))
+	return nil
+}
+
+// writeSnapshots writes the number of stale pull requests of every scan
+// since given time, followed by those pull requests and their assignees.
+// Only pull requests of repositories matching given glob and assigned to
+// given login are listed, if set.
+func writeSnapshots(w io.Writer, from time.Time, repo, assignee string) error {
+	snapshots, err := loadPullSnapshots()
+	if err != nil {
+		return fmt.Errorf("cannot load history: %s", err)
+	}
+	for _, s := range snapshots {
+		if s.At.Before(from) {
+			continue
+		}
+		var pulls []pullSnapshot
+		for _, p := range s.Pulls {
+			if (assignee == "" || strings.EqualFold(p.Assignee, assignee)) && repoMatches(repo, p.Key) {
+				pulls = append(pulls, p)
+			}
+		}
+		fmt.Fprintf(w, "%s  %d stale pull requests\n", s.At.In(timezone).Format("2006-01-02 15:04"), len(pulls))
+		for _, p := range pulls {
+			login := p.Assignee
+			if login == "" {
+				login = "-"
+			}
+			fmt.Fprintf(w, "  %-20s  %-8s  %s\n", login, formatAge(s.At.Sub(p.StaleSince)), p.Key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// authorReminderText returns why author of given pull request should act on
+// it, or empty string if the pull request waits for reviewers.
+func authorReminderText(issue *Issue) (string, error) {
+	latest, err := latestReviews(issue)
+	if err != nil {
+		return "", err
+	}
+	var requesters []string
+	for login, state := range latest {
+		if state == "CHANGES_REQUESTED" {
+			requesters = append(requesters, "@"+login)
+		}
+	}
+	if len(requesters) > 0 {
+		sort.Strings(requesters)
+		return tr(issue, "changes_requested", strings.Join(requesters, ", ")), nil
+	}
+	if issue.Assignee != nil || len(latest) > 0 {
+		return "", nil
+	}
+	pull, err := getPull(issue)
+	if err != nil {
+		return "", err
+	}
+	if len(pull.RequestedReviewers) > 0 {
+		return "", nil
+	}
+	return tr(issue, "no_reviewers"), nil
+}
+
+// remindAuthor reminds author of given stale pull request on Slack if the
+// pull request waits for the author, at most once per -author-remind-every.
+func remindAuthor(issue *Issue, now time.Time) {
+	if *authorRemindEveryFl <= 0 || !notificationsEnabled() || githubOnly() != nil {
+		return
+	}
+	if staleSince(issue).Add(*authorRemindAfterFl).After(now) {
+		return
+	}
+	key := issueKey(issue)
+	st := getState(key)
+	author := issue.User.Login
+	if st.quiet(now) || now.Before(st.AuthorRemindedAt.Add(*authorRemindEveryFl)) || quietAt(now, memberLocation(author)) {
+		return
+	}
+	if len(awaitingAuthor(issue)) > 0 {
+		// reminded instead of the assignee
+		return
+	}
+	text, err := authorReminderText(issue)
+	if err != nil {
+		log.Printf("cannot check reviews of #%d: %s", issue.Number, err)
+		return
+	}
+	if text == "" {
+		return
+	}
+
+	log.Printf("Reminding author %s of PR #%d: %s", author, issue.Number, text)
+	msg := slackMessage{
+		Text:  tr(issue, "author_notice", userMention(author), issue.HTMLURL, issue.Number, issue.Title, text),
+		Issue: issue,
+	}
+	if *slackTokenFl != "" {
+		msg.Key = key
+	}
+	if err := notify(msg); err != nil {
+		log.Printf("cannot write slack notification: %s", err)
+		return
+	}
+	err = updateState(key, func(s *PRState) {
+		s.AuthorRemindedAt = now
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+}
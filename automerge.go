@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// AutoMerge configures merging of approved pull requests nobody merged.
+// Pull requests are merged when they belong to one of the repositories or
+// have one of the labels.
+type AutoMerge struct {
+	Repos  []string `json:"repos"`
+	Labels []string `json:"labels"`
+	// After is the minimum age of merged pull requests.
+	After Duration `json:"after"`
+	// Method is the merge method, one of merge, squash or rebase. Merge by
+	// default.
+	Method string `json:"method"`
+}
+
+// enabled returns true if auto-merge is enabled for given pull request by
+// its repository policy, or by repository or label.
+func (a *AutoMerge) enabled(issue *Issue) bool {
+	if p := issuePolicy(issue); p != nil {
+		if on, ok := p.Features["auto_merge"]; ok {
+			return on
+		}
+	}
+	repo, _ := issue.GetRepository()
+	for _, r := range a.Repos {
+		if r == repo {
+			return true
+		}
+	}
+	for _, name := range a.Labels {
+		for _, label := range issue.Labels {
+			if label.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeable returns true if given pull request is approved by everyone who
+// reviewed it, has enough approvals, passes CI and has no conflicts.
+func mergeable(issue *Issue) (bool, error) {
+	latest, err := latestReviews(issue)
+	if err != nil {
+		return false, fmt.Errorf("cannot list reviews: %s", err)
+	}
+	approved := 0
+	for _, state := range latest {
+		switch state {
+		case "APPROVED":
+			approved++
+		case "CHANGES_REQUESTED":
+			return false, nil
+		}
+	}
+	if approved == 0 {
+		return false, nil
+	}
+	missing, err := missingApprovals(issue)
+	if err != nil && err != errNoRequiredApprovals {
+		return false, err
+	}
+	if missing > 0 {
+		return false, nil
+	}
+	status, err := ciStatus(issue)
+	if err != nil {
+		return false, fmt.Errorf("cannot get CI status: %s", err)
+	}
+	if status != "success" {
+		return false, nil
+	}
+	pull, err := getPull(issue)
+	if err != nil {
+		return false, err
+	}
+	return pull.Mergeable != nil && *pull.Mergeable, nil
+}
+
+// mergePullRequest merges given pull request using given method.
+func mergePullRequest(issue *Issue, method string) error {
+	pull, err := getPull(issue)
+	if err != nil {
+		return err
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var body bytes.Buffer
+	err = json.NewEncoder(&body).Encode(map[string]interface{}{
+		"merge_method": method,
+		// do not merge commits pushed after the checks
+		"sha": pull.Head.SHA,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/merge", *ghAPIFl, *ghOrgFl, repo, issue.Number)
+	req, err := http.NewRequest("PUT", url, &body)
+	if err != nil {
+		return fmt.Errorf("cannot create PUT request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// autoMerge merges eligible pull requests of given issues and returns the
+// issues that are still open.
+func autoMerge(issues []Issue, now time.Time) []Issue {
+	a := config.AutoMerge
+	if a == nil || githubOnly() != nil {
+		return issues
+	}
+	method := a.Method
+	if method == "" {
+		method = "merge"
+	}
+	open := issues[:0]
+	for _, issue := range issues {
+		if !issue.isPullRequest() || optedOut(&issue) || ignored(&issue) || !a.enabled(&issue) || issue.CreatedAt.Add(time.Duration(a.After)).After(now) {
+			open = append(open, issue)
+			continue
+		}
+		ok, err := mergeable(&issue)
+		if err != nil {
+			log.Printf("cannot check if #%d can be merged: %s", issue.Number, err)
+		}
+		if !ok {
+			open = append(open, issue)
+			continue
+		}
+		if err := mergePullRequest(&issue, method); err != nil {
+			log.Printf("cannot merge #%d: %s", issue.Number, err)
+			open = append(open, issue)
+			continue
+		}
+		log.Printf("Merged PR #%d (%s)", issue.Number, issue.Title)
+		comment := tr(&issue, "merged_comment", formatAge(now.Sub(issue.CreatedAt)), method)
+		if err := forge.Comment(&issue, comment); err != nil {
+			log.Printf("cannot comment on #%d pull request: %s", issue.Number, err)
+			continue
+		}
+		recordAction(AuditEntry{Time: now, Key: issueKey(&issue), Action: "commented", Detail: "merged_comment"})
+	}
+	return open
+}
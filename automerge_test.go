@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAutoMerge(t *testing.T) {
+	reviews := map[string]string{
+		// approved, green and mergeable
+		"1": `[{"user": {"login": "bob"}, "state": "APPROVED"}]`,
+		// approved by one reviewer, changes requested by another
+		"2": `[{"user": {"login": "bob"}, "state": "APPROVED"}, {"user": {"login": "carol"}, "state": "CHANGES_REQUESTED"}]`,
+		// not approved by anybody
+		"3": `[{"user": {"login": "bob"}, "state": "COMMENTED"}]`,
+	}
+	var merged, commented []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/repos/acme/api/"), "/")
+		switch {
+		case r.Method == "GET" && len(parts) == 2 && parts[0] == "pulls":
+			fmt.Fprintf(w, `{"head": {"sha": "sha%s"}, "base": {"ref": "main"}, "mergeable": true}`, parts[1])
+		case r.Method == "GET" && len(parts) == 3 && parts[2] == "reviews":
+			w.Write([]byte(reviews[parts[1]]))
+		case r.Method == "GET" && len(parts) == 3 && parts[2] == "status":
+			w.Write([]byte(`{"state": "success", "statuses": [{"state": "success"}]}`))
+		case r.Method == "GET" && len(parts) == 3 && parts[2] == "check-runs":
+			w.Write([]byte(`{"check_runs": [{"status": "completed", "conclusion": "success"}]}`))
+		case r.Method == "GET" && parts[0] == "rules":
+			w.Write([]byte(`[]`))
+		case r.Method == "PUT" && len(parts) == 3 && parts[2] == "merge":
+			merged = append(merged, parts[1])
+		case r.Method == "POST" && len(parts) == 3 && parts[2] == "comments":
+			commented = append(commented, parts[1])
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 1}`))
+		default:
+			// branch protection is not readable
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(api, org, provider string, cfg Config, s Store) {
+		*ghAPIFl, *ghOrgFl, *providerFl, config, store = api, org, provider, cfg, s
+	}(*ghAPIFl, *ghOrgFl, *providerFl, config, store)
+	defer func() {
+		pullsCache, reviewsCache, ciCache = map[int64]*Pull{}, map[int64]map[string]string{}, map[int64]string{}
+		requiredCache = map[string]int{}
+	}()
+	*ghAPIFl, *ghOrgFl, *providerFl, store = srv.URL, "acme", "github", newMemoryStore()
+	config = Config{AutoMerge: &AutoMerge{Repos: []string{"api"}, After: Duration(24 * time.Hour)}}
+	pullsCache, reviewsCache, ciCache = map[int64]*Pull{}, map[int64]map[string]string{}, map[int64]string{}
+	requiredCache = map[string]int{}
+
+	now := time.Now()
+	issue := func(number int64, repo string, age time.Duration) Issue {
+		return Issue{ID: number, Number: number, Repo: repo, Title: "Fix", PullRequest: &PullRequest{},
+			User: &User{Login: "alice"}, CreatedAt: now.Add(-age)}
+	}
+	issues := []Issue{
+		issue(1, "api", 48*time.Hour),
+		issue(2, "api", 48*time.Hour),
+		issue(3, "api", 48*time.Hour),
+		// younger than after
+		issue(4, "api", time.Hour),
+		// repository without auto-merge
+		issue(5, "web", 48*time.Hour),
+	}
+	open := autoMerge(issues, now)
+	if len(open) != 4 {
+		t.Errorf("autoMerge() left %d pull requests open, want 4", len(open))
+	}
+	if strings.Join(merged, ",") != "1" || strings.Join(commented, ",") != "1" {
+		t.Errorf("autoMerge() merged %v and commented on %v, want only #1", merged, commented)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batchedReminder is a reminder collected for the batch message.
+type batchedReminder struct {
+	// Key is the key of the pull request.
+	Key      string
+	Repo     string
+	Assignee string
+	// Team is the team of the assignee, empty if not configured.
+	Team string
+	Age  time.Duration
+	Line string
+	// Breached tells if the pull request is past its critical threshold.
+	Breached bool
+}
+
+var (
+	batchMu sync.Mutex
+	batch   []batchedReminder
+)
+
+// addToBatch collects reminder about given pull request, to be sent by
+// flushBatch.
+func addToBatch(issue *Issue, line string, breached bool) {
+	batchMu.Lock()
+	defer batchMu.Unlock()
+	repo, _ := issue.GetRepository()
+	batch = append(batch, batchedReminder{
+		Key:      issueKey(issue),
+		Repo:     repo,
+		Assignee: issue.Assignee.Login,
+		Team:     config.Members[issue.Assignee.Login].Team,
+		Age:      time.Since(staleSince(issue)),
+		Line:     line,
+		Breached: breached,
+	})
+}
+
+// batchTeams returns teams of given reminders sorted by name, assignees
+// without team last, and reminders by team.
+func batchTeams(reminders []batchedReminder) ([]string, map[string][]batchedReminder) {
+	byTeam := map[string][]batchedReminder{}
+	var teams []string
+	for _, r := range reminders {
+		if _, ok := byTeam[r.Team]; !ok {
+			teams = append(teams, r.Team)
+		}
+		byTeam[r.Team] = append(byTeam[r.Team], r)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		if teams[i] == "" || teams[j] == "" {
+			return teams[j] == ""
+		}
+		return teams[i] < teams[j]
+	})
+	return teams, byTeam
+}
+
+// batchText returns text of the message listing given reminders in sections
+// of teams of the assignees, if any are configured, grouped by assignee,
+// assignees with the oldest pull requests first and pull requests sorted by
+// age.
+func batchText(reminders []batchedReminder) string {
+	var b strings.Builder
+	b.WriteString(tr(nil, "batch_header", len(reminders)) + "\n")
+	teams, byTeam := batchTeams(reminders)
+	if len(teams) == 1 && teams[0] == "" {
+		writeAssignees(&b, reminders)
+		return b.String()
+	}
+	for _, team := range teams {
+		name := team
+		if name == "" {
+			name = tr(nil, "no_team")
+		}
+		fmt.Fprintf(&b, "\n*%s*\n", name)
+		writeAssignees(&b, byTeam[team])
+	}
+	return b.String()
+}
+
+// writeAssignees writes given reminders grouped by assignee to given
+// builder.
+func writeAssignees(b *strings.Builder, reminders []batchedReminder) {
+	byAssignee := map[string][]batchedReminder{}
+	for _, r := range reminders {
+		byAssignee[r.Assignee] = append(byAssignee[r.Assignee], r)
+	}
+	var assignees []string
+	for login, list := range byAssignee {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Age > list[j].Age
+		})
+		assignees = append(assignees, login)
+	}
+	sort.Slice(assignees, func(i, j int) bool {
+		return byAssignee[assignees[i]][0].Age > byAssignee[assignees[j]][0].Age
+	})
+
+	for _, login := range assignees {
+		fmt.Fprintf(b, "\n@%s\n", login)
+		for _, r := range byAssignee[login] {
+			b.WriteString(tr(nil, "batch_line", r.Line, formatAge(r.Age)) + "\n")
+		}
+	}
+}
+
+// managerText returns summary of given reminders for managers: counts and
+// SLA breaches per team and the pull requests waiting longest.
+func managerText(reminders []batchedReminder) string {
+	breached := 0
+	for _, r := range reminders {
+		if r.Breached {
+			breached++
+		}
+	}
+	var b strings.Builder
+	b.WriteString(tr(nil, "manager_header", len(reminders), breached) + "\n")
+	teams, byTeam := batchTeams(reminders)
+	if len(teams) > 1 || teams[0] != "" {
+		b.WriteString("\n")
+		for _, team := range teams {
+			name := team
+			if name == "" {
+				name = tr(nil, "no_team")
+			}
+			n := 0
+			for _, r := range byTeam[team] {
+				if r.Breached {
+					n++
+				}
+			}
+			b.WriteString(tr(nil, "manager_team", name, len(byTeam[team]), n) + "\n")
+		}
+	}
+
+	longest := append([]batchedReminder(nil), reminders...)
+	sort.Slice(longest, func(i, j int) bool {
+		return longest[i].Age > longest[j].Age
+	})
+	if len(longest) > *managerLongestFl {
+		longest = longest[:*managerLongestFl]
+	}
+	if len(longest) > 0 {
+		b.WriteString("\n" + tr(nil, "manager_longest") + "\n")
+		for _, r := range longest {
+			b.WriteString(tr(nil, "batch_line", r.Line+" @"+r.Assignee, formatAge(r.Age)) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// postManagerSummary sends summary of given reminders to every channel and
+// user of -manager-channels.
+func postManagerSummary(reminders []batchedReminder) {
+	text := managerText(reminders)
+	for _, channel := range strings.Split(*managerChannelsFl, ",") {
+		if channel = strings.TrimSpace(channel); channel == "" {
+			continue
+		}
+		msg := map[string]interface{}{
+			"channel":    channel,
+			"text":       text,
+			"username":   "github-pr",
+			"icon_emoji": ":octocat:",
+		}
+		if err := slackCall("chat.postMessage", msg, nil); err != nil {
+			log.Printf("cannot post manager summary to %s: %s", channel, err)
+		}
+	}
+}
+
+// batchRoute returns identifier of the channels reminders about pull
+// requests of given repository are posted to, by its policy.
+func batchRoute(repo string) string {
+	p := repoPolicy(repo)
+	if p == nil {
+		return ""
+	}
+	return strings.Join([]string{p.SlackChannel, p.MattermostChannel, p.TelegramChat}, "\x00")
+}
+
+// flushBatch sends all collected reminders, a single message to every
+// channel of their repository policies. Pull requests are recorded as
+// notified once the message listing them was sent.
+func flushBatch() error {
+	batchMu.Lock()
+	reminders := batch
+	batch = nil
+	batchMu.Unlock()
+
+	if len(reminders) == 0 {
+		return nil
+	}
+	if *managerChannelsFl != "" {
+		postManagerSummary(reminders)
+	}
+	byRoute := map[string][]batchedReminder{}
+	var routes []string
+	for _, r := range reminders {
+		route := batchRoute(r.Repo)
+		if _, ok := byRoute[route]; !ok {
+			routes = append(routes, route)
+		}
+		byRoute[route] = append(byRoute[route], r)
+	}
+	sort.Strings(routes)
+	var firstErr error
+	now := time.Now()
+	for _, route := range routes {
+		list := byRoute[route]
+		if err := notify(slackMessage{Text: batchText(list), Repo: list[0].Repo}); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, r := range list {
+			err := updateState(r.Key, func(s *PRState) {
+				s.NotifiedAt = now
+			})
+			if err != nil {
+				log.Printf("cannot update state of %s: %s", r.Key, err)
+			}
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchText(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name      string
+		reminders []batchedReminder
+		want      string
+	}{
+		{
+			name: "assignees with the oldest pull requests first",
+			reminders: []batchedReminder{
+				{Assignee: "alice", Age: day, Line: "api#1"},
+				{Assignee: "bob", Age: 3 * day, Line: "api#2"},
+				{Assignee: "alice", Age: 2 * day, Line: "web#3"},
+			},
+			want: "3 pull requests are waiting for review:\n" +
+				"\n@bob\n• api#2 (stale for 3d 0h)\n" +
+				"\n@alice\n• web#3 (stale for 2d 0h)\n• api#1 (stale for 1d 0h)\n",
+		},
+		{
+			name: "teams sorted by name, members without team last",
+			reminders: []batchedReminder{
+				{Assignee: "carol", Age: day, Line: "api#1"},
+				{Assignee: "bob", Team: "web", Age: day, Line: "web#2"},
+				{Assignee: "alice", Team: "api", Age: day, Line: "api#3"},
+			},
+			want: "3 pull requests are waiting for review:\n" +
+				"\n*api*\n\n@alice\n• api#3 (stale for 1d 0h)\n" +
+				"\n*web*\n\n@bob\n• web#2 (stale for 1d 0h)\n" +
+				"\n*No team*\n\n@carol\n• api#1 (stale for 1d 0h)\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := batchText(tt.reminders); got != tt.want {
+			t.Errorf("%s: batchText() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
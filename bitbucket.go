@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const bitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+// bitbucketUser is the user representation of Bitbucket API. Bitbucket has
+// no numeric user IDs and refers to users by UUID.
+type bitbucketUser struct {
+	UUID     string `json:"uuid"`
+	Nickname string `json:"nickname"`
+	Type     string `json:"type"`
+}
+
+var (
+	bitbucketUsersMu sync.Mutex
+	// bitbucketUUIDs maps nicknames of all seen users, prefixed with the
+	// tenant scope, to their UUIDs.
+	bitbucketUUIDs = map[string]string{}
+)
+
+func (u *bitbucketUser) user() *User {
+	if u == nil {
+		return nil
+	}
+	bitbucketUsersMu.Lock()
+	bitbucketUUIDs[tenantScope()+u.Nickname] = u.UUID
+	bitbucketUsersMu.Unlock()
+
+	user := &User{Login: u.Nickname, Type: "User"}
+	// apps and workspace access tokens are represented as "app_user"
+	if u.Type == "app_user" {
+		user.Type = "Bot"
+	}
+	return user
+}
+
+// bitbucketPullRequest is the pull request representation of Bitbucket API.
+type bitbucketPullRequest struct {
+	ID      int64  `json:"id"`
+	Title   string `json:"title"`
+	Summary struct {
+		Raw string `json:"raw"`
+	} `json:"summary"`
+	State     string           `json:"state"`
+	CreatedOn time.Time        `json:"created_on"`
+	UpdatedOn time.Time        `json:"updated_on"`
+	Author    *bitbucketUser   `json:"author"`
+	Reviewers []*bitbucketUser `json:"reviewers"`
+	// Participants are included in listings with fields=+values.participants,
+	// which leave out reviewers.
+	Participants []struct {
+		Role string         `json:"role"`
+		User *bitbucketUser `json:"user"`
+	} `json:"participants"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+// issue converts pull request of given repository to the issue
+// representation shared by all providers. Bitbucket pull requests have no
+// assignee, so the first reviewer is considered to be the assignee.
+func (pr *bitbucketPullRequest) issue(repo string) Issue {
+	issue := Issue{
+		ID:          bitbucketIssueID(repo, pr.ID),
+		Number:      pr.ID,
+		CreatedAt:   pr.CreatedOn,
+		UpdatedAt:   pr.UpdatedOn,
+		User:        pr.Author.user(),
+		URL:         pr.Links.HTML.Href,
+		HTMLURL:     pr.Links.HTML.Href,
+		Title:       pr.Title,
+		Body:        pr.Summary.Raw,
+		State:       strings.ToLower(pr.State),
+		PullRequest: &PullRequest{HTMLURL: pr.Links.HTML.Href},
+		Repo:        repo,
+	}
+	if reviewers := pr.reviewers(); len(reviewers) > 0 {
+		issue.Assignee = reviewers[0].user()
+	}
+	return issue
+}
+
+// reviewers returns reviewers of the pull request, taken from participants
+// in listings.
+func (pr *bitbucketPullRequest) reviewers() []*bitbucketUser {
+	if len(pr.Reviewers) > 0 {
+		return pr.Reviewers
+	}
+	var reviewers []*bitbucketUser
+	for _, p := range pr.Participants {
+		if p.Role == "REVIEWER" {
+			reviewers = append(reviewers, p.User)
+		}
+	}
+	return reviewers
+}
+
+// bitbucketIssueID returns ID unique across the workspace, because pull
+// request IDs are unique per repository only.
+func bitbucketIssueID(repo string, id int64) int64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s#%d", repo, id)
+	return int64(h.Sum64() >> 1)
+}
+
+// bitbucketRequest sends request to Bitbucket API and decodes JSON response
+// into given value. Path can be an absolute URL, as returned in "next" field
+// of paginated responses.
+func bitbucketRequest(method, path string, body interface{}, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		var b bytes.Buffer
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return fmt.Errorf("cannot encode body: %s", err)
+		}
+		r = &b
+	}
+	u := path
+	if !strings.HasPrefix(path, "https://") {
+		u = bitbucketAPIURL + path
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return fmt.Errorf("cannot create %s request: %s", method, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+*bitbucketTokenFl)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return fmt.Errorf("cannot decode response: %s", err)
+		}
+	}
+	return nil
+}
+
+// bitbucketPages calls fn with raw values of all pages of given paginated
+// resource.
+func bitbucketPages(path string, fn func(json.RawMessage) error) error {
+	for path != "" {
+		var page struct {
+			Values json.RawMessage `json:"values"`
+			Next   string          `json:"next"`
+		}
+		if err := bitbucketRequest("GET", path, nil, &page); err != nil {
+			return err
+		}
+		if err := fn(page.Values); err != nil {
+			return fmt.Errorf("cannot decode response: %s", err)
+		}
+		path = page.Next
+	}
+	return nil
+}
+
+// bitbucketProvider is the Provider using Bitbucket Cloud API. Organization
+// flag is the workspace and all workspace members are assigned.
+type bitbucketProvider struct{}
+
+func (bitbucketProvider) OpenIssues() ([]Issue, error) {
+	var repos []string
+	path := fmt.Sprintf("/repositories/%s?pagelen=100", url.PathEscape(*ghOrgFl))
+	err := bitbucketPages(path, func(raw json.RawMessage) error {
+		var page []struct {
+			Slug string `json:"slug"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		for _, r := range page {
+			repos = append(repos, r.Slug)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list repositories: %s", err)
+	}
+
+	var issues []Issue
+	for _, repo := range repos {
+		// reviewers are not part of the listing, but participants are
+		// when asked for
+		path := fmt.Sprintf("/repositories/%s/%s/pullrequests?state=OPEN&pagelen=50&fields=%%2Bvalues.participants",
+			url.PathEscape(*ghOrgFl), url.PathEscape(repo))
+		err := bitbucketPages(path, func(raw json.RawMessage) error {
+			var page []bitbucketPullRequest
+			if err := json.Unmarshal(raw, &page); err != nil {
+				return err
+			}
+			for i := range page {
+				issues = append(issues, page[i].issue(repo))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot list %q pull requests: %s", repo, err)
+		}
+	}
+	return issues, nil
+}
+
+// bitbucketPull returns pull request with given ID, including reviewers.
+func bitbucketPull(repo string, id int64) (*bitbucketPullRequest, error) {
+	var pr bitbucketPullRequest
+	path := fmt.Sprintf("/repositories/%s/%s/pullrequests/%d", url.PathEscape(*ghOrgFl), url.PathEscape(repo), id)
+	if err := bitbucketRequest("GET", path, nil, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+func (bitbucketProvider) Issue(repo string, number int64) (*Issue, error) {
+	pr, err := bitbucketPull(repo, number)
+	if err != nil {
+		return nil, err
+	}
+	issue := pr.issue(repo)
+	return &issue, nil
+}
+
+func (bitbucketProvider) Members() ([]User, error) {
+	var members []User
+	path := fmt.Sprintf("/workspaces/%s/members?pagelen=100", url.PathEscape(*ghOrgFl))
+	err := bitbucketPages(path, func(raw json.RawMessage) error {
+		var page []struct {
+			User *bitbucketUser `json:"user"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		for _, m := range page {
+			// members without linked user cannot be assigned
+			if u := m.User.user(); u != nil {
+				members = append(members, *u)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// DefaultReviewers returns logins of default reviewers of given repository,
+// including those inherited from its project.
+func (bitbucketProvider) DefaultReviewers(repo string) ([]string, error) {
+	var logins []string
+	path := fmt.Sprintf("/repositories/%s/%s/effective-default-reviewers?pagelen=100", url.PathEscape(*ghOrgFl), url.PathEscape(repo))
+	err := bitbucketPages(path, func(raw json.RawMessage) error {
+		var page []struct {
+			User *bitbucketUser `json:"user"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		for _, r := range page {
+			if u := r.User.user(); u != nil {
+				logins = append(logins, u.Login)
+			}
+		}
+		return nil
+	})
+	return logins, err
+}
+
+// Assign adds given user as the first reviewer of the pull request, because
+// Bitbucket pull requests have no assignee.
+func (bitbucketProvider) Assign(issue *Issue, user *User) error {
+	return addBitbucketReviewer(issue, user, true)
+}
+
+// RequestReview adds given user as a reviewer of the pull request, after
+// the existing ones, so that the assignee stays the first reviewer.
+func (bitbucketProvider) RequestReview(issue *Issue, user *User) error {
+	return addBitbucketReviewer(issue, user, false)
+}
+
+// addBitbucketReviewer adds given user to reviewers of given pull request,
+// as the first one if first is true, otherwise as the last one unless the
+// user is a reviewer already.
+func addBitbucketReviewer(issue *Issue, user *User, first bool) error {
+	bitbucketUsersMu.Lock()
+	uuid, ok := bitbucketUUIDs[tenantScope()+user.Login]
+	bitbucketUsersMu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown user %q", user.Login)
+	}
+	pr, err := bitbucketPull(issue.Repo, issue.Number)
+	if err != nil {
+		return fmt.Errorf("cannot fetch pull request: %s", err)
+	}
+	var reviewers []map[string]string
+	if first {
+		reviewers = append(reviewers, map[string]string{"uuid": uuid})
+	}
+	for _, r := range pr.Reviewers {
+		if r.UUID != uuid {
+			reviewers = append(reviewers, map[string]string{"uuid": r.UUID})
+		} else if !first {
+			// already a reviewer
+			return nil
+		}
+	}
+	if !first {
+		reviewers = append(reviewers, map[string]string{"uuid": uuid})
+	}
+	path := fmt.Sprintf("/repositories/%s/%s/pullrequests/%d", url.PathEscape(*ghOrgFl), url.PathEscape(issue.Repo), issue.Number)
+	body := map[string]interface{}{
+		"title":     pr.Title,
+		"reviewers": reviewers,
+	}
+	if err := bitbucketRequest("PUT", path, body, nil); err != nil {
+		return err
+	}
+	log.Printf("%s added as reviewer of #%d pull request of %q", user.Login, issue.Number, issue.Repo)
+	return nil
+}
+
+func (bitbucketProvider) Comment(issue *Issue, comment string) error {
+	path := fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/comments", url.PathEscape(*ghOrgFl), url.PathEscape(issue.Repo), issue.Number)
+	body := map[string]interface{}{
+		"content": map[string]string{"raw": comment},
+	}
+	return bitbucketRequest("POST", path, body, nil)
+}
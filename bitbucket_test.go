@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// redirectTransport sends all requests to the test server, for APIs with
+// fixed URLs.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestBitbucketOpenIssues(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/2.0/repositories/acme":
+			w.Write([]byte(`{"values": [{"slug": "api"}]}`))
+		case "/2.0/repositories/acme/api/pullrequests":
+			if r.URL.Query().Get("fields") != "+values.participants" {
+				t.Errorf("pull requests listed with fields %q", r.URL.Query().Get("fields"))
+			}
+			w.Write([]byte(`{"values": [
+				{"id": 1, "title": "Fix login", "state": "OPEN",
+				 "author": {"uuid": "{a}", "nickname": "alice"},
+				 "participants": [
+					{"role": "PARTICIPANT", "user": {"uuid": "{c}", "nickname": "carol"}},
+					{"role": "REVIEWER", "user": {"uuid": "{b}", "nickname": "bob"}}
+				 ]},
+				{"id": 2, "title": "Add logout", "state": "OPEN",
+				 "author": {"uuid": "{a}", "nickname": "alice"}}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	defer func(c *http.Client, org string) { httpClient, *ghOrgFl = c, org }(httpClient, *ghOrgFl)
+	httpClient, *ghOrgFl = &http.Client{Transport: redirectTransport{target: target}}, "acme"
+
+	issues, err := bitbucketProvider{}.OpenIssues()
+	if err != nil {
+		t.Fatalf("OpenIssues() error = %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("OpenIssues() returned %d issues, want 2", len(issues))
+	}
+	// the first reviewer is the assignee, other participants are not
+	if a := issues[0].Assignee; a == nil || a.Login != "bob" {
+		t.Errorf("#1 assignee = %+v, want bob", a)
+	}
+	if a := issues[1].Assignee; a != nil {
+		t.Errorf("#2 assignee = %+v, want none", a)
+	}
+	// reviewers are not fetched for every pull request
+	if len(requests) != 2 {
+		t.Errorf("OpenIssues() made requests %v, want 2", requests)
+	}
+}
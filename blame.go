@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+const (
+	// blameMaxFiles limits number of changed files for which history is
+	// fetched, to keep number of API calls per pull request low.
+	blameMaxFiles = 10
+	// blameMaxCommits is the number of recent commits inspected per file.
+	blameMaxCommits = 10
+)
+
+// fileCommitters return logins of authors of the most recent commits that
+// changed given file in given repository, most recent first.
+func fileCommitters(repo, filename string) ([]string, error) {
+	if err := githubOnly(); err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/repos/%s/%s/commits?path=%s&per_page=%d",
+		*ghAPIFl, *ghOrgFl, repo, url.QueryEscape(filename), blameMaxCommits)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch response: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var commits []struct {
+		Author *User `json:"author"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %s", err)
+	}
+	var logins []string
+	for _, c := range commits {
+		// commits of authors not known to github have no author
+		if c.Author != nil {
+			logins = append(logins, c.Author.Login)
+		}
+	}
+	return logins, nil
+}
+
+// blameReviewer returns team member that recently changed files touched by
+// given pull request. Recent commits weight more than older ones. False is
+// returned if none of the recent committers can be assigned.
+func blameReviewer(issue *Issue) (User, bool, error) {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return User{}, false, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	files, err := changedFiles(issue)
+	if err != nil {
+		return User{}, false, fmt.Errorf("cannot list changed files: %s", err)
+	}
+	if len(files) > blameMaxFiles {
+		files = files[:blameMaxFiles]
+	}
+
+	members, err := listMembers()
+	if err != nil {
+		return User{}, false, fmt.Errorf("cannot list members: %s", err)
+	}
+	byLogin := make(map[string]User, len(members))
+	for _, m := range members {
+		byLogin[m.Login] = m
+	}
+
+	score := map[string]int{}
+	for _, f := range files {
+		logins, err := fileCommitters(repo, f)
+		if err != nil {
+			return User{}, false, fmt.Errorf("cannot list %q commits: %s", f, err)
+		}
+		for i, login := range logins {
+			if _, ok := byLogin[login]; ok {
+				score[login] += len(logins) - i
+			}
+		}
+	}
+
+	candidates := make([]string, 0, len(score))
+	for login := range score {
+		candidates = append(candidates, login)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if score[candidates[i]] == score[candidates[j]] {
+			return candidates[i] < candidates[j]
+		}
+		return score[candidates[i]] > score[candidates[j]]
+	})
+
+	for _, login := range candidates {
+		if canReview(issue, login) {
+			return byLogin[login], true, nil
+		}
+	}
+	return User{}, false, nil
+}
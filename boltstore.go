@@ -0,0 +1,75 @@
+//go:build bolt
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func init() {
+	storeBackends["bolt"] = newBoltStore
+}
+
+// boltBucket is the bucket documents are kept in.
+var boltBucket = []byte("state")
+
+// boltStore keeps documents in an embedded BoltDB file, given as
+// "bolt:///path/to/state.db".
+type boltStore struct {
+	db *bolt.DB
+}
+
+func newBoltStore(rawurl string) (Store, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state store url: %s", err)
+	}
+	// the file is locked, waiting for it would hang a second instance
+	db, err := bolt.Open(u.Path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open state: %s", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot create state bucket: %s", err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// values are valid only within the transaction
+		if v := tx.Bucket(boltBucket).Get([]byte(key)); v != nil {
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (s *boltStore) Set(key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), value)
+	})
+}
+
+func (s *boltStore) SetMany(docs map[string][]byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		for key, value := range docs {
+			if err := b.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
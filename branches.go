@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// baseBranch returns name of the branch given pull request targets.
+func baseBranch(issue *Issue) (string, error) {
+	pull, err := getPull(issue)
+	if err != nil {
+		return "", err
+	}
+	return pull.Base.Ref, nil
+}
+
+// branchMatches returns true if branch matches any of given comma separated
+// glob patterns.
+func branchMatches(branch, patterns string) bool {
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// processBranch returns true if given pull request targets one of
+// -base-branches. Pull requests whose base branch cannot be read are
+// processed.
+func processBranch(issue *Issue) bool {
+	if *baseBranchesFl == "" {
+		return true
+	}
+	branch, err := baseBranch(issue)
+	if err != nil {
+		log.Printf("cannot get base branch of #%d: %s", issue.Number, err)
+		return true
+	}
+	return branchMatches(branch, *baseBranchesFl)
+}
+
+// branchSLA returns SLA configured for the base branch of given pull request
+// and the pattern it matched. Patterns are tried in alphabetical order.
+func branchSLA(issue *Issue) (SLA, string, bool) {
+	if len(config.BranchSLA) == 0 {
+		return SLA{}, "", false
+	}
+	branch, err := baseBranch(issue)
+	if err != nil {
+		log.Printf("cannot get base branch of #%d: %s", issue.Number, err)
+		return SLA{}, "", false
+	}
+	var patterns []string
+	for pattern := range config.BranchSLA {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return config.BranchSLA[pattern], pattern, true
+		}
+	}
+	return SLA{}, "", false
+}
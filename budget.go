@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// apiFeatures are the features github API calls are budgeted by.
+var apiFeatures = []string{"core", "graphql", "reviews", "checks", "files"}
+
+// bestEffort are features that give way to core calls, listing pull
+// requests and all changes the bot makes, and to GraphQL calls, which
+// list pull requests with -fetcher=graphql.
+var bestEffort = map[string]bool{"reviews": true, "checks": true, "files": true}
+
+// errBudgetExhausted is returned for github API calls refused by the
+// budget.
+var errBudgetExhausted = errors.New("API call budget exhausted")
+
+// apiFeature returns feature given github API request is made for.
+// Requests changing anything are core, the bot must not leave actions
+// half done.
+func apiFeature(req *http.Request) string {
+	path := req.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/graphql"):
+		return "graphql"
+	case req.Method != "GET":
+		return "core"
+	case strings.HasSuffix(path, "/reviews"):
+		return "reviews"
+	case strings.HasSuffix(path, "/check-runs"), strings.HasSuffix(path, "/status"), strings.HasSuffix(path, "/statuses"):
+		return "checks"
+	case strings.HasSuffix(path, "/files"):
+		return "files"
+	}
+	return "core"
+}
+
+// parseQuotas parses -api-quotas, comma separated feature=calls pairs.
+func parseQuotas(s string) (map[string]int, error) {
+	quotas := map[string]int{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid -api-quotas entry %q, expected feature=calls", part)
+		}
+		n, err := strconv.Atoi(kv[1])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid -api-quotas entry %q, expected feature=calls", part)
+		}
+		known := false
+		for _, f := range apiFeatures {
+			known = known || f == kv[0]
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown -api-quotas feature %q, expected one of: %s", kv[0], strings.Join(apiFeatures, ", "))
+		}
+		quotas[kv[0]] = n
+	}
+	return quotas, nil
+}
+
+// apiBudget counts github API calls of the current scan by feature.
+type apiBudget struct {
+	mu      sync.Mutex
+	quotas  map[string]int
+	used    map[string]int
+	refused map[string]int
+	total   int
+	// scanning is true while a scan runs. Calls made outside of scans, by
+	// webhooks, slash commands and Slack buttons, are not budgeted.
+	scanning bool
+	// remaining is the rate limit remaining as reported by the last
+	// response, -1 if unknown.
+	remaining int
+}
+
+// budget is the github API call budget of the current scan.
+var budget = &apiBudget{
+	used:      map[string]int{},
+	refused:   map[string]int{},
+	remaining: -1,
+}
+
+// reset starts budget of a new scan.
+func (b *apiBudget) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used = map[string]int{}
+	b.refused = map[string]int{}
+	b.total = 0
+	b.scanning = true
+	// the previous scan may have been of a tenant with another token
+	b.remaining = -1
+}
+
+// stop ends budget of the finished scan.
+func (b *apiBudget) stop() {
+	b.mu.Lock()
+	b.scanning = false
+	b.mu.Unlock()
+}
+
+// take returns true and counts the call if given feature may make one more
+// call. Only best-effort features are ever refused: once -api-reserve or
+// fewer calls remain in -max-api-calls-per-run or in the github rate
+// limit, leaving them for the others, or once their quota is used up.
+// Core and GraphQL calls are only counted, exhausted tells when no further
+// pull request should be started.
+func (b *apiBudget) take(feature string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.scanning {
+		return true
+	}
+	if bestEffort[feature] {
+		reserve := *apiReserveFl
+		ok := true
+		switch {
+		case *maxAPICallsFl > 0 && b.total >= *maxAPICallsFl-reserve:
+			ok = false
+		case b.remaining >= 0 && b.remaining <= reserve:
+			ok = false
+		}
+		if quota, limited := b.quotas[feature]; limited && b.used[feature] >= quota {
+			ok = false
+		}
+		if !ok {
+			b.refused[feature]++
+			return false
+		}
+	}
+	b.used[feature]++
+	b.total++
+	return true
+}
+
+// exhausted returns true if the scan used up -max-api-calls-per-run or the
+// quota of core or GraphQL calls. The scan then does not start handling
+// further pull requests, but lets those being handled finish their
+// changes.
+func (b *apiBudget) exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.scanning {
+		return false
+	}
+	if *maxAPICallsFl > 0 && b.total >= *maxAPICallsFl {
+		return true
+	}
+	for feature, quota := range b.quotas {
+		if !bestEffort[feature] && b.used[feature] >= quota {
+			return true
+		}
+	}
+	return false
+}
+
+// observe remembers REST API rate limit reported by given response
+// headers. GraphQL and search have limits of their own.
+func (b *apiBudget) observe(header http.Header) {
+	if r := header.Get("X-RateLimit-Resource"); r != "" && r != "core" {
+		return
+	}
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	b.mu.Lock()
+	b.remaining = remaining
+	b.mu.Unlock()
+}
+
+// String returns calls made and refused by each feature.
+func (b *apiBudget) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	features := map[string]bool{}
+	for f := range b.used {
+		features[f] = true
+	}
+	for f := range b.refused {
+		features[f] = true
+	}
+	names := make([]string, 0, len(features))
+	for f := range features {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, f := range names {
+		part := fmt.Sprintf("%s=%d", f, b.used[f])
+		if b.refused[f] > 0 {
+			part += fmt.Sprintf(" (%d refused)", b.refused[f])
+		}
+		parts = append(parts, part)
+	}
+	return fmt.Sprintf("%d github API calls: %s", b.total, strings.Join(parts, ", "))
+}
+
+// budgetTransport refuses github API requests over the budget and counts
+// the others. Requests to other hosts are not budgeted.
+type budgetTransport struct {
+	base http.RoundTripper
+	host string
+}
+
+func newBudgetTransport(base http.RoundTripper) (*budgetTransport, error) {
+	quotas, err := parseQuotas(*apiQuotasFl)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(*ghAPIFl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid github API url: %s", err)
+	}
+	budget.quotas = quotas
+	return &budgetTransport{base: base, host: u.Host}, nil
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	feature := apiFeature(req)
+	if !budget.take(feature) {
+		return nil, fmt.Errorf("%s: %s", feature, errBudgetExhausted)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		budget.observe(resp.Header)
+	}
+	return resp, err
+}
+
+// logBudget logs github API calls of the finished scan.
+func logBudget() {
+	log.Print(budget.String())
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuotas(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]int
+		err  bool
+	}{
+		{in: "", want: map[string]int{}},
+		{in: "reviews=100", want: map[string]int{"reviews": 100}},
+		{in: "reviews=100, files=0,", want: map[string]int{"reviews": 100, "files": 0}},
+		{in: "reviews", err: true},
+		{in: "reviews=-1", err: true},
+		{in: "reviews=many", err: true},
+		{in: "comments=10", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseQuotas(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("parseQuotas(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseQuotas(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// freshnessCheckName is the name of the check run published on stale pull
+// requests.
+const freshnessCheckName = "review-freshness"
+
+// postFreshnessCheck publishes check run on the head commit of given stale
+// pull request, showing its age and assignee. Pull requests past the old
+// threshold get a failing check, other stale pull requests a neutral one.
+// Checks API is only available to github Apps.
+func postFreshnessCheck(issue *Issue, assignee string, now time.Time) error {
+	if !appEnabled() {
+		return errors.New("check runs require github App authentication")
+	}
+	pull, err := getPull(issue)
+	if err != nil {
+		return fmt.Errorf("cannot get pull request: %s", err)
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+
+	age := now.Sub(staleSince(issue))
+	conclusion := "neutral"
+	title := tr(issue, "check_waiting", formatAge(age))
+	if age > policyFor(issue).Old {
+		conclusion = "failure"
+		title = tr(issue, "check_overdue", formatAge(age))
+	}
+	summary := tr(issue, "check_summary", formatAge(age), assignee)
+	if sla := policyFor(issue).describe(issue); sla != "" {
+		summary += fmt.Sprintf("\n\n%s", sla)
+	}
+
+	var body bytes.Buffer
+	err = json.NewEncoder(&body).Encode(map[string]interface{}{
+		"name":         freshnessCheckName,
+		"head_sha":     pull.Head.SHA,
+		"status":       "completed",
+		"conclusion":   conclusion,
+		"completed_at": now.UTC().Format(time.RFC3339),
+		"output": map[string]string{
+			"title":   title,
+			"summary": summary,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/check-runs", *ghAPIFl, *ghOrgFl, repo)
+	req, err := http.NewRequest("POST", url, &body)
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	return nil
+}
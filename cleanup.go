@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SlackReminder is a reminder posted with Slack Web API.
+type SlackReminder struct {
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+	Text    string `json:"text"`
+}
+
+// hasLabel returns true if given issue has label with given name.
+func hasLabel(issue *Issue, name string) bool {
+	for _, label := range issue.Labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addLabel adds label with given name to given issue.
+func addLabel(issue *Issue, name string) error {
+	if err := githubOnly(); err != nil {
+		return err
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var body bytes.Buffer
+	err = json.NewEncoder(&body).Encode(map[string]interface{}{
+		"labels": []string{name},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/labels", *ghAPIFl, *ghOrgFl, repo, issue.Number)
+	req, err := http.NewRequest("POST", url, &body)
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	issue.Labels = append(issue.Labels, Label{Name: name})
+	recordAction(AuditEntry{Time: time.Now(), Key: issueKey(issue), Action: "labeled", Detail: name})
+	return nil
+}
+
+// removeLabel removes label with given name from given issue. Missing label
+// is not an error.
+func removeLabel(issue *Issue, name string) error {
+	if err := githubOnly(); err != nil {
+		return err
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	u := fmt.Sprintf("%s/repos/%s/%s/issues/%d/labels/%s", *ghAPIFl, *ghOrgFl, repo, issue.Number, url.PathEscape(name))
+	req, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create DELETE request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// cleanupResolved acknowledges pull request with given key that is no longer
+// stale, or was merged or closed. It removes the stale label, resolves pages,
+// marks Slack reminders about it as done and drops its undelivered
+// reminders. Issue is
+// fetched if nil.
+func cleanupResolved(key string, issue *Issue, phase Phase) {
+	if *staleLabelFl != "" {
+		var err error
+		if issue == nil {
+			issue, err = issueByKey(key)
+		}
+		if err != nil {
+			log.Printf("cannot remove stale label of %s: %s", key, err)
+		} else if hasLabel(issue, *staleLabelFl) {
+			if err := removeLabel(issue, *staleLabelFl); err != nil {
+				log.Printf("cannot remove stale label of %s: %s", key, err)
+			}
+		}
+	}
+
+	resolvePage(key)
+
+	if err := dropPending(key); err != nil {
+		log.Printf("cannot drop undelivered reminders of %s: %s", key, err)
+	}
+
+	if !*cleanupRemindersFl || *slackTokenFl == "" {
+		return
+	}
+	reminders := getState(key).Reminders
+	for _, r := range reminders {
+		err := slackAPI("chat.update", map[string]interface{}{
+			"channel": r.Channel,
+			"ts":      r.TS,
+			"text":    fmt.Sprintf("~%s~ :white_check_mark: %s", r.Text, phase),
+			// removes the buttons
+			"blocks": []interface{}{},
+		})
+		if err != nil {
+			log.Printf("cannot update reminder of %s: %s", key, err)
+		}
+	}
+	if len(reminders) == 0 {
+		return
+	}
+	err := updateState(key, func(s *PRState) {
+		s.Reminders = nil
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+}
+
+// dropPending removes undelivered messages about pull request with given
+// key.
+func dropPending(key string) error {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	var pending []slackMessage
+	if _, err := loadDocument(pendingKey, &pending); err != nil {
+		return err
+	}
+	kept := pending[:0]
+	for _, msg := range pending {
+		if msg.Key != key {
+			kept = append(kept, msg)
+		}
+	}
+	if len(kept) == len(pending) {
+		return nil
+	}
+	return saveDocument(pendingKey, kept)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"text/template"
+)
+
+// Config is the optional configuration file content, used for settings that
+// cannot be expressed by command line flags.
+type Config struct {
+	// Expertise is the list of rules used by the expertise assignment
+	// strategy.
+	Expertise []ExpertiseRule `json:"expertise"`
+	// LabelSLA maps label names to thresholds used for pull requests
+	// with that label.
+	LabelSLA map[string]SLA `json:"label_sla"`
+	// SizeSLA maps pull request size classes (XS, S, M, L, XL) to
+	// thresholds used for pull requests of that size.
+	SizeSLA map[string]SLA `json:"size_sla"`
+	// BranchSLA maps base branch glob patterns, for example "release/*",
+	// to thresholds used for pull requests targeting matching branches.
+	BranchSLA map[string]SLA `json:"branch_sla"`
+	// Leads is the list of team leads notified about escalated pull
+	// requests.
+	Leads []Lead `json:"leads"`
+	// Members maps github logins to settings of individual members.
+	Members map[string]Member `json:"members"`
+	// Holidays maps region names to ICS files or URLs with their public
+	// holidays. Regions mapped to empty string must be ISO 3166 country
+	// codes, their holidays are fetched from public holidays API.
+	Holidays map[string]string `json:"holidays"`
+	// External is the policy of pull requests opened by outside
+	// contributors.
+	External *ExternalPolicy `json:"external"`
+	// PrioritySLA maps JIRA priority names to thresholds used for pull
+	// requests implementing issues of that priority.
+	PrioritySLA map[string]SLA `json:"priority_sla"`
+	// Policies override settings for pull requests of matching
+	// repositories. The first matching policy is used.
+	Policies []RepoPolicy `json:"policies"`
+	// AutoMerge enables merging of approved pull requests.
+	AutoMerge *AutoMerge `json:"auto_merge"`
+	// Tenants are other teams served by the daemon, each with its own
+	// flags, configuration and state.
+	Tenants []Tenant `json:"tenants"`
+	// Messages maps locales to bot messages by message ID, overriding or
+	// adding to the bundled translations.
+	Messages map[string]map[string]string `json:"messages"`
+	// Description enables checking descriptions of stale pull requests.
+	Description *DescriptionCheck `json:"description"`
+	// SLOs are objectives for time to first review tracked across
+	// scans.
+	SLOs []SLO `json:"slos"`
+	// Ignore lists pull requests, as "repo#number" or URL, the bot must
+	// never touch.
+	Ignore []string `json:"ignore"`
+}
+
+var config Config
+
+// loadConfig reads JSON configuration from given file. Empty path is
+// allowed and results in empty configuration.
+func loadConfig(path string) error {
+	c, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	config = c
+	return nil
+}
+
+// readConfig reads and validates JSON configuration from given file.
+func readConfig(path string) (Config, error) {
+	var c Config
+	if path == "" {
+		return c, nil
+	}
+	fd, err := os.Open(path)
+	if err != nil {
+		return c, fmt.Errorf("cannot open file: %s", err)
+	}
+	defer fd.Close()
+
+	if err := json.NewDecoder(fd).Decode(&c); err != nil {
+		return c, fmt.Errorf("cannot decode %s: %s", path, err)
+	}
+	if c.AutoMerge != nil {
+		switch c.AutoMerge.Method {
+		case "", "merge", "squash", "rebase":
+		default:
+			return c, fmt.Errorf("invalid auto merge method %q", c.AutoMerge.Method)
+		}
+	}
+	for _, p := range c.Policies {
+		if _, ok := catalogs[p.Locale]; p.Locale != "" && !ok && c.Messages[p.Locale] == nil {
+			return c, fmt.Errorf("unknown locale %q of policy %v", p.Locale, p.Repos)
+		}
+		switch p.Teammates {
+		case "", "any", "avoid", "prefer":
+		default:
+			return c, fmt.Errorf("invalid teammates %q of policy %v", p.Teammates, p.Repos)
+		}
+	}
+	if err := checkVacations(c.Members); err != nil {
+		return c, err
+	}
+	if err := checkSLOs(c.SLOs); err != nil {
+		return c, err
+	}
+	if c.Description != nil {
+		for pattern := range c.Description.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return c, fmt.Errorf("invalid description pattern %q: %s", pattern, err)
+			}
+		}
+	}
+	if c.External != nil && c.External.Welcome != "" {
+		if _, err := template.New("welcome").Parse(c.External.Welcome); err != nil {
+			return c, fmt.Errorf("invalid external welcome template: %s", err)
+		}
+	}
+	return c, nil
+}
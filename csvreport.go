@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slaStatus returns ok for pull requests that are not stale, or severity of
+// stale ones.
+func slaStatus(issue *Issue, now time.Time) string {
+	policy := policyFor(issue)
+	since := staleSince(issue)
+	if since.Add(policy.Stale).After(now) {
+		return "ok"
+	}
+	return string(policy.severity(issue, since, now))
+}
+
+// reportColumns are the columns of the CSV report.
+var reportColumns = []string{
+	"repo", "number", "title", "url", "author", "assignee", "created_at",
+	"age_hours", "stale_hours", "labels", "review_state", "sla_status",
+}
+
+// csvSafe prefixes cells starting with =, +, -, @, tab or carriage return
+// with an apostrophe, so that spreadsheets do not evaluate titles or labels
+// as formulas.
+func csvSafe(row []string) []string {
+	for i, cell := range row {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			row[i] = "'" + cell
+		}
+	}
+	return row
+}
+
+// runReport runs the report subcommand with given arguments, writing all
+// open pull requests with given output format, only "csv" is supported.
+func runReport(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	format := fs.String("o", "csv", "Output format, csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "csv" {
+		return fmt.Errorf("unsupported output format %q, expected csv", *format)
+	}
+	issues, err := forge.OpenIssues()
+	if err != nil {
+		return fmt.Errorf("cannot list pull requests: %s", err)
+	}
+	var prs []*Issue
+	for i := range issues {
+		if issues[i].isPullRequest() {
+			prs = append(prs, &issues[i])
+		}
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].CreatedAt.Before(prs[j].CreatedAt)
+	})
+
+	now := time.Now()
+	cw := csv.NewWriter(w)
+	if err := cw.Write(reportColumns); err != nil {
+		return err
+	}
+	for _, issue := range prs {
+		repo, _ := issue.GetRepository()
+		assignee := ""
+		if issue.Assignee != nil {
+			assignee = issue.Assignee.Login
+		}
+		labels := make([]string, 0, len(issue.Labels))
+		for _, l := range issue.Labels {
+			labels = append(labels, l.Name)
+		}
+		review, err := reviewState(issue)
+		if err != nil {
+			return fmt.Errorf("cannot get review state of %s: %s", issueKey(issue), err)
+		}
+		err = cw.Write(csvSafe([]string{
+			repo,
+			strconv.FormatInt(issue.Number, 10),
+			issue.Title,
+			issue.HTMLURL,
+			issue.User.Login,
+			assignee,
+			issue.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.Itoa(int(now.Sub(issue.CreatedAt).Hours())),
+			strconv.Itoa(int(now.Sub(staleSince(issue)).Hours())),
+			strings.Join(labels, ","),
+			review,
+			slaStatus(issue, now),
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
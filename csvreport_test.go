@@ -0,0 +1,23 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCSVSafe(t *testing.T) {
+	tests := []struct {
+		row  []string
+		want []string
+	}{
+		{row: []string{"", "Fix login", "bug"}, want: []string{"", "Fix login", "bug"}},
+		{row: []string{"=1+2", "+cmd", "-2", "@SUM(A1)"}, want: []string{"'=1+2", "'+cmd", "'-2", "'@SUM(A1)"}},
+		// spreadsheets strip leading whitespace before evaluating
+		{row: []string{"\t=1+2", "\r=1+2", "a\t=1"}, want: []string{"'\t=1+2", "'\r=1+2", "a\t=1"}},
+	}
+	for _, tt := range tests {
+		if got := csvSafe(append([]string(nil), tt.row...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("csvSafe(%q) = %q, want %q", tt.row, got, tt.want)
+		}
+	}
+}
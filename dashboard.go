@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StalePR is a stale pull request as seen by the last scan.
+type StalePR struct {
+	Repo     string        `json:"repo"`
+	Number   int64         `json:"number"`
+	Title    string        `json:"title"`
+	URL      string        `json:"url"`
+	Author   string        `json:"author"`
+	Assignee string        `json:"assignee"`
+	Age      time.Duration `json:"-"`
+	Labels   []string      `json:"labels"`
+	CI       string        `json:"ci"`
+	Old      bool          `json:"old"`
+	Phase    Phase         `json:"phase"`
+	// AgeSeconds is the time since the pull request became stale.
+	AgeSeconds int64 `json:"age_seconds"`
+}
+
+// scanView is the result of the last scan, served by the dashboard, the
+// API, /lifecycle and /metrics. It holds everything they show, so that they
+// do not read globals, which tenant scans swap.
+type scanView struct {
+	At  time.Time
+	PRs []StalePR
+	// Members are logins of all team members.
+	Members []string
+	// Teams maps logins of members to their teams, if configured.
+	Teams map[string]string
+	// States maps keys of tracked pull requests to their states.
+	States map[string]PRState
+}
+
+var (
+	viewMu sync.Mutex
+	// views maps tenant scopes to results of their last scans.
+	views = map[string]scanView{}
+)
+
+// ciStatus returns combined state of commit statuses and check runs of the
+// pull request's head commit: success, pending, failure, or empty string
+// if there are none.
+func ciStatus(issue *Issue) (string, error) {
+	ciMu.Lock()
+	state, ok := ciCache[issue.ID]
+	ciMu.Unlock()
+	if ok {
+		return state, nil
+	}
+	pull, err := getPull(issue)
+	if err != nil {
+		return "", err
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return "", err
+	}
+	base := fmt.Sprintf("%s/repos/%s/%s/commits/%s", *ghAPIFl, *ghOrgFl, repo, pull.Head.SHA)
+
+	var status struct {
+		State      string        `json:"state"`
+		TotalCount int           `json:"total_count"`
+		Statuses   []interface{} `json:"statuses"`
+	}
+	if err := githubGet(base+"/status", &status); err != nil {
+		return "", err
+	}
+	var checks struct {
+		CheckRuns []struct {
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}
+	err = githubGetPages(base+"/check-runs?per_page=100", func(raw json.RawMessage) error {
+		var runs struct {
+			CheckRuns []struct {
+				Status     string `json:"status"`
+				Conclusion string `json:"conclusion"`
+			} `json:"check_runs"`
+		}
+		if err := json.Unmarshal(raw, &runs); err != nil {
+			return fmt.Errorf("cannot decode response: %s", err)
+		}
+		checks.CheckRuns = append(checks.CheckRuns, runs.CheckRuns...)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	state = ""
+	if len(status.Statuses) > 0 {
+		state = status.State
+	}
+	for _, run := range checks.CheckRuns {
+		switch {
+		case run.Status != "completed":
+			if state == "" || state == "success" {
+				state = "pending"
+			}
+		case run.Conclusion == "failure" || run.Conclusion == "timed_out" || run.Conclusion == "cancelled":
+			state = "failure"
+		default:
+			if state == "" {
+				state = "success"
+			}
+		}
+	}
+	ciMu.Lock()
+	ciCache[issue.ID] = state
+	ciMu.Unlock()
+	return state, nil
+}
+
+// githubGet decodes JSON response of github API GET request into v.
+func githubGet(url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &responseError{Status: resp.StatusCode}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("cannot decode response: %s", err)
+	}
+	return nil
+}
+
+// dashboardEnabled returns true if the daemon serves the dashboard or the
+// API, which show CI status of stale pull requests.
+func dashboardEnabled() bool {
+	return *listenFl != "" && (*apiTokenFl != "" || *publicDashboardFl)
+}
+
+// recordView remembers handled stale pull requests, members and phases of
+// tracked pull requests for the dashboard.
+func recordView(stale []Issue, now time.Time) {
+	v := scanView{At: now, PRs: stalePRList(stale, now, dashboardEnabled()), Teams: map[string]string{}, States: map[string]PRState{}}
+	if members, err := listMembers(); err == nil {
+		for _, m := range members {
+			v.Members = append(v.Members, m.Login)
+		}
+	}
+	for login, m := range config.Members {
+		if m.Team != "" {
+			v.Teams[login] = m.Team
+		}
+	}
+	var tracked []string
+	if _, err := loadDocument(trackedKey, &tracked); err != nil {
+		log.Printf("cannot load tracked pull requests: %s", err)
+	}
+	for _, key := range tracked {
+		v.States[key] = getState(key)
+	}
+	viewMu.Lock()
+	views[tenantScope()] = v
+	viewMu.Unlock()
+}
+
+// stalePRList returns given stale pull requests as shown on the dashboard,
+// the oldest first. CI status, which takes API requests for every pull
+// request, is included only if withCI is true.
+func stalePRList(stale []Issue, now time.Time, withCI bool) []StalePR {
+	prs := make([]StalePR, 0, len(stale))
+	for i := range stale {
+		issue := &stale[i]
+		repo, _ := issue.GetRepository()
+		since := staleSince(issue)
+		pr := StalePR{
+			Repo:   repo,
+			Number: issue.Number,
+			Title:  issue.Title,
+			URL:    issue.HTMLURL,
+			Author: issue.User.Login,
+			Age:    now.Sub(since),
+			Labels: []string{},
+			Old:    since.Add(policyFor(issue).Old).Before(now),
+			Phase:  getState(issueKey(issue)).Phase,
+		}
+		pr.AgeSeconds = int64(pr.Age / time.Second)
+		if issue.Assignee != nil {
+			pr.Assignee = issue.Assignee.Login
+		}
+		for _, l := range issue.Labels {
+			pr.Labels = append(pr.Labels, l.Name)
+		}
+		if withCI && githubOnly() == nil {
+			ci, err := ciStatus(issue)
+			if err != nil {
+				log.Printf("cannot get CI status of #%d: %s", issue.Number, err)
+			}
+			pr.CI = ci
+		}
+		prs = append(prs, pr)
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].Age > prs[j].Age
+	})
+	return prs
+}
+
+// currentView returns result of the last scan of the daemon's own team.
+func currentView() scanView {
+	viewMu.Lock()
+	defer viewMu.Unlock()
+	return views[""]
+}
+
+// assignmentCounts returns number of stale pull requests assigned to each
+// member, including members without any.
+func (v scanView) assignmentCounts() map[string]int {
+	counts := map[string]int{}
+	for _, login := range v.Members {
+		counts[login] = 0
+	}
+	for _, pr := range v.PRs {
+		if pr.Assignee != "" {
+			counts[pr.Assignee]++
+		}
+	}
+	return counts
+}
+
+var dashboardTmpl = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"age": formatAge,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Stale pull requests</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { padding: 4px 8px; text-align: left; border-bottom: 1px solid #ddd; }
+.old { background: #fdecea; }
+.label { background: #eee; border-radius: 3px; padding: 0 4px; font-size: 85%; }
+.ci-success { color: #1a7f37; } .ci-failure { color: #cf222e; } .ci-pending { color: #9a6700; }
+.bar { background: #0969da; height: 1em; }
+</style>
+</head>
+<body>
+<h1>Stale pull requests</h1>
+<p>{{len .PRs}} stale pull requests as of {{.At.Format "2006-01-02 15:04 MST"}}. Grouped by
+{{if eq .GroupBy "assignee"}}assignee, <a href="?group=repo">group by repository</a>{{else}}repository, <a href="?group=assignee">group by assignee</a>{{end}}.</p>
+{{range .Groups}}
+<h2>{{.Name}}</h2>
+<table>
+<tr><th>Pull request</th><th>Repository</th><th>Author</th><th>Assignee</th><th>Stale for</th><th>Phase</th><th>CI</th><th>Labels</th></tr>
+{{range .PRs}}
+<tr{{if .Old}} class="old"{{end}}>
+<td><a href="{{.URL}}">#{{.Number}} {{.Title}}</a></td>
+<td>{{.Repo}}</td>
+<td>{{.Author}}</td>
+<td>{{or .Assignee "unassigned"}}</td>
+<td>{{age .Age}}</td>
+<td>{{.Phase}}</td>
+<td class="ci-{{.CI}}">{{.CI}}</td>
+<td>{{range .Labels}}<span class="label">{{.}}</span> {{end}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+<h2>Assignments per member</h2>
+<table>
+{{range .Counts}}
+<tr><td>{{.Name}}</td><td>{{.Count}}</td><td><div class="bar" style="width: {{.Width}}px"></div></td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// dashboardHandler serves HTML page listing stale pull requests of the last
+// scan, grouped by repository or, with group=assignee, by assignee.
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	v := currentView()
+	groupBy := r.URL.Query().Get("group")
+
+	type group struct {
+		Name string
+		PRs  []StalePR
+	}
+	var groups []group
+	index := map[string]int{}
+	for _, pr := range v.PRs {
+		name := pr.Repo
+		if groupBy == "assignee" {
+			name = pr.Assignee
+			if name == "" {
+				name = "unassigned"
+			}
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, group{Name: name})
+		}
+		groups[i].PRs = append(groups[i].PRs, pr)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	type count struct {
+		Name  string
+		Count int
+		Width int
+	}
+	var counts []count
+	for name, n := range v.assignmentCounts() {
+		counts = append(counts, count{Name: name, Count: n, Width: n * 20})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+
+	err := dashboardTmpl.Execute(w, map[string]interface{}{
+		"At":      v.At,
+		"PRs":     v.PRs,
+		"GroupBy": groupBy,
+		"Groups":  groups,
+		"Counts":  counts,
+	})
+	if err != nil {
+		log.Printf("cannot render dashboard: %s", err)
+	}
+}
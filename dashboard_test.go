@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStalePRListCI(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/repos/acme/api/pulls/1":
+			w.Write([]byte(`{"head": {"sha": "abc"}}`))
+		case "/repos/acme/api/commits/abc/status":
+			w.Write([]byte(`{"state": "pending", "statuses": []}`))
+		case "/repos/acme/api/commits/abc/check-runs":
+			w.Write([]byte(`{"check_runs": [{"status": "completed", "conclusion": "failure"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(api, org, provider string) {
+		*ghAPIFl, *ghOrgFl, *providerFl = api, org, provider
+	}(*ghAPIFl, *ghOrgFl, *providerFl)
+	defer func() { ciCache, pullsCache = map[int64]string{}, map[int64]*Pull{} }()
+	*ghAPIFl, *ghOrgFl, *providerFl = srv.URL, "acme", "github"
+	ciCache, pullsCache = map[int64]string{}, map[int64]*Pull{}
+
+	now := time.Now()
+	stale := []Issue{{ID: 1, Number: 1, Repo: "api", User: &User{Login: "alice"}, CreatedAt: now.Add(-72 * time.Hour)}}
+	if prs := stalePRList(stale, now, false); prs[0].CI != "" || calls != 0 {
+		t.Errorf("stalePRList() without CI = %q after %d requests, want none", prs[0].CI, calls)
+	}
+	for i := 0; i < 2; i++ {
+		if prs := stalePRList(stale, now, true); prs[0].CI != "failure" {
+			t.Errorf("stalePRList() CI = %q, want failure", prs[0].CI)
+		}
+	}
+	// the second list reuses cached CI status
+	if calls != 3 {
+		t.Errorf("CI status took %d requests, want 3", calls)
+	}
+}
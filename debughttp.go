@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// rateLimitHeaders are response headers logged by debugTransport.
+var rateLimitHeaders = []string{
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"X-RateLimit-Resource",
+	"Retry-After",
+}
+
+// debugTransport logs every outbound request and its response, with
+// secrets redacted.
+type debugTransport struct {
+	base http.RoundTripper
+	// body is the number of bytes of request and response bodies logged,
+	// 0 disables logging of bodies.
+	body int
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if t.body > 0 && req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read request body: %s", err)
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	took := time.Since(start).Round(time.Millisecond)
+	target := req.Method + " " + redactURL(req.URL)
+	if err != nil {
+		log.Printf("http: %s failed after %s: %s", target, took, redact(err.Error()))
+		return nil, err
+	}
+
+	var limits []string
+	for _, h := range rateLimitHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			limits = append(limits, h+"="+v)
+		}
+	}
+	log.Printf("http: %s %d in %s %s", target, resp.StatusCode, took, strings.Join(limits, " "))
+	if t.body == 0 {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %s", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if secretBackend(req.URL) {
+		reqBody, body = []byte(redacted), []byte(redacted)
+	}
+	if len(reqBody) > 0 {
+		log.Printf("http: %s request body: %s", target, t.truncate(redact(string(reqBody))))
+	}
+	log.Printf("http: %s response body: %s", target, t.truncate(redact(string(body))))
+	return resp, nil
+}
+
+// truncate returns given body shortened to the logged number of bytes.
+func (t *debugTransport) truncate(body string) string {
+	if len(body) <= t.body {
+		return body
+	}
+	return body[:t.body] + "..."
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DescriptionCheck describes what descriptions of stale pull requests must
+// contain. Authors of pull requests that fall short are asked to add
+// context in the bot's comments.
+type DescriptionCheck struct {
+	// MinLength is the minimum length of the description in characters,
+	// not counting HTML comments and surrounding whitespace.
+	MinLength int `json:"min_length"`
+	// Sections are headings of the pull request template that must be
+	// present and followed by some text.
+	Sections []string `json:"sections"`
+	// Patterns maps regular expressions the description must match to
+	// descriptions of what is missing when it does not.
+	Patterns map[string]string `json:"patterns"`
+}
+
+var (
+	htmlCommentRegex = regexp.MustCompile(`(?s)<!--.*?-->`)
+	headingRegex     = regexp.MustCompile(`(?m)^#{1,6}\s+(.+?)\s*#*\s*$`)
+)
+
+// descriptionSections returns text of markdown sections of given
+// description by lower case heading.
+func descriptionSections(body string) map[string]string {
+	sections := map[string]string{}
+	matches := headingRegex.FindAllStringSubmatchIndex(body, -1)
+	for i, m := range matches {
+		end := len(body)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		heading := strings.ToLower(body[m[2]:m[3]])
+		sections[heading] = strings.TrimSpace(body[m[1]:end])
+	}
+	return sections
+}
+
+// descriptionProblems returns what the description of given pull request
+// lacks according to the description check, nil if nothing.
+func descriptionProblems(issue *Issue) []string {
+	c := config.Description
+	if c == nil {
+		return nil
+	}
+	body := strings.TrimSpace(htmlCommentRegex.ReplaceAllString(issue.Body, ""))
+	var problems []string
+	if len([]rune(body)) < c.MinLength {
+		problems = append(problems, tr(issue, "description_short"))
+	}
+	if len(c.Sections) > 0 {
+		sections := descriptionSections(body)
+		for _, name := range c.Sections {
+			if sections[strings.ToLower(name)] == "" {
+				problems = append(problems, tr(issue, "description_section", name))
+			}
+		}
+	}
+	var missing []string
+	for pattern, what := range c.Patterns {
+		// patterns are validated when the configuration is loaded
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(body) {
+			missing = append(missing, what)
+		}
+	}
+	sort.Strings(missing)
+	return append(problems, missing...)
+}
+
+// descriptionNudge returns text asking author of given pull request to
+// improve its description, empty if the description is fine.
+func descriptionNudge(issue *Issue) string {
+	problems := descriptionProblems(issue)
+	if len(problems) == 0 {
+		return ""
+	}
+	return tr(issue, "description_nudge", issue.User.Login, strings.Join(problems, ", "))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// discordColors maps Slack attachment colors to Discord embed colors.
+var discordColors = map[string]int{
+	"warning": 0xdaa038,
+	"danger":  0xa30200,
+}
+
+// discordNotifier posts messages to a Discord channel using its webhook.
+type discordNotifier struct {
+	url string
+}
+
+// discordFormat converts Slack formatted text to Discord markdown.
+var discordFormat = chatFormat{
+	Link: func(url, label string) string {
+		if url == label {
+			return url
+		}
+		return fmt.Sprintf("[%s](%s)", label, url)
+	},
+	Mention: func(login string) string {
+		if id := config.Members[login].Discord; id != "" {
+			return fmt.Sprintf("<@%s>", id)
+		}
+		return ""
+	},
+	Broadcast: func(name string) string {
+		if name == "channel" {
+			return "@everyone"
+		}
+		return "@here"
+	},
+}
+
+func (n *discordNotifier) Notify(msg slackMessage) error {
+	payload := map[string]interface{}{
+		"username": "github-pr",
+		"content":  discordFormat.convert(msg.Text),
+		"allowed_mentions": map[string]interface{}{
+			"parse": []string{"users", "everyone"},
+		},
+	}
+	if issue := msg.Issue; issue != nil {
+		repo, _ := issue.GetRepository()
+		assignee := tr(issue, "nobody")
+		if issue.Assignee != nil {
+			assignee = discordFormat.plain("@" + issue.Assignee.Login)
+		}
+		title := fmt.Sprintf("%s#%d %s", repo, issue.Number, issue.Title)
+		if len(title) > 256 {
+			title = title[:253] + "..."
+		}
+		embed := map[string]interface{}{
+			"title": title,
+			"url":   issue.HTMLURL,
+			"fields": []interface{}{
+				map[string]interface{}{"name": tr(issue, "field_repository"), "value": repo, "inline": true},
+				map[string]interface{}{"name": tr(issue, "field_age"), "value": formatAge(time.Since(issue.CreatedAt)), "inline": true},
+				map[string]interface{}{"name": tr(issue, "field_assignee"), "value": assignee, "inline": true},
+			},
+		}
+		if color, ok := discordColors[msg.Color]; ok {
+			embed["color"] = color
+		}
+		payload["embeds"] = []interface{}{embed}
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode payload: %s", err)
+	}
+	resp, err := httpClient.Post(n.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("cannot POST to Discord: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected Discord response: %d, %s", resp.StatusCode, body)
+	}
+	return nil
+}
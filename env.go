@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix prefixes environment variable names of flags, so that common
+// variables like VERSION, EXEC or CONFIG set for other programs do not
+// change flags of the bot.
+const envPrefix = "STALE_PR_BOT_"
+
+// envNames maps flag names to environment variable names that differ from
+// the default prefixed, upper case, underscore separated flag name.
+var envNames = map[string]string{
+	"github-api":   "GH_API",
+	"user":         "GH_USER",
+	"pass":         "GH_PASS",
+	"auth-key":     "GH_AUTH_KEY",
+	"organization": "GH_ORGANIZATION",
+	"team-id":      "GH_TEAM_ID",
+	"stale":        "STALE_DURATION",
+	"old":          "OLD_DURATION",
+	"slack-url":    "SLACK_URL",
+
+	"otlp-endpoint":     "OTEL_EXPORTER_OTLP_ENDPOINT",
+	"otlp-headers":      "OTEL_EXPORTER_OTLP_HEADERS",
+	"otlp-service-name": "OTEL_SERVICE_NAME",
+}
+
+// envName returns name of the environment variable equivalent to the flag
+// with given name.
+func envName(flagName string) string {
+	if name, ok := envNames[flagName]; ok {
+		return name
+	}
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flagName))
+}
+
+// documentEnv appends environment variable names to usage of all flags.
+// Must be called before the flags are parsed.
+func documentEnv() {
+	flag.VisitAll(func(f *flag.Flag) {
+		f.Usage = fmt.Sprintf("%s (env %s)", f.Usage, envName(f.Name))
+	})
+}
+
+// applyEnv sets flags that were not given on the command line from the
+// environment variables. Must be called after the flags are parsed, so that
+// flags override the environment.
+func applyEnv() error {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || err != nil {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if e := f.Value.Set(value); e != nil {
+			err = fmt.Errorf("invalid %s value %q: %s", envName(f.Name), value, e)
+		}
+	})
+	return err
+}
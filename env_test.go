@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		flag, want string
+	}{
+		{flag: "max-assignments-per-user", want: "STALE_PR_BOT_MAX_ASSIGNMENTS_PER_USER"},
+		{flag: "version", want: "STALE_PR_BOT_VERSION"},
+		{flag: "auth-key", want: "GH_AUTH_KEY"},
+		{flag: "slack-url", want: "SLACK_URL"},
+		{flag: "stale", want: "STALE_DURATION"},
+		{flag: "otlp-endpoint", want: "OTEL_EXPORTER_OTLP_ENDPOINT"},
+	}
+	for _, tt := range tests {
+		if got := envName(tt.flag); got != tt.want {
+			t.Errorf("envName(%q) = %q, want %q", tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEnvIgnoresUnrelatedVariables(t *testing.T) {
+	defer func(values map[string]string) {
+		for name, value := range values {
+			flag.Lookup(name).Value.Set(value)
+		}
+	}(map[string]string{"version": flag.Lookup("version").Value.String(), "slack-url": *slackURLFl})
+	for name, value := range map[string]string{
+		"VERSION":   "1.2.3",
+		"EXEC":      "rm -rf /",
+		"CONFIG":    "/etc/other.json",
+		"SLACK_URL": "https://hooks.slack.com/services/T/B/X",
+	} {
+		old, ok := os.LookupEnv(name)
+		os.Setenv(name, value)
+		if ok {
+			defer os.Setenv(name, old)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+
+	if err := applyEnv(); err != nil {
+		t.Fatalf("applyEnv() error = %v", err)
+	}
+	for _, name := range []string{"version", "exec", "config"} {
+		if f := flag.Lookup(name); f != nil && f.Value.String() != f.DefValue {
+			t.Errorf("-%s = %q, want default %q", name, f.Value.String(), f.DefValue)
+		}
+	}
+	if *slackURLFl != "https://hooks.slack.com/services/T/B/X" {
+		t.Errorf("-slack-url = %q, want SLACK_URL", *slackURLFl)
+	}
+}
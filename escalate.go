@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Lead is the team lead notified about pull requests that were not
+// progressing for too long. Lead is responsible for pull requests of
+// repositories matching any of the globs, and for pull requests authored by
+// or assigned to members of any of the teams.
+type Lead struct {
+	// Repos is the list of repository name globs.
+	Repos []string `json:"repos"`
+	// Teams is the list of github team slugs.
+	Teams []string `json:"teams"`
+	// GitHub is the github login of the lead.
+	GitHub string `json:"github"`
+	// Slack is the Slack member ID of the lead, for example U024BE7LH.
+	Slack string `json:"slack"`
+}
+
+// mention returns Slack mention of the lead.
+func (l *Lead) mention() string {
+	if l.Slack != "" {
+		return fmt.Sprintf("<@%s>", l.Slack)
+	}
+	return "@" + l.GitHub
+}
+
+// responsibleFor returns true if lead is responsible for given issue.
+func (l *Lead) responsibleFor(issue *Issue) (bool, error) {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return false, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	for _, glob := range l.Repos {
+		if globMatch(glob, repo) {
+			return true, nil
+		}
+	}
+	for _, slug := range l.Teams {
+		members, err := teamMembers(slug)
+		if err != nil {
+			return false, fmt.Errorf("cannot list %q team members: %s", slug, err)
+		}
+		for _, m := range members {
+			if m.Login == issue.User.Login || (issue.Assignee != nil && m.Login == issue.Assignee.Login) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// leadsFor returns all leads responsible for given issue.
+func leadsFor(issue *Issue) ([]Lead, error) {
+	var leads []Lead
+	for _, lead := range config.Leads {
+		ok, err := lead.responsibleFor(issue)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			leads = append(leads, lead)
+		}
+	}
+	return leads, nil
+}
+
+// leadLogins returns github mentions of given leads that have github login.
+func leadLogins(leads []Lead) []string {
+	var logins []string
+	for _, lead := range leads {
+		if lead.GitHub != "" {
+			logins = append(logins, "@"+lead.GitHub)
+		}
+	}
+	return logins
+}
+
+// escalateToLead notifies all leads responsible for given pull request that
+// it is not progressing, on the channels switched on for escalations.
+func escalateToLead(issue *Issue) error {
+	leads, err := leadsFor(issue)
+	if err != nil {
+		return fmt.Errorf("cannot find leads: %s", err)
+	}
+	if len(leads) == 0 {
+		return errors.New("no lead configured")
+	}
+	mentions := make([]string, 0, len(leads))
+	for _, lead := range leads {
+		mentions = append(mentions, lead.mention())
+	}
+	log.Printf("Escalating PR #%d (%s) to %s\n", issue.Number, issue.Title, strings.Join(mentions, ", "))
+	text := tr(issue, "escalate", strings.Join(mentions, " "), issue.HTMLURL, issue.Number, issue.Title, issue.Assignee.Login)
+	if sla := policyFor(issue).describe(issue); sla != "" {
+		text += fmt.Sprintf(" [%s]", sla)
+	}
+	if commentOn(issue, "escalate") && len(leadLogins(leads)) > 0 {
+		comment := tr(issue, "escalate_comment", strings.Join(leadLogins(leads), " "), issue.Assignee.Login)
+		if err := forge.Comment(issue, comment); err != nil {
+			return fmt.Errorf("cannot comment on #%d pull request: %s", issue.Number, err)
+		}
+	}
+	if !notifyOn(issue, "escalate") {
+		return nil
+	}
+	if *slackTokenFl == "" {
+		return postSlack(text)
+	}
+	// leads with Slack ID are messaged directly, the channel is used only
+	// when some lead cannot be
+	channel := false
+	for _, lead := range leads {
+		if lead.Slack == "" {
+			channel = true
+			continue
+		}
+		if err := directMessage(lead.Slack, text); err != nil {
+			log.Printf("cannot message lead %s directly: %s", lead.Slack, err)
+			channel = true
+		}
+	}
+	if channel {
+		return postSlack(text)
+	}
+	return nil
+}
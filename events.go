@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event describes a decision of the bot about a pull request. It is encoded
+// as CloudEvents structured JSON.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Time            time.Time `json:"time"`
+	Subject         string    `json:"subject"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            EventData `json:"data"`
+}
+
+// EventData is the payload of an event.
+type EventData struct {
+	Phase    Phase  `json:"phase"`
+	Repo     string `json:"repo"`
+	Number   int    `json:"number"`
+	URL      string `json:"url,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Assignee string `json:"assignee,omitempty"`
+	// Age is the age of the pull request in seconds.
+	Age int64 `json:"age,omitempty"`
+}
+
+// Publisher delivers events to an external system.
+type Publisher interface {
+	Publish(e *Event) error
+}
+
+// publishers are all configured event destinations.
+var publishers []Publisher
+
+// newPublisher returns publisher for given "nats://..." or
+// "kafka+http(s)://..." URL.
+func newPublisher(rawurl string) (Publisher, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid events url: %s", err)
+	}
+	subject := strings.Trim(u.Path, "/")
+	if subject == "" {
+		return nil, fmt.Errorf("events url %q has no subject or topic", rawurl)
+	}
+	switch u.Scheme {
+	case "nats":
+		p := &natsPublisher{addr: u.Host, subject: subject}
+		if !strings.Contains(p.addr, ":") {
+			p.addr += ":4222"
+		}
+		if u.User != nil {
+			p.user = u.User.Username()
+			p.pass, _ = u.User.Password()
+		}
+		return p, nil
+	case "kafka+http", "kafka+https":
+		scheme := strings.TrimPrefix(u.Scheme, "kafka+")
+		return &kafkaRESTPublisher{url: fmt.Sprintf("%s://%s/topics/%s", scheme, u.Host, url.PathEscape(subject))}, nil
+	}
+	return nil, fmt.Errorf("unsupported events url scheme %q", u.Scheme)
+}
+
+// emit publishes event about pull request with given key entering given
+// phase. Issue is nil for pull requests that are no longer open. Errors are
+// logged.
+func emit(phase Phase, key string, issue *Issue, now time.Time) {
+	if len(publishers) == 0 {
+		return
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	e := &Event{
+		SpecVersion:     "1.0",
+		ID:              hex.EncodeToString(id),
+		Source:          "github-stale-pr-bot",
+		Type:            "github-stale-pr-bot.pull-request." + string(phase),
+		Time:            now,
+		Subject:         key,
+		DataContentType: "application/json",
+		Data:            EventData{Phase: phase},
+	}
+	if i := strings.LastIndex(key, "#"); i >= 0 {
+		e.Data.Repo = key[:i]
+		fmt.Sscan(key[i+1:], &e.Data.Number)
+	}
+	if issue != nil {
+		e.Data.URL = issue.HTMLURL
+		e.Data.Title = issue.Title
+		e.Data.Author = issue.User.Login
+		if issue.Assignee != nil {
+			e.Data.Assignee = issue.Assignee.Login
+		}
+		e.Data.Age = int64(now.Sub(issue.CreatedAt) / time.Second)
+	}
+	for _, p := range publishers {
+		if err := p.Publish(e); err != nil {
+			log.Printf("cannot publish %s event of %s: %s", phase, key, err)
+		}
+	}
+}
+
+// natsPublisher publishes events to a NATS subject using the NATS text
+// protocol. Every publish is confirmed with a PING, so that errors are not
+// missed.
+type natsPublisher struct {
+	addr    string
+	user    string
+	pass    string
+	subject string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (p *natsPublisher) Publish(e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("cannot encode event: %s", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+	if err := p.publish(b); err != nil {
+		p.conn.Close()
+		p.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (p *natsPublisher) connect() error {
+	conn, err := net.DialTimeout("tcp", p.addr, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("cannot connect to nats: %s", err)
+	}
+	p.conn = conn
+	p.r = bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	// server greets with INFO
+	if _, err := p.r.ReadString('\n'); err != nil {
+		conn.Close()
+		p.conn = nil
+		return fmt.Errorf("cannot read server info: %s", err)
+	}
+	opts, _ := json.Marshal(map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "github-stale-pr-bot",
+		"user":     p.user,
+		"pass":     p.pass,
+	})
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", opts); err != nil {
+		conn.Close()
+		p.conn = nil
+		return fmt.Errorf("cannot connect to nats: %s", err)
+	}
+	return nil
+}
+
+func (p *natsPublisher) publish(payload []byte) error {
+	p.conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if _, err := fmt.Fprintf(p.conn, "PUB %s %d\r\n%s\r\nPING\r\n", p.subject, len(payload), payload); err != nil {
+		return fmt.Errorf("cannot publish: %s", err)
+	}
+	for {
+		line, err := p.r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("cannot read reply: %s", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			fmt.Fprint(p.conn, "PONG\r\n")
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats error: %s", strings.TrimSpace(line[4:]))
+		}
+	}
+}
+
+// kafkaRESTPublisher produces events to a Kafka topic through Confluent
+// REST Proxy.
+type kafkaRESTPublisher struct {
+	url string
+}
+
+func (p *kafkaRESTPublisher) Publish(e *Event) error {
+	b, err := json.Marshal(map[string]interface{}{
+		"records": []interface{}{
+			map[string]interface{}{"key": e.Subject, "value": e},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode event: %s", err)
+	}
+	resp, err := httpClient.Post(p.url, "application/vnd.kafka.json.v2+json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("cannot POST data: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response: %d, %s", resp.StatusCode, body)
+	}
+	return nil
+}
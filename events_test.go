@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmitKafka(t *testing.T) {
+	var records []struct {
+		Key   string `json:"key"`
+		Value Event  `json:"value"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/pr-events" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Records []struct {
+				Key   string `json:"key"`
+				Value Event  `json:"value"`
+			} `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		records = append(records, body.Records...)
+	}))
+	defer srv.Close()
+	p, err := newPublisher("kafka+" + srv.URL + "/pr-events")
+	if err != nil {
+		t.Fatalf("newPublisher() error = %v", err)
+	}
+	defer func(p []Publisher) { publishers = p }(publishers)
+	publishers = []Publisher{p}
+
+	now := time.Now()
+	issue := &Issue{Title: "Fix login", User: &User{Login: "alice"}, Assignee: &User{Login: "bob"}, CreatedAt: now.Add(-time.Hour)}
+	emit(PhaseAssigned, "api#1", issue, now)
+	if len(records) != 1 {
+		t.Fatalf("emit() produced %d records, want 1", len(records))
+	}
+	r := records[0]
+	if r.Key != "api#1" || r.Value.Type != "github-stale-pr-bot.pull-request.assigned" {
+		t.Errorf("record key %q and type %q", r.Key, r.Value.Type)
+	}
+	if d := r.Value.Data; d.Repo != "api" || d.Number != 1 || d.Assignee != "bob" || d.Age != 3600 {
+		t.Errorf("record data = %+v", d)
+	}
+}
+
+func TestNatsPublisher(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %s", err)
+	}
+	defer l.Close()
+	published := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("INFO {}\r\n"))
+		connect, _ := r.ReadString('\n')
+		if !strings.Contains(connect, `"user":"bot"`) {
+			conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+			return
+		}
+		pub, _ := r.ReadString('\n')
+		payload, _ := r.ReadString('\n')
+		r.ReadString('\n') // PING
+		conn.Write([]byte("PONG\r\n"))
+		published <- strings.TrimSpace(pub) + " " + payload
+	}()
+
+	p, err := newPublisher("nats://bot:secret@" + l.Addr().String() + "/pr.events")
+	if err != nil {
+		t.Fatalf("newPublisher() error = %v", err)
+	}
+	if err := p.Publish(&Event{Subject: "api#1", Type: "github-stale-pr-bot.pull-request.stale"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	got := <-published
+	if !strings.HasPrefix(got, "PUB pr.events ") || !strings.Contains(got, `"subject":"api#1"`) {
+		t.Errorf("published %q", got)
+	}
+}
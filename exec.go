@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execPublisher runs external command for every event, like git hooks. The
+// event is written to its standard input as JSON and the command responds
+// with execResponse JSON on standard output.
+type execPublisher struct {
+	command string
+	timeout time.Duration
+}
+
+// execResponse is the acknowledgement of the external command. Empty output
+// is a successful acknowledgement.
+type execResponse struct {
+	// OK is false if the command failed to handle the event.
+	OK *bool `json:"ok"`
+	// Error describes why the command failed.
+	Error string `json:"error"`
+	// Snooze is the duration, for example "4h", for which reminders about
+	// the pull request should not be sent.
+	Snooze Duration `json:"snooze"`
+}
+
+func (p *execPublisher) Publish(e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("cannot encode event: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", p.command)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Env = append(os.Environ(), "STALE_PR_BOT_EVENT="+string(e.Data.Phase))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp execResponse
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return fmt.Errorf("cannot decode command output: %s", err)
+		}
+	}
+	if resp.OK != nil && !*resp.OK {
+		if resp.Error == "" {
+			return errors.New("command did not acknowledge the event")
+		}
+		return fmt.Errorf("command failed: %s", resp.Error)
+	}
+	if resp.Snooze > 0 {
+		until := e.Time.Add(time.Duration(resp.Snooze))
+		return updateState(e.Subject, func(s *PRState) {
+			if until.After(s.SnoozedUntil) {
+				s.SnoozedUntil = until
+			}
+		})
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ExpertiseRule maps changed files to preferred reviewers. A file matches the
+// rule if it matches any of the path globs or is written in any of the
+// languages.
+type ExpertiseRule struct {
+	// Paths is the list of globs, for example "frontend/**" or "*.tf".
+	// Globs without slash are matched against the file name only.
+	Paths []string `json:"paths"`
+	// Languages is the list of language names, for example "Go".
+	Languages []string `json:"languages"`
+	// Reviewers is the list of github logins.
+	Reviewers []string `json:"reviewers"`
+	// Teams is the list of github team slugs whose members are reviewers.
+	Teams []string `json:"teams"`
+}
+
+var languageExtensions = map[string]string{
+	".go":    "Go",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".rs":    "Rust",
+	".php":   "PHP",
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".cs":    "C#",
+	".swift": "Swift",
+	".scala": "Scala",
+	".sh":    "Shell",
+	".sql":   "SQL",
+	".tf":    "HCL",
+	".css":   "CSS",
+	".scss":  "SCSS",
+	".html":  "HTML",
+	".md":    "Markdown",
+	".yml":   "YAML",
+	".yaml":  "YAML",
+	".proto": "Protocol Buffers",
+}
+
+// languageOf returns language name of given file, or empty string if unknown.
+func languageOf(filename string) string {
+	return languageExtensions[strings.ToLower(path.Ext(filename))]
+}
+
+// globMatch returns true if name matches given glob. In addition to
+// path.Match syntax, "**" matches any number of directories.
+func globMatch(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	var rx strings.Builder
+	rx.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					rx.WriteString("(.*/)?")
+				} else {
+					rx.WriteString(".*")
+				}
+			} else {
+				rx.WriteString("[^/]*")
+			}
+		case '?':
+			rx.WriteString("[^/]")
+		default:
+			rx.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	rx.WriteString("$")
+	ok, err := regexp.MatchString(rx.String(), name)
+	return err == nil && ok
+}
+
+// matches returns true if given file path matches the rule.
+func (r *ExpertiseRule) matches(filename string) bool {
+	for _, p := range r.Paths {
+		if globMatch(p, filename) {
+			return true
+		}
+	}
+	if lang := languageOf(filename); lang != "" {
+		for _, l := range r.Languages {
+			if strings.EqualFold(l, lang) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// changedFiles return names of all files changed by given pull request.
+func changedFiles(issue *Issue) ([]string, error) {
+	if err := githubOnly(); err != nil {
+		return nil, err
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var files []string
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files?per_page=100",
+		*ghAPIFl, *ghOrgFl, repo, issue.Number)
+	var list []struct {
+		Filename string `json:"filename"`
+	}
+	if err := githubGetAll(url, &list); err != nil {
+		return nil, err
+	}
+	for _, f := range list {
+		files = append(files, f.Filename)
+	}
+	return files, nil
+}
+
+var (
+	teamsMu    sync.Mutex
+	teamsCache = map[string][]User{}
+)
+
+// teamMembers return all members of the organization's team with given slug.
+// Cached per tenant, as tenants may have teams with the same slug.
+func teamMembers(slug string) ([]User, error) {
+	if err := githubOnly(); err != nil {
+		return nil, err
+	}
+	teamsMu.Lock()
+	defer teamsMu.Unlock()
+
+	key := tenantScope() + slug
+	if members, ok := teamsCache[key]; ok {
+		return members, nil
+	}
+	url := fmt.Sprintf("%s/orgs/%s/teams/%s/members?per_page=100", *ghAPIFl, *ghOrgFl, slug)
+	var members []User
+	if err := githubGetAll(url, &members); err != nil {
+		return nil, err
+	}
+	teamsCache[key] = members
+	return members, nil
+}
+
+// expertReviewer returns reviewer preferred by expertise rules for files
+// changed by given pull request. The more changed files are matched by
+// rules pointing to reviewer, the higher is reviewer's preference. False is
+// returned if no rule matches or none of preferred reviewers can be assigned.
+func expertReviewer(issue *Issue) (User, bool, error) {
+	if len(config.Expertise) == 0 {
+		return User{}, false, nil
+	}
+	files, err := changedFiles(issue)
+	if err != nil {
+		return User{}, false, fmt.Errorf("cannot list changed files: %s", err)
+	}
+
+	score := map[string]int{}
+	for _, rule := range config.Expertise {
+		matched := 0
+		for _, f := range files {
+			if rule.matches(f) {
+				matched++
+			}
+		}
+		if matched == 0 {
+			continue
+		}
+		for _, login := range rule.Reviewers {
+			score[strings.TrimPrefix(login, "@")] += matched
+		}
+		for _, slug := range rule.Teams {
+			members, err := teamMembers(slug)
+			if err != nil {
+				return User{}, false, fmt.Errorf("cannot list %q team members: %s", slug, err)
+			}
+			for _, m := range members {
+				score[m.Login] += matched
+			}
+		}
+	}
+
+	// random order before sorting, so that equally good reviewers are
+	// picked evenly
+	candidates := make([]string, 0, len(score))
+	for login := range score {
+		candidates = append(candidates, login)
+	}
+	for i := len(candidates) - 1; i > 0; i-- {
+		j, _ := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		candidates[i], candidates[j.Int64()] = candidates[j.Int64()], candidates[i]
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return score[candidates[i]] > score[candidates[j]]
+	})
+
+	for _, login := range candidates {
+		if canReview(issue, login) {
+			return User{Login: login}, true, nil
+		}
+	}
+	return User{}, false, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewExporter(t *testing.T) {
+	tests := []struct {
+		url string
+		err string
+	}{
+		{url: "file:///tmp/snapshots.jsonl", err: ""},
+		{url: "bigquery://project/dataset", err: "expected bigquery://"},
+		{url: "postgres://bot@localhost/bot", err: "invalid export table"},
+		{url: "postgres://bot@localhost/bot?table=pr;drop", err: "invalid export table"},
+		{url: "postgres://bot@localhost/bot?table=analytics.snapshots", err: "build with -tags postgres"},
+		{url: "mysql://bot@localhost/bot", err: "unsupported export url scheme"},
+	}
+	for _, tt := range tests {
+		_, err := newExporter(tt.url)
+		if tt.err == "" && err != nil {
+			t.Errorf("newExporter(%q) error = %v", tt.url, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("newExporter(%q) error = %v, want %q", tt.url, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// ExternalPolicy configures handling of pull requests opened by people
+// outside of the organization.
+type ExternalPolicy struct {
+	// SLA overrides default thresholds of external pull requests.
+	SLA SLA `json:"sla"`
+	// Triagers are logins external pull requests are assigned to instead
+	// of the team members.
+	Triagers []string `json:"triagers"`
+	// Welcome is the text/template of the comment posted when a pull
+	// request of a first-time contributor is assigned. Author and Assignee
+	// logins are available as {{.Author}} and {{.Assignee}}.
+	Welcome string `json:"welcome"`
+}
+
+// isExternal returns true if given pull request was opened by someone who is
+// neither a member nor a collaborator of the repository.
+func isExternal(issue *Issue) bool {
+	switch issue.AuthorAssociation {
+	case "CONTRIBUTOR", "NONE", "FIRST_TIME_CONTRIBUTOR", "FIRST_TIMER":
+		return true
+	}
+	return false
+}
+
+// isFirstTimer returns true if given pull request is the first contribution
+// of its author to the repository.
+func isFirstTimer(issue *Issue) bool {
+	return issue.AuthorAssociation == "FIRST_TIME_CONTRIBUTOR" || issue.AuthorAssociation == "FIRST_TIMER"
+}
+
+// externalPolicy returns policy of external pull requests, nil if given pull
+// request is not external or no policy is configured.
+func externalPolicy(issue *Issue) *ExternalPolicy {
+	if config.External == nil || !isExternal(issue) {
+		return nil
+	}
+	return config.External
+}
+
+// welcomeComment returns comment welcoming first-time contributor, empty if
+// none is configured.
+func welcomeComment(issue *Issue, assignee string) (string, error) {
+	p := externalPolicy(issue)
+	if p == nil || p.Welcome == "" || !isFirstTimer(issue) {
+		return "", nil
+	}
+	tmpl, err := template.New("welcome").Parse(p.Welcome)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse template: %s", err)
+	}
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, struct {
+		Author   string
+		Assignee string
+	}{issue.User.Login, assignee})
+	if err != nil {
+		return "", fmt.Errorf("cannot execute template: %s", err)
+	}
+	return b.String(), nil
+}
+
+// nextTriager returns triager from round robin of the external pull requests
+// triage pool.
+func nextTriager(issue *Issue) (User, error) {
+	return nextFromPool("triagers", config.External.Triagers, issue)
+}
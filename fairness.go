@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// memberLoad is the number of assignments a member received.
+type memberLoad struct {
+	Login string
+	Count int
+}
+
+// fairnessReport writes number of assignments of every member since given
+// time, their mean and standard deviation, and marks members more than one
+// standard deviation away from the mean as over or under loaded. Shadow
+// reviews, reminders, escalations, labels and comments are not assignments.
+func fairnessReport(w io.Writer, entries []AuditEntry, members []User, since time.Time) {
+	counts := map[string]int{}
+	for _, m := range members {
+		counts[m.Login] = 0
+	}
+	total := 0
+	for _, e := range entries {
+		if e.Time.Before(since) || !e.assignment() {
+			continue
+		}
+		counts[e.Login]++
+		total++
+	}
+	if len(counts) == 0 {
+		fmt.Fprintln(w, "no assignments and no members")
+		return
+	}
+
+	loads := make([]memberLoad, 0, len(counts))
+	for login, n := range counts {
+		loads = append(loads, memberLoad{Login: login, Count: n})
+	}
+	sort.Slice(loads, func(i, j int) bool {
+		if loads[i].Count != loads[j].Count {
+			return loads[i].Count > loads[j].Count
+		}
+		return loads[i].Login < loads[j].Login
+	})
+	mean := float64(total) / float64(len(loads))
+	variance := 0.0
+	for _, l := range loads {
+		variance += (float64(l.Count) - mean) * (float64(l.Count) - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(loads)))
+
+	fmt.Fprintf(w, "Assignments since %s\n\n", since.Format("2006-01-02"))
+	for _, l := range loads {
+		note := ""
+		switch {
+		case float64(l.Count) > mean+stddev:
+			note = "overloaded"
+		case float64(l.Count) < mean-stddev:
+			note = "underloaded"
+		}
+		fmt.Fprintf(w, "%-24s %5d  %+6.1f  %s\n", l.Login, l.Count, float64(l.Count)-mean, note)
+	}
+	fmt.Fprintf(w, "\ntotal %d, mean %.1f, standard deviation %.1f\n", total, mean, stddev)
+}
+
+// runFairness prints the fairness report of the last -fairness-window.
+func runFairness(w io.Writer) error {
+	entries, err := loadAudit()
+	if err != nil {
+		return fmt.Errorf("cannot load audit log: %s", err)
+	}
+	members, err := listMembers()
+	if err != nil {
+		return fmt.Errorf("cannot list members: %s", err)
+	}
+	fairnessReport(w, entries, members, time.Now().Add(-*fairnessWindowFl))
+	return nil
+}
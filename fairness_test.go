@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFairnessReport(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	since := now.AddDate(0, 0, -30)
+	entries := []AuditEntry{
+		{Time: now.AddDate(0, 0, -40), Key: "api#1", Login: "bob", Action: "assigned"},
+		{Time: now.AddDate(0, 0, -3), Key: "api#2", Login: "alice", Action: "assigned"},
+		{Time: now.AddDate(0, 0, -2), Key: "api#2", Login: "alice", Action: "reminded"},
+		{Time: now.AddDate(0, 0, -2), Key: "api#2", Login: "alice", Action: "escalated"},
+		{Time: now.AddDate(0, 0, -2), Key: "api#2", Action: "labeled", Detail: "stale"},
+		{Time: now.AddDate(0, 0, -2), Key: "api#3", Login: "carol", Action: "commented", Detail: "link_missing"},
+		{Time: now.AddDate(0, 0, -2), Key: "api#3", Login: "bob", Action: "requested"},
+		{Time: now.AddDate(0, 0, -1), Key: "api#3", Login: "carol", Action: "shadow"},
+		{Time: now.AddDate(0, 0, -1), Key: "api#4", Login: "carol", Action: "reassigned"},
+	}
+	members := []User{{Login: "alice"}, {Login: "bob"}, {Login: "carol"}}
+
+	var b bytes.Buffer
+	fairnessReport(&b, entries, members, since)
+	want := "Assignments since 2026-09-15\n\n" +
+		"alice                        1    +0.0  \n" +
+		"bob                          1    +0.0  \n" +
+		"carol                        1    +0.0  \n" +
+		"\ntotal 3, mean 1.0, standard deviation 0.0\n"
+	if got := b.String(); got != want {
+		t.Errorf("fairnessReport() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// giteaPageSize is the number of items requested per page. Gitea servers
+// cap it by their MAX_RESPONSE_ITEMS setting, 50 by default.
+const giteaPageSize = 50
+
+// giteaIssue is the issue representation of Gitea API. It is close to the
+// github one, but the HTML URL points to the Gitea instance, so repository
+// has to be taken from the repository field.
+type giteaIssue struct {
+	Issue
+	Repository *struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+}
+
+func (i *giteaIssue) issue() Issue {
+	issue := i.Issue
+	if i.Repository != nil {
+		issue.Repo = i.Repository.Name
+	}
+	return issue
+}
+
+// giteaRequest sends request to Gitea API and decodes JSON response into
+// given value.
+func giteaRequest(method, path string, body interface{}, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		var b bytes.Buffer
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return fmt.Errorf("cannot encode body: %s", err)
+		}
+		r = &b
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(*giteaURLFl, "/")+"/api/v1"+path, r)
+	if err != nil {
+		return fmt.Errorf("cannot create %s request: %s", method, err)
+	}
+	req.Header.Set("Authorization", "token "+*giteaTokenFl)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return fmt.Errorf("cannot decode response: %s", err)
+		}
+	}
+	return nil
+}
+
+// giteaProvider is the Provider using Gitea (or Forgejo) API. Organization
+// flag is the owner of repositories and team ID flag the team whose members
+// are assigned.
+type giteaProvider struct{}
+
+func (giteaProvider) OpenIssues() ([]Issue, error) {
+	var issues []Issue
+	// Gitea does not paginate using Link header consistently across
+	// versions, so request pages until an empty one is returned
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/issues/search?type=pulls&state=open&owner=%s&limit=%d&page=%d",
+			url.QueryEscape(*ghOrgFl), giteaPageSize, page)
+		var list []giteaIssue
+		if err := giteaRequest("GET", path, nil, &list); err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return issues, nil
+		}
+		for i := range list {
+			issues = append(issues, list[i].issue())
+		}
+	}
+}
+
+func (giteaProvider) Issue(repo string, number int64) (*Issue, error) {
+	var issue giteaIssue
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", url.PathEscape(*ghOrgFl), url.PathEscape(repo), number)
+	if err := giteaRequest("GET", path, nil, &issue); err != nil {
+		return nil, err
+	}
+	i := issue.issue()
+	if i.Repo == "" {
+		i.Repo = repo
+	}
+	return &i, nil
+}
+
+func (giteaProvider) Members() ([]User, error) {
+	var members []User
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/teams/%s/members?limit=%d&page=%d",
+			url.PathEscape(*ghTeamFl), giteaPageSize, page)
+		var list []User
+		if err := giteaRequest("GET", path, nil, &list); err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return members, nil
+		}
+		members = append(members, list...)
+	}
+}
+
+func (giteaProvider) Assign(issue *Issue, user *User) error {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name: %s", err)
+	}
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", url.PathEscape(*ghOrgFl), url.PathEscape(repo), issue.Number)
+	body := map[string]interface{}{
+		"assignees": []string{user.Login},
+	}
+	if err := giteaRequest("PATCH", path, body, nil); err != nil {
+		return err
+	}
+	log.Printf("%s assigned to #%d issue of %q", user.Login, issue.Number, repo)
+	return nil
+}
+
+func (giteaProvider) RequestReview(issue *Issue, user *User) error {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name: %s", err)
+	}
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/requested_reviewers", url.PathEscape(*ghOrgFl), url.PathEscape(repo), issue.Number)
+	body := map[string]interface{}{
+		"reviewers": []string{user.Login},
+	}
+	if err := giteaRequest("POST", path, body, nil); err != nil {
+		return err
+	}
+	log.Printf("review of #%d pull request of %q requested from %s", issue.Number, repo, user.Login)
+	return nil
+}
+
+func (giteaProvider) Comment(issue *Issue, comment string) error {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name: %s", err)
+	}
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", url.PathEscape(*ghOrgFl), url.PathEscape(repo), issue.Number)
+	body := map[string]interface{}{
+		"body": comment,
+	}
+	return giteaRequest("POST", path, body, nil)
+}
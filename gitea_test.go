@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGiteaProvider(t *testing.T) {
+	var requested map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/repos/issues/search":
+			if r.URL.Query().Get("owner") != "acme" || r.URL.Query().Get("type") != "pulls" {
+				t.Errorf("searched %s", r.URL.RawQuery)
+			}
+			// pages are requested until an empty one
+			if r.URL.Query().Get("page") != "1" {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"id": 7, "number": 3, "title": "Fix login",
+				"html_url": "https://git.example.com/acme/api/pulls/3",
+				"user": {"login": "alice"}, "pull_request": {},
+				"repository": {"name": "api"}}]`))
+		case "POST /api/v1/repos/acme/api/pulls/3/requested_reviewers":
+			json.NewDecoder(r.Body).Decode(&requested)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(u, token, org string) { *giteaURLFl, *giteaTokenFl, *ghOrgFl = u, token, org }(*giteaURLFl, *giteaTokenFl, *ghOrgFl)
+	*giteaURLFl, *giteaTokenFl, *ghOrgFl = srv.URL, "secret", "acme"
+
+	issues, err := giteaProvider{}.OpenIssues()
+	if err != nil {
+		t.Fatalf("OpenIssues() error = %v", err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("OpenIssues() returned %d pull requests, want 1", len(issues))
+	}
+	// repository comes from the repository field, not the URL
+	if repo, _ := issues[0].GetRepository(); repo != "api" || !issues[0].isPullRequest() {
+		t.Errorf("pull request = %+v, want pull request of api", issues[0])
+	}
+
+	if err := (giteaProvider{}).RequestReview(&issues[0], &User{Login: "bob"}); err != nil {
+		t.Fatalf("RequestReview() error = %v", err)
+	}
+	if r := requested["reviewers"]; len(r) != 1 || r[0] != "bob" {
+		t.Errorf("RequestReview() sent reviewers %v, want [bob]", r)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+// verifyGithubRequest returns body of github webhook request if its
+// signature is valid.
+func verifyGithubRequest(r *http.Request) ([]byte, error) {
+	secret := currentAuth().githubWebhookSecret
+	if secret == "" {
+		return nil, errors.New("webhook secret not configured")
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read body: %s", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Hub-Signature-256"))) {
+		return nil, errors.New("invalid signature")
+	}
+	return body, nil
+}
+
+// githubEvent is the part of github webhook payloads used to notify authors.
+type githubEvent struct {
+	Action     string `json:"action"`
+	State      string `json:"state"`
+	SHA        string `json:"sha"`
+	Repository struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+	Review struct {
+		State string `json:"state"`
+	} `json:"review"`
+	PullRequest struct {
+		Number int64 `json:"number"`
+		Head   struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	CheckSuite struct {
+		HeadSHA      string `json:"head_sha"`
+		Conclusion   string `json:"conclusion"`
+		PullRequests []struct {
+			Number int64 `json:"number"`
+		} `json:"pull_requests"`
+	} `json:"check_suite"`
+}
+
+// handleGithubWebhook serves github webhook deliveries of
+// pull_request_review, check_suite and status events and notifies authors
+// when their pull request is ready to merge or fails CI.
+func handleGithubWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := verifyGithubRequest(r)
+	if err != nil {
+		log.Printf("rejecting github webhook: %s", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var ev githubEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	// github expects a response within 10 seconds, a tenant scan may be
+	// running for longer
+	w.WriteHeader(http.StatusAccepted)
+	event := r.Header.Get("X-GitHub-Event")
+	goTracked(func() { handleGithubEvent(event, ev) })
+}
+
+// handleGithubEvent notifies authors about given github event of given type.
+func handleGithubEvent(event string, ev githubEvent) {
+	if !*notifyAuthorsFl || !notificationsEnabled() {
+		return
+	}
+	repo := ev.Repository.Name
+	switch event {
+	case "pull_request_review":
+		if ev.Action == "submitted" && ev.Review.State == "approved" {
+			notifyReady(repo, ev.PullRequest.Number, ev.PullRequest.Head.SHA)
+		}
+	case "check_suite":
+		if ev.Action != "completed" {
+			return
+		}
+		if ev.CheckSuite.Conclusion != "failure" && ev.CheckSuite.Conclusion != "timed_out" {
+			return
+		}
+		for _, pr := range ev.CheckSuite.PullRequests {
+			notifyFailure(repo, pr.Number, ev.CheckSuite.HeadSHA)
+		}
+	case "status":
+		if ev.State != "failure" && ev.State != "error" {
+			return
+		}
+		numbers, err := commitPullRequests(repo, ev.SHA)
+		if err != nil {
+			log.Printf("cannot list pull requests of %s: %s", ev.SHA, err)
+			return
+		}
+		for _, number := range numbers {
+			notifyFailure(repo, number, ev.SHA)
+		}
+	}
+}
+
+// commitPullRequests returns numbers of open pull requests containing given
+// commit.
+func commitPullRequests(repo, sha string) ([]int64, error) {
+	var pulls []struct {
+		Number int64  `json:"number"`
+		State  string `json:"state"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s/pulls?per_page=100", *ghAPIFl, *ghOrgFl, repo, sha)
+	if err := githubGetAll(url, &pulls); err != nil {
+		return nil, err
+	}
+	var numbers []int64
+	for _, p := range pulls {
+		if p.State == "open" {
+			numbers = append(numbers, p.Number)
+		}
+	}
+	return numbers, nil
+}
+
+// userMention returns Slack mention of user with given github login.
+func userMention(login string) string {
+	if id := config.Members[login].Slack; id != "" {
+		return fmt.Sprintf("<@%s>", id)
+	}
+	return "@" + login
+}
+
+// notifyUser sends given text to the member with given login in a direct
+// Slack message if possible, or to the configured channel otherwise.
+func notifyUser(login, text string) error {
+	id := config.Members[login].Slack
+	if *slackTokenFl == "" || id == "" {
+		return postSlack(text)
+	}
+	if err := directMessage(id, text); err != nil {
+		log.Printf("cannot message %s directly: %s", login, err)
+		return postSlack(text)
+	}
+	return nil
+}
+
+// notifyAuthor sends given notice about given pull request to its author,
+// once per notice. Text is the ID of the message describing the notice.
+func notifyAuthor(repo string, number int64, notice, text string) {
+	issue, err := forge.Issue(repo, number)
+	if err != nil {
+		log.Printf("cannot get %s#%d: %s", repo, number, err)
+		return
+	}
+	if issue.State != "open" || optedOut(issue) || ignored(issue) {
+		return
+	}
+	key := issueKey(issue)
+	for _, n := range getState(key).AuthorNotices {
+		if n == notice {
+			return
+		}
+	}
+	log.Printf("Notifying %s about PR #%d: %s", issue.User.Login, issue.Number, notice)
+	msg := tr(issue, "author_notice", userMention(issue.User.Login), issue.HTMLURL, issue.Number, issue.Title, tr(issue, text))
+	if err := notifyUser(issue.User.Login, msg); err != nil {
+		log.Printf("cannot write slack notification: %s", err)
+		return
+	}
+	err = updateState(key, func(s *PRState) {
+		s.AuthorNotices = append(s.AuthorNotices, notice)
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+}
+
+// notifyReady notifies author of given pull request if it has all required
+// approvals.
+func notifyReady(repo string, number int64, sha string) {
+	issue, err := forge.Issue(repo, number)
+	if err != nil {
+		log.Printf("cannot get %s#%d: %s", repo, number, err)
+		return
+	}
+	if optedOut(issue) || ignored(issue) {
+		return
+	}
+	missing, err := missingApprovals(issue)
+	if err == errNoRequiredApprovals || missing > 0 {
+		return
+	}
+	if err != nil {
+		log.Printf("cannot check approvals of #%d: %s", number, err)
+		return
+	}
+	notifyAuthor(repo, number, "ready:"+sha, "ready")
+}
+
+// notifyFailure notifies author of given pull request that CI failed.
+func notifyFailure(repo string, number int64, sha string) {
+	notifyAuthor(repo, number, "ci-failed:"+sha, "ci_failed")
+}
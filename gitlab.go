@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// gitlabUser is the user representation of GitLab API.
+type gitlabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Bot      bool   `json:"bot"`
+}
+
+func (u *gitlabUser) user() *User {
+	if u == nil {
+		return nil
+	}
+	user := &User{ID: u.ID, Login: u.Username, Type: "User"}
+	if u.Bot {
+		user.Type = "Bot"
+	}
+	return user
+}
+
+// gitlabMergeRequest is the merge request representation of GitLab API.
+type gitlabMergeRequest struct {
+	ID          int64       `json:"id"`
+	IID         int64       `json:"iid"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	State       string      `json:"state"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	Author      *gitlabUser `json:"author"`
+	Assignee    *gitlabUser `json:"assignee"`
+	WebURL      string      `json:"web_url"`
+	Labels      []string    `json:"labels"`
+	References  struct {
+		Full string `json:"full"`
+	} `json:"references"`
+}
+
+// issue converts merge request to the issue representation shared by all
+// providers.
+func (mr *gitlabMergeRequest) issue() Issue {
+	issue := Issue{
+		ID:          mr.ID,
+		Number:      mr.IID,
+		CreatedAt:   mr.CreatedAt,
+		UpdatedAt:   mr.UpdatedAt,
+		User:        mr.Author.user(),
+		Assignee:    mr.Assignee.user(),
+		URL:         mr.WebURL,
+		HTMLURL:     mr.WebURL,
+		Title:       mr.Title,
+		Body:        mr.Description,
+		State:       mr.State,
+		PullRequest: &PullRequest{HTMLURL: mr.WebURL},
+		// full reference is in "group/project!iid" format
+		Repo: strings.SplitN(mr.References.Full, "!", 2)[0],
+	}
+	for _, name := range mr.Labels {
+		issue.Labels = append(issue.Labels, Label{Name: name})
+	}
+	return issue
+}
+
+// gitlabRequest sends request to GitLab API and decodes JSON response into
+// given value. Path of the next page is returned for paginated responses.
+func gitlabRequest(method, path string, body interface{}, v interface{}) (string, error) {
+	var r io.Reader
+	if body != nil {
+		var b bytes.Buffer
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return "", fmt.Errorf("cannot encode body: %s", err)
+		}
+		r = &b
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(*gitlabURLFl, "/")+"/api/v4"+path, r)
+	if err != nil {
+		return "", fmt.Errorf("cannot create %s request: %s", method, err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", *gitlabTokenFl)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return "", fmt.Errorf("cannot decode response: %s", err)
+		}
+	}
+	next := ""
+	if page := resp.Header.Get("X-Next-Page"); page != "" {
+		u, err := url.Parse(path)
+		if err == nil {
+			q := u.Query()
+			q.Set("page", page)
+			u.RawQuery = q.Encode()
+			next = u.String()
+		}
+	}
+	return next, nil
+}
+
+// gitlabProvider is the Provider using GitLab API. Organization flag is
+// used as the group whose merge requests are handled and team ID flag as the
+// group whose members are assigned.
+type gitlabProvider struct{}
+
+func (gitlabProvider) OpenIssues() ([]Issue, error) {
+	var issues []Issue
+	path := fmt.Sprintf("/groups/%s/merge_requests?state=opened&scope=all&per_page=100",
+		url.PathEscape(*ghOrgFl))
+	for path != "" {
+		var mrs []gitlabMergeRequest
+		next, err := gitlabRequest("GET", path, nil, &mrs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range mrs {
+			issues = append(issues, mrs[i].issue())
+		}
+		path = next
+	}
+	return issues, nil
+}
+
+func (gitlabProvider) Issue(repo string, number int64) (*Issue, error) {
+	var mr gitlabMergeRequest
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d", url.PathEscape(repo), number)
+	if _, err := gitlabRequest("GET", path, nil, &mr); err != nil {
+		return nil, err
+	}
+	issue := mr.issue()
+	return &issue, nil
+}
+
+func (gitlabProvider) Members() ([]User, error) {
+	var members []User
+	path := fmt.Sprintf("/groups/%s/members/all?per_page=100", url.PathEscape(*ghTeamFl))
+	for path != "" {
+		var page []gitlabUser
+		next, err := gitlabRequest("GET", path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page {
+			members = append(members, *page[i].user())
+		}
+		path = next
+	}
+	return members, nil
+}
+
+// gitlabUserID returns GitLab ID of given user.
+func gitlabUserID(user *User) (int64, error) {
+	if user.ID != 0 {
+		return user.ID, nil
+	}
+	// users picked by some strategies are known by login only
+	var users []gitlabUser
+	path := "/users?username=" + url.QueryEscape(user.Login)
+	if _, err := gitlabRequest("GET", path, nil, &users); err != nil {
+		return 0, fmt.Errorf("cannot find user: %s", err)
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("user %q not found", user.Login)
+	}
+	return users[0].ID, nil
+}
+
+func (gitlabProvider) Assign(issue *Issue, user *User) error {
+	id, err := gitlabUserID(user)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d", url.PathEscape(issue.Repo), issue.Number)
+	body := map[string]interface{}{
+		"assignee_ids": []int64{id},
+	}
+	if _, err := gitlabRequest("PUT", path, body, nil); err != nil {
+		return err
+	}
+	log.Printf("%s assigned to !%d merge request of %q", user.Login, issue.Number, issue.Repo)
+	return nil
+}
+
+func (gitlabProvider) RequestReview(issue *Issue, user *User) error {
+	id, err := gitlabUserID(user)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d", url.PathEscape(issue.Repo), issue.Number)
+	body := map[string]interface{}{
+		"reviewer_ids": []int64{id},
+	}
+	if _, err := gitlabRequest("PUT", path, body, nil); err != nil {
+		return err
+	}
+	log.Printf("review of !%d merge request of %q requested from %s", issue.Number, issue.Repo, user.Login)
+	return nil
+}
+
+func (gitlabProvider) Comment(issue *Issue, comment string) error {
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d/notes", url.PathEscape(issue.Repo), issue.Number)
+	body := map[string]interface{}{
+		"body": comment,
+	}
+	_, err := gitlabRequest("POST", path, body, nil)
+	return err
+}
+
+// MissingApprovals returns approvals given merge request still needs by its
+// approval rules. Approval rules need GitLab Premium, on other tiers no
+// approvals are required.
+func (gitlabProvider) MissingApprovals(issue *Issue) (int, error) {
+	var approvals struct {
+		ApprovalsRequired int `json:"approvals_required"`
+		ApprovalsLeft     int `json:"approvals_left"`
+	}
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d/approvals", url.PathEscape(issue.Repo), issue.Number)
+	if _, err := gitlabRequest("GET", path, nil, &approvals); err != nil {
+		return 0, err
+	}
+	if approvals.ApprovalsRequired == 0 {
+		return 0, errNoRequiredApprovals
+	}
+	return approvals.ApprovalsLeft, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitlabProvider(t *testing.T) {
+	var assigned map[string][]int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v4/groups/acme/merge_requests":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("X-Next-Page", "2")
+				w.Write([]byte(`[{"id": 101, "iid": 1, "title": "Fix login", "state": "opened",
+					"author": {"id": 1, "username": "alice"}, "labels": ["bug"],
+					"references": {"full": "acme/api!1"}}]`))
+				return
+			}
+			w.Write([]byte(`[{"id": 102, "iid": 2, "title": "Bump deps", "state": "opened",
+				"author": {"id": 9, "username": "renovate", "bot": true},
+				"assignee": {"id": 2, "username": "bob"},
+				"references": {"full": "acme/web!2"}}]`))
+		case "GET /api/v4/users":
+			w.Write([]byte(`[{"id": 3, "username": "carol"}]`))
+		case "PUT /api/v4/projects/acme%2Fapi/merge_requests/1":
+			json.NewDecoder(r.Body).Decode(&assigned)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(u, token, org string) { *gitlabURLFl, *gitlabTokenFl, *ghOrgFl = u, token, org }(*gitlabURLFl, *gitlabTokenFl, *ghOrgFl)
+	*gitlabURLFl, *gitlabTokenFl, *ghOrgFl = srv.URL+"/", "secret", "acme"
+
+	issues, err := gitlabProvider{}.OpenIssues()
+	if err != nil {
+		t.Fatalf("OpenIssues() error = %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("OpenIssues() returned %d merge requests, want 2 of both pages", len(issues))
+	}
+	first, second := issues[0], issues[1]
+	if first.Repo != "acme/api" || first.Number != 1 || first.Assignee != nil || len(first.Labels) != 1 {
+		t.Errorf("first merge request = %+v", first)
+	}
+	if second.Repo != "acme/web" || second.User.Type != "Bot" || second.Assignee.Login != "bob" {
+		t.Errorf("second merge request = %+v", second)
+	}
+
+	// members known by login only are looked up
+	if err := (gitlabProvider{}).Assign(&first, &User{Login: "carol"}); err != nil {
+		t.Fatalf("Assign() error = %v", err)
+	}
+	if ids := assigned["assignee_ids"]; len(ids) != 1 || ids[0] != 3 {
+		t.Errorf("Assign() sent assignee_ids %v, want [3]", ids)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// googleChatNotifier posts messages to a Google Chat space using its
+// webhook. Messages about the same pull request are posted to one thread.
+type googleChatNotifier struct {
+	url string
+}
+
+// googleChatFormat converts Slack formatted text to Google Chat text, which
+// uses the same link syntax.
+var googleChatFormat = chatFormat{
+	Link: func(url, label string) string {
+		return fmt.Sprintf("<%s|%s>", url, label)
+	},
+	Mention: func(login string) string {
+		id := config.Members[login].GoogleChat
+		if id == "" {
+			return ""
+		}
+		return fmt.Sprintf("<users/%s>", strings.TrimPrefix(id, "users/"))
+	},
+	Broadcast: func(name string) string {
+		return "<users/all>"
+	},
+}
+
+func (n *googleChatNotifier) Notify(msg slackMessage) error {
+	payload := map[string]interface{}{
+		"text": googleChatFormat.convert(msg.Text),
+	}
+	u, err := url.Parse(n.url)
+	if err != nil {
+		return fmt.Errorf("invalid Google Chat webhook URL: %s", err)
+	}
+	thread := msg.Key
+	if issue := msg.Issue; issue != nil {
+		thread = issueKey(issue)
+		payload["cardsV2"] = []interface{}{map[string]interface{}{
+			"cardId": "reminder",
+			"card":   googleChatCard(issue),
+		}}
+	}
+	if thread != "" {
+		q := u.Query()
+		q.Set("threadKey", thread)
+		q.Set("messageReplyOption", "REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD")
+		u.RawQuery = q.Encode()
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode payload: %s", err)
+	}
+	resp, err := httpClient.Post(u.String(), "application/json; charset=UTF-8", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("cannot POST to Google Chat: %s", redact(err.Error()))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected Google Chat response: %d, %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// googleChatCard returns card showing details of given pull request.
+func googleChatCard(issue *Issue) map[string]interface{} {
+	repo, _ := issue.GetRepository()
+	assignee := tr(issue, "nobody")
+	if issue.Assignee != nil {
+		assignee = googleChatFormat.plain("@" + issue.Assignee.Login)
+	}
+	field := func(label, text string) interface{} {
+		return map[string]interface{}{
+			"decoratedText": map[string]interface{}{"topLabel": label, "text": text},
+		}
+	}
+	return map[string]interface{}{
+		"header": map[string]interface{}{
+			"title":    fmt.Sprintf("#%d %s", issue.Number, issue.Title),
+			"subtitle": repo,
+		},
+		"sections": []interface{}{map[string]interface{}{
+			"widgets": []interface{}{
+				field(tr(issue, "field_age"), formatAge(time.Since(issue.CreatedAt))),
+				field(tr(issue, "field_assignee"), assignee),
+				map[string]interface{}{
+					"buttonList": map[string]interface{}{
+						"buttons": []interface{}{map[string]interface{}{
+							"text": tr(issue, "button_open"),
+							"onClick": map[string]interface{}{
+								"openLink": map[string]interface{}{"url": issue.HTMLURL},
+							},
+						}},
+					},
+				},
+			},
+		}},
+	}
+}
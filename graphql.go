@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// graphqlURL returns github GraphQL API endpoint. Github Enterprise serves
+// REST API under /api/v3 and GraphQL under /api/graphql.
+func graphqlURL() string {
+	api := strings.TrimSuffix(*ghAPIFl, "/")
+	if strings.HasSuffix(api, "/v3") {
+		return strings.TrimSuffix(api, "/v3") + "/graphql"
+	}
+	return api + "/graphql"
+}
+
+// githubGraphQL executes given GraphQL query with given variables and
+// decodes the response data into v.
+func githubGraphQL(query string, vars map[string]interface{}, v interface{}) error {
+	if err := githubOnly(); err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(map[string]interface{}{
+		"query":     query,
+		"variables": vars,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	req, err := http.NewRequest("POST", graphqlURL(), &body)
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("cannot decode response: %s", err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("query failed: %s", result.Errors[0].Message)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, v); err != nil {
+		return fmt.Errorf("cannot decode data: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// graphqlPageSize is the number of pull requests fetched per query. Larger
+// pages exceed the node limit because of the nested connections.
+const graphqlPageSize = 50
+
+// graphqlSearchLimit is the maximum number of results github search returns.
+const graphqlSearchLimit = 1000
+
+const openPullRequestsQuery = `query($q: String!, $first: Int!, $cursor: String) {
+  search(query: $q, type: ISSUE, first: $first, after: $cursor) {
+    issueCount
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      ... on PullRequest {
+        databaseId number title body url createdAt updatedAt
+        authorAssociation
+        author { __typename login }
+        assignees(first: 1) { nodes { databaseId login } }
+        labels(first: 50) { nodes { name } }
+        repository {
+          name isArchived isFork isPrivate visibility
+          repositoryTopics(first: 20) { nodes { topic { name } } }
+        }
+        additions deletions changedFiles
+        headRefName headRefOid baseRefName mergeable
+        reviews(last: 100) { nodes { author { login } state } }
+        reviewRequests(first: 20) { nodes { requestedReviewer { ... on User { login } } } }
+        commits(last: 1) { nodes { commit { statusCheckRollup { state } } } }
+      }
+    }
+  }
+}`
+
+// graphqlPullRequest is a pull request as returned by openPullRequestsQuery.
+type graphqlPullRequest struct {
+	DatabaseID        int64     `json:"databaseId"`
+	Number            int64     `json:"number"`
+	Title             string    `json:"title"`
+	Body              string    `json:"body"`
+	URL               string    `json:"url"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+	AuthorAssociation string    `json:"authorAssociation"`
+	Author            *struct {
+		Typename string `json:"__typename"`
+		Login    string `json:"login"`
+	} `json:"author"`
+	Assignees struct {
+		Nodes []struct {
+			DatabaseID int64  `json:"databaseId"`
+			Login      string `json:"login"`
+		} `json:"nodes"`
+	} `json:"assignees"`
+	Labels struct {
+		Nodes []Label `json:"nodes"`
+	} `json:"labels"`
+	Repository struct {
+		Name             string `json:"name"`
+		IsArchived       bool   `json:"isArchived"`
+		IsFork           bool   `json:"isFork"`
+		IsPrivate        bool   `json:"isPrivate"`
+		Visibility       string `json:"visibility"`
+		RepositoryTopics struct {
+			Nodes []struct {
+				Topic struct {
+					Name string `json:"name"`
+				} `json:"topic"`
+			} `json:"nodes"`
+		} `json:"repositoryTopics"`
+	} `json:"repository"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	ChangedFiles int    `json:"changedFiles"`
+	HeadRefName  string `json:"headRefName"`
+	HeadRefOid   string `json:"headRefOid"`
+	BaseRefName  string `json:"baseRefName"`
+	Mergeable    string `json:"mergeable"`
+	Reviews      struct {
+		Nodes []struct {
+			Author *struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			State string `json:"state"`
+		} `json:"nodes"`
+	} `json:"reviews"`
+	ReviewRequests struct {
+		Nodes []struct {
+			RequestedReviewer struct {
+				Login string `json:"login"`
+			} `json:"requestedReviewer"`
+		} `json:"nodes"`
+	} `json:"reviewRequests"`
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				StatusCheckRollup *struct {
+					State string `json:"state"`
+				} `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+}
+
+// issue returns issue representation of the pull request.
+func (p *graphqlPullRequest) issue() Issue {
+	issue := Issue{
+		ID:                p.DatabaseID,
+		Number:            p.Number,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+		URL:               fmt.Sprintf("%s/repos/%s/%s/issues/%d", *ghAPIFl, *ghOrgFl, p.Repository.Name, p.Number),
+		HTMLURL:           p.URL,
+		Title:             p.Title,
+		Body:              p.Body,
+		State:             "open",
+		Labels:            p.Labels.Nodes,
+		PullRequest:       &PullRequest{HTMLURL: p.URL},
+		AuthorAssociation: p.AuthorAssociation,
+		Repo:              p.Repository.Name,
+		Repository: &Repository{
+			Name:       p.Repository.Name,
+			Archived:   p.Repository.IsArchived,
+			Fork:       p.Repository.IsFork,
+			Private:    p.Repository.IsPrivate,
+			Visibility: strings.ToLower(p.Repository.Visibility),
+		},
+	}
+	if issue.Labels == nil {
+		issue.Labels = []Label{}
+	}
+	for _, t := range p.Repository.RepositoryTopics.Nodes {
+		issue.Repository.Topics = append(issue.Repository.Topics, t.Topic.Name)
+	}
+	// deleted accounts have no author
+	issue.User = &User{Login: "ghost", Type: "User"}
+	if p.Author != nil {
+		issue.User = &User{Login: p.Author.Login, Type: p.Author.Typename}
+		if p.Author.Typename == "Bot" {
+			// REST API logins of apps have the suffix
+			issue.User.Login += "[bot]"
+		}
+	}
+	if len(p.Assignees.Nodes) > 0 {
+		a := p.Assignees.Nodes[0]
+		issue.Assignee = &User{ID: a.DatabaseID, Login: a.Login}
+	}
+	return issue
+}
+
+// pull returns pull request details of the pull request.
+func (p *graphqlPullRequest) pull() *Pull {
+	pull := &Pull{
+		Additions:    p.Additions,
+		Deletions:    p.Deletions,
+		ChangedFiles: p.ChangedFiles,
+	}
+	pull.Head.SHA = p.HeadRefOid
+	pull.Head.Ref = p.HeadRefName
+	pull.Base.Ref = p.BaseRefName
+	if p.Mergeable != "UNKNOWN" {
+		mergeable := p.Mergeable == "MERGEABLE"
+		pull.Mergeable = &mergeable
+	}
+	for _, r := range p.ReviewRequests.Nodes {
+		if r.RequestedReviewer.Login != "" {
+			pull.RequestedReviewers = append(pull.RequestedReviewers, User{Login: r.RequestedReviewer.Login})
+		}
+	}
+	return pull
+}
+
+// ciState returns CI status of the pull request in the format of ciStatus.
+func (p *graphqlPullRequest) ciState() string {
+	if len(p.Commits.Nodes) == 0 || p.Commits.Nodes[0].Commit.StatusCheckRollup == nil {
+		return ""
+	}
+	switch p.Commits.Nodes[0].Commit.StatusCheckRollup.State {
+	case "SUCCESS":
+		return "success"
+	case "FAILURE", "ERROR":
+		return "failure"
+	}
+	return "pending"
+}
+
+// latestReviews returns state of the latest review of every reviewer, in the
+// format of latestReviews function.
+func (p *graphqlPullRequest) latestReviews() map[string]string {
+	latest := map[string]string{}
+	for _, r := range p.Reviews.Nodes {
+		if r.Author == nil || r.State == "COMMENTED" || r.State == "PENDING" {
+			continue
+		}
+		latest[r.Author.Login] = r.State
+	}
+	return latest
+}
+
+var (
+	ciMu    sync.Mutex
+	ciCache = map[int64]string{}
+
+	reviewsMu    sync.Mutex
+	reviewsCache = map[int64]map[string]string{}
+)
+
+// openIssuesGraphQL returns all open pull requests of the organization
+// using GraphQL search, and fills pull request, review, CI and review
+// request caches, so that no further requests are needed for them. Issues
+// are fetched using REST API if -include-issues is set, and so are pull
+// requests if there are more of them than search returns.
+func openIssuesGraphQL() ([]Issue, error) {
+	var issues []Issue
+	requests := map[string][]int64{}
+	q := searchOwner() + " is:pr is:open"
+	var cursor interface{}
+	for {
+		var data struct {
+			Search struct {
+				IssueCount int `json:"issueCount"`
+				PageInfo   struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []graphqlPullRequest `json:"nodes"`
+			} `json:"search"`
+		}
+		err := githubGraphQL(openPullRequestsQuery, map[string]interface{}{"q": q, "first": graphqlPageSize, "cursor": cursor}, &data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot search pull requests: %s", err)
+		}
+		if cursor == nil && data.Search.IssueCount > graphqlSearchLimit {
+			// search would miss the rest of them
+			log.Printf("%d open pull requests, more than search returns, listing them using REST API", data.Search.IssueCount)
+			return openIssues()
+		}
+		for i := range data.Search.Nodes {
+			p := &data.Search.Nodes[i]
+			issue := p.issue()
+			issues = append(issues, issue)
+
+			pullsMu.Lock()
+			pullsCache[issue.ID] = p.pull()
+			pullsMu.Unlock()
+			ciMu.Lock()
+			ciCache[issue.ID] = p.ciState()
+			ciMu.Unlock()
+			reviewsMu.Lock()
+			reviewsCache[issue.ID] = p.latestReviews()
+			reviewsMu.Unlock()
+			for _, r := range p.ReviewRequests.Nodes {
+				if login := r.RequestedReviewer.Login; login != "" {
+					requests[login] = append(requests[login], issue.ID)
+				}
+			}
+		}
+		if !data.Search.PageInfo.HasNextPage {
+			break
+		}
+		cursor = data.Search.PageInfo.EndCursor
+	}
+	loadReviewRequests(requests)
+
+	if *includeIssuesFl {
+		all, err := openIssues()
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range all {
+			if !issue.isPullRequest() {
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return filterRepositories(issues), nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthCheckTTL is the time check results are reused for, so that probes
+// do not exhaust API rate limits.
+const healthCheckTTL = time.Minute
+
+// check is a single named verification of the bot's dependencies.
+type check struct {
+	Name string
+	Fn   func() error
+}
+
+// checkGithubCredentials verifies that the configured github credentials
+// are accepted.
+func checkGithubCredentials() error {
+	if err := githubOnly(); err != nil {
+		return nil
+	}
+	if appEnabled() {
+		_, err := installationToken()
+		return err
+	}
+	req, err := http.NewRequest("GET", *ghAPIFl+"/user", nil)
+	if err != nil {
+		return fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkTeam verifies that the team can be resolved and has members.
+func checkTeam() error {
+	members, err := forge.Members()
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return errors.New("team has no members")
+	}
+	return nil
+}
+
+// checkSlack verifies that Slack accepts the configured credentials.
+// Incoming webhooks cannot be checked without sending to them, so only
+// their URL is checked, unless -slack-probe is set. Then an empty message
+// is sent, which Slack rejects with "no_text" error for valid webhooks
+// without posting anything.
+func checkSlack() error {
+	if *slackTokenFl != "" {
+		return slackAPI("auth.test", map[string]interface{}{})
+	}
+	if *slackURLFl == "" {
+		return nil
+	}
+	if !*slackProbeFl {
+		u, err := url.Parse(*slackURLFl)
+		if err != nil {
+			return fmt.Errorf("invalid -slack-url: %s", err)
+		}
+		if u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("invalid -slack-url %q, expected https URL", redact(*slackURLFl))
+		}
+		return nil
+	}
+	resp, err := httpClient.Post(*slackURLFl, "application/json", bytes.NewBufferString("{}"))
+	if err != nil {
+		return fmt.Errorf("cannot POST data: %s", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusBadRequest && strings.Contains(string(body), "no_text") {
+		return nil
+	}
+	return fmt.Errorf("invalid response: %d, %s", resp.StatusCode, body)
+}
+
+// checkResult is the cached result of a list of checks.
+type checkResult struct {
+	At     time.Time
+	Errors map[string]string
+}
+
+var (
+	checksMu    sync.Mutex
+	checksCache = map[string]checkResult{}
+)
+
+// runChecks runs given checks and returns errors of the failed ones, keyed
+// by check name. Results are cached under given key for healthCheckTTL.
+func runChecks(key string, checks []check) map[string]string {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+
+	if res, ok := checksCache[key]; ok && time.Since(res.At) < healthCheckTTL {
+		return res.Errors
+	}
+	errs := map[string]string{}
+	for _, c := range checks {
+		if err := c.Fn(); err != nil {
+			errs[c.Name] = err.Error()
+		}
+	}
+	checksCache[key] = checkResult{At: time.Now(), Errors: errs}
+	return errs
+}
+
+// cachedChecks returns errors of the last run of checks cached under given
+// key, however old.
+func cachedChecks(key string) map[string]string {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	return checksCache[key].Errors
+}
+
+// livenessChecks are the checks of the process itself. There are none
+// besides serving the request: failing dependencies must not get the
+// process restarted, which would not fix them.
+var livenessChecks = []check{}
+
+// readinessChecks are the checks of all dependencies.
+var readinessChecks = []check{
+	{"github credentials", checkGithubCredentials},
+	{"team", checkTeam},
+	{"slack", checkSlack},
+}
+
+// healthHandler returns handler serving results of given checks.
+func healthHandler(key string, checks []check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var errs map[string]string
+		if globalsMu.TryRLock() {
+			errs = runChecks(key, checks)
+			globalsMu.RUnlock()
+		} else {
+			// a tenant scan has its own credentials swapped in, probes
+			// must not wait for it
+			errs = cachedChecks(key)
+		}
+		status := http.StatusOK
+		if len(errs) > 0 {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":     len(errs) == 0,
+			"errors": errs,
+		})
+	}
+}
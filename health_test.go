@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthChecks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	defer func(api, provider, slackURL, slackToken string) {
+		*ghAPIFl, *providerFl, *slackURLFl, *slackTokenFl = api, provider, slackURL, slackToken
+	}(*ghAPIFl, *providerFl, *slackURLFl, *slackTokenFl)
+	defer func() { checksCache = map[string]checkResult{} }()
+	*ghAPIFl, *providerFl, *slackURLFl, *slackTokenFl = srv.URL, "github", "", ""
+	checksCache = map[string]checkResult{}
+
+	tests := []struct {
+		name   string
+		checks []check
+		want   int
+	}{
+		// rejected credentials do not make the process unhealthy
+		{name: "healthz", checks: livenessChecks, want: http.StatusOK},
+		{name: "readyz", checks: readinessChecks, want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		healthHandler(tt.name, tt.checks)(w, httptest.NewRequest("GET", "/"+tt.name, nil))
+		if w.Code != tt.want {
+			t.Errorf("/%s status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// historyKey is the state store key of snapshots of past scans.
+const historyKey = "history/snapshots"
+
+// scanSnapshot is the summary of a single scan kept for trends.
+type scanSnapshot struct {
+	At    time.Time `json:"at"`
+	Stale int       `json:"stale"`
+	// Old is the number of stale pull requests past their old threshold.
+	Old int `json:"old"`
+	// MedianAge is the median time since stale pull requests became
+	// stale, in seconds.
+	MedianAge int64 `json:"median_age"`
+}
+
+// pullSnapshotsKey is the state store key of stale pull requests of past
+// scans.
+const pullSnapshotsKey = "history/pull-requests"
+
+// pullSnapshot is a stale pull request as seen by a single scan.
+type pullSnapshot struct {
+	Key        string    `json:"key"`
+	Assignee   string    `json:"assignee,omitempty"`
+	StaleSince time.Time `json:"stale_since"`
+}
+
+// scanPulls are stale pull requests seen by a single scan.
+type scanPulls struct {
+	At    time.Time      `json:"at"`
+	Pulls []pullSnapshot `json:"pulls"`
+}
+
+var historyMu sync.Mutex
+
+// loadHistory returns snapshots of past scans, the oldest first.
+func loadHistory() ([]scanSnapshot, error) {
+	var snapshots []scanSnapshot
+	_, err := loadDocument(historyKey, &snapshots)
+	return snapshots, err
+}
+
+// loadPullSnapshots returns stale pull requests of past scans, the oldest
+// first.
+func loadPullSnapshots() ([]scanPulls, error) {
+	var snapshots []scanPulls
+	_, err := loadDocument(pullSnapshotsKey, &snapshots)
+	return snapshots, err
+}
+
+// recordSnapshot appends snapshot of given stale pull requests to the
+// history, dropping snapshots older than -history-retention.
+func recordSnapshot(stale []Issue, now time.Time) {
+	s := scanSnapshot{At: now, Stale: len(stale)}
+	pulls := scanPulls{At: now, Pulls: make([]pullSnapshot, 0, len(stale))}
+	ages := make([]time.Duration, 0, len(stale))
+	for i := range stale {
+		since := staleSince(&stale[i])
+		ages = append(ages, now.Sub(since))
+		if since.Add(policyFor(&stale[i]).Old).Before(now) {
+			s.Old++
+		}
+		p := pullSnapshot{Key: issueKey(&stale[i]), StaleSince: since}
+		if stale[i].Assignee != nil {
+			p.Assignee = stale[i].Assignee.Login
+		}
+		pulls.Pulls = append(pulls.Pulls, p)
+	}
+	s.MedianAge = int64(median(ages) / time.Second)
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	recordPulls(pulls)
+	snapshots, err := loadHistory()
+	if err != nil {
+		log.Printf("cannot load history: %s", err)
+		return
+	}
+	kept := snapshots[:0]
+	for _, old := range snapshots {
+		if old.At.After(now.Add(-*historyRetentionFl)) {
+			kept = append(kept, old)
+		}
+	}
+	if err := saveDocument(historyKey, append(kept, s)); err != nil {
+		log.Printf("cannot save history: %s", err)
+	}
+}
+
+// recordPulls appends given stale pull requests to their history, dropping
+// those of scans older than -history-retention.
+func recordPulls(pulls scanPulls) {
+	snapshots, err := loadPullSnapshots()
+	if err != nil {
+		log.Printf("cannot load history of pull requests: %s", err)
+		return
+	}
+	kept := snapshots[:0]
+	for _, old := range snapshots {
+		if old.At.After(pulls.At.Add(-*historyRetentionFl)) {
+			kept = append(kept, old)
+		}
+	}
+	if err := saveDocument(pullSnapshotsKey, append(kept, pulls)); err != nil {
+		log.Printf("cannot save history of pull requests: %s", err)
+	}
+}
+
+// median returns median of given durations, zero if there are none.
+func median(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// snapshotAt returns the last snapshot taken at or before given time, false
+// if there is none.
+func snapshotAt(snapshots []scanSnapshot, t time.Time) (scanSnapshot, bool) {
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if !snapshots[i].At.After(t) {
+			return snapshots[i], true
+		}
+	}
+	return scanSnapshot{}, false
+}
+
+// dailySnapshots returns the last snapshot of each of given number of days
+// before and including the day of given time, skipping days without any.
+func dailySnapshots(snapshots []scanSnapshot, now time.Time, days int) []scanSnapshot {
+	var daily []scanSnapshot
+	for d := days - 1; d >= 0; d-- {
+		day := now.In(timezone).AddDate(0, 0, -d)
+		end := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, timezone).AddDate(0, 0, 1)
+		s, ok := snapshotAt(snapshots, end)
+		if !ok || (len(daily) > 0 && s.At.Equal(daily[len(daily)-1].At)) {
+			continue
+		}
+		daily = append(daily, s)
+	}
+	return daily
+}
+
+// sparkBars are characters of sparklines, from the lowest to the highest.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline returns given values drawn as a line of bars scaled between the
+// lowest and the highest value.
+func sparkline(values []int64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int((v - lo) * int64(len(sparkBars)-1) / (hi - lo))
+		}
+		b.WriteRune(sparkBars[i])
+	}
+	return b.String()
+}
+
+// signedCount returns given difference with explicit sign.
+func signedCount(d int) string {
+	return fmt.Sprintf("%+d", d)
+}
+
+// signedAge returns given age difference with explicit sign.
+func signedAge(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatAge(-d)
+	}
+	return "+" + formatAge(d)
+}
+
+// trendText returns Markdown lines describing trends of the number of stale
+// pull requests and their median age over the last two weeks, compared to
+// a week ago. Empty if there is no history.
+func trendText(snapshots []scanSnapshot, now time.Time) string {
+	current, ok := snapshotAt(snapshots, now)
+	if !ok {
+		return ""
+	}
+	daily := dailySnapshots(snapshots, now, 14)
+	counts := make([]int64, len(daily))
+	ages := make([]int64, len(daily))
+	for i, s := range daily {
+		counts[i] = int64(s.Stale)
+		ages[i] = s.MedianAge
+	}
+	age := time.Duration(current.MedianAge) * time.Second
+	stale := fmt.Sprintf("%d", current.Stale)
+	medianAge := formatAge(age)
+	if before, ok := snapshotAt(snapshots, now.AddDate(0, 0, -7)); ok {
+		stale += fmt.Sprintf(" (%s vs last week)", signedCount(current.Stale-before.Stale))
+		medianAge += fmt.Sprintf(" (%s vs last week)", signedAge(age-time.Duration(before.MedianAge)*time.Second))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "- Stale pull requests: %s `%s`\n", stale, sparkline(counts))
+	fmt.Fprintf(&b, "- Median stale age: %s `%s`\n", medianAge, sparkline(ages))
+	return b.String()
+}
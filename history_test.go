@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		in   []time.Duration
+		want time.Duration
+	}{
+		{in: nil, want: 0},
+		{in: []time.Duration{time.Hour}, want: time.Hour},
+		{in: []time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour}, want: 2 * time.Hour},
+		{in: []time.Duration{4 * time.Hour, time.Hour, 2 * time.Hour, 3 * time.Hour}, want: 150 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := median(tt.in); got != tt.want {
+			t.Errorf("median(%v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		in   []int64
+		want string
+	}{
+		{in: nil, want: ""},
+		{in: []int64{5, 5, 5}, want: "▁▁▁"},
+		{in: []int64{0, 7}, want: "▁█"},
+		{in: []int64{0, 1, 2, 3, 4, 5, 6, 7}, want: "▁▂▃▄▅▆▇█"},
+		{in: []int64{10, 20, 15}, want: "▁█▄"},
+	}
+	for _, tt := range tests {
+		if got := sparkline(tt.in); got != tt.want {
+			t.Errorf("sparkline(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
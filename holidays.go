@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// calendar is a set of holiday dates of a region.
+type calendar struct {
+	// dates holds holidays in "2006-01-02" format.
+	dates map[string]bool
+	// yearly holds holidays repeating every year in "01-02" format.
+	yearly map[string]bool
+	// country is the ISO 3166 country code for calendars generated from
+	// the public holidays API, empty for ICS calendars.
+	country string
+	// years are the years fetched from the public holidays API.
+	years map[int]bool
+	// pending are closed once the years being fetched are fetched.
+	pending map[int]chan struct{}
+}
+
+var (
+	calendarsMu sync.Mutex
+	calendars   = map[string]*calendar{}
+)
+
+// loadHolidays loads calendars configured in the holidays section of the
+// configuration. Calendars given by country code are fetched lazily.
+func loadHolidays() error {
+	loaded := map[string]*calendar{}
+	for region, source := range config.Holidays {
+		c := &calendar{dates: map[string]bool{}, yearly: map[string]bool{}, years: map[int]bool{}, pending: map[int]chan struct{}{}}
+		if source == "" {
+			c.country = region
+		} else if err := c.loadICS(source); err != nil {
+			return fmt.Errorf("cannot load holidays of %s: %s", region, err)
+		}
+		loaded[region] = c
+	}
+	calendarsMu.Lock()
+	calendars = loaded
+	calendarsMu.Unlock()
+	return nil
+}
+
+// loadICS reads all-day events from ICS file or URL. Yearly recurring events
+// are supported, other recurrence rules are ignored.
+func (c *calendar) loadICS(source string) error {
+	var r io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := httpClient.Get(source)
+		if err != nil {
+			return fmt.Errorf("cannot GET calendar: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		fd, err := os.Open(source)
+		if err != nil {
+			return fmt.Errorf("cannot open file: %s", err)
+		}
+		defer fd.Close()
+		r = fd
+	}
+
+	// unfold continuation lines first
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("cannot read calendar: %s", err)
+	}
+
+	var start, end time.Time
+	yearly := false
+	for _, line := range lines {
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		name, value := line[:i], line[i+1:]
+		if j := strings.Index(name, ";"); j >= 0 {
+			name = name[:j]
+		}
+		switch name {
+		case "BEGIN":
+			start, end, yearly = time.Time{}, time.Time{}, false
+		case "DTSTART":
+			start = icsDate(value)
+		case "DTEND":
+			end = icsDate(value)
+		case "RRULE":
+			yearly = strings.Contains(value, "FREQ=YEARLY")
+		case "END":
+			if value != "VEVENT" || start.IsZero() {
+				continue
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				if yearly {
+					c.yearly[d.Format("01-02")] = true
+				} else {
+					c.dates[d.Format("2006-01-02")] = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// icsDate returns date part of ICS date or date-time value, or zero time if
+// it is invalid.
+func icsDate(value string) time.Time {
+	if len(value) > 8 {
+		value = value[:8]
+	}
+	t, _ := time.Parse("20060102", value)
+	return t
+}
+
+// fetchHolidays returns public holidays of given country in given year, in
+// "2006-01-02" format, from the Nager.Date API.
+func fetchHolidays(country string, year int) ([]string, error) {
+	url := fmt.Sprintf("https://date.nager.at/api/v3/PublicHolidays/%d/%s", year, country)
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var holidays []struct {
+		Date   string `json:"date"`
+		Global bool   `json:"global"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&holidays); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %s", err)
+	}
+	var dates []string
+	for _, h := range holidays {
+		// regional holidays apply to parts of the country only
+		if h.Global {
+			dates = append(dates, h.Date)
+		}
+	}
+	return dates, nil
+}
+
+// isHoliday returns true if given date is a holiday in given region. Unknown
+// regions have no holidays. Holidays of a year are fetched once, without
+// holding the lock, while other callers asking about the same year wait.
+func isHoliday(region string, date time.Time) bool {
+	year := date.Year()
+	calendarsMu.Lock()
+	defer calendarsMu.Unlock()
+
+	c, ok := calendars[region]
+	if !ok {
+		return false
+	}
+	for c.country != "" && !c.years[year] {
+		if wait, ok := c.pending[year]; ok {
+			calendarsMu.Unlock()
+			<-wait
+			calendarsMu.Lock()
+			continue
+		}
+		done := make(chan struct{})
+		c.pending[year] = done
+		calendarsMu.Unlock()
+		dates, err := fetchHolidays(c.country, year)
+		calendarsMu.Lock()
+		delete(c.pending, year)
+		close(done)
+		if err != nil {
+			log.Printf("cannot fetch holidays of %s: %s", region, err)
+		}
+		for _, d := range dates {
+			c.dates[d] = true
+		}
+		// do not retry failed years on every call
+		c.years[year] = true
+	}
+	return c.dates[date.Format("2006-01-02")] || c.yearly[date.Format("01-02")]
+}
+
+// onHoliday returns true if member with given login has a public holiday at
+// given time, according to region and timezone of the member.
+func onHoliday(login string, now time.Time) bool {
+	region := config.Members[login].Region
+	if region == "" {
+		return false
+	}
+	return isHoliday(region, now.In(memberLocation(login)))
+}
+
+// skipHolidays returns given time moved forward by the holiday time of the
+// -holiday-region between it and now, so that holidays do not count towards
+// staleness.
+func skipHolidays(since, now time.Time) time.Time {
+	if *holidayRegionFl == "" || !since.Before(now) {
+		return since
+	}
+	var skipped time.Duration
+	local := since.In(timezone)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, timezone)
+	for ; day.Before(now); day = day.AddDate(0, 0, 1) {
+		if !isHoliday(*holidayRegionFl, day) {
+			continue
+		}
+		from, to := day, day.AddDate(0, 0, 1)
+		if from.Before(since) {
+			from = since
+		}
+		if to.After(now) {
+			to = now
+		}
+		skipped += to.Sub(from)
+	}
+	return since.Add(skipped)
+}
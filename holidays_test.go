@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSkipHolidays(t *testing.T) {
+	defer func(tz *time.Location, region string, cals map[string]*calendar) {
+		timezone, *holidayRegionFl, calendars = tz, region, cals
+	}(timezone, *holidayRegionFl, calendars)
+	timezone = time.UTC
+	*holidayRegionFl = "test"
+	calendars = map[string]*calendar{
+		"test": {
+			dates:  map[string]bool{"2026-10-05": true},
+			yearly: map[string]bool{"12-25": true},
+		},
+	}
+
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		since, now, want string
+	}{
+		// no holiday in between
+		{since: "2026-10-01 10:00", now: "2026-10-02 10:00", want: "2026-10-01 10:00"},
+		// whole holiday in between
+		{since: "2026-10-04 10:00", now: "2026-10-06 10:00", want: "2026-10-05 10:00"},
+		// stale since the middle of the holiday
+		{since: "2026-10-05 12:00", now: "2026-10-06 10:00", want: "2026-10-06 00:00"},
+		// now in the middle of the holiday
+		{since: "2026-10-04 10:00", now: "2026-10-05 06:00", want: "2026-10-04 16:00"},
+		// yearly holiday
+		{since: "2030-12-24 10:00", now: "2030-12-26 10:00", want: "2030-12-25 10:00"},
+		// since after now
+		{since: "2026-10-06 10:00", now: "2026-10-04 10:00", want: "2026-10-06 10:00"},
+	}
+	for _, tt := range tests {
+		got := skipHolidays(date(tt.since), date(tt.now))
+		if want := date(tt.want); !got.Equal(want) {
+			t.Errorf("skipHolidays(%s, %s) = %s, want %s", tt.since, tt.now, got, want)
+		}
+	}
+}
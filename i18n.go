@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// catalog maps message IDs to fmt format strings. Formats use explicit
+// argument indexes, like %[1]s, so that translations can reorder them.
+type catalog map[string]string
+
+// catalogs are the bundled translations of bot messages by locale. English
+// is complete, other locales fall back to it for missing messages.
+var catalogs = map[string]catalog{
+	"en": {
+		"assign_comment":         "Pull request seem to be stale, assigning @%[1]s as the responsible developer.",
+		"reassign_comment":       "Reassigning pull request to @%[1]s as the responsible developer.",
+		"shadow_comment":         "@%[1]s is shadowing the review to learn, reviewing is optional.",
+		"remind_comment":         "@%[1]s, please take a look at this stale pull request.",
+		"escalate_comment":       "%[1]s, this pull request assigned to @%[2]s is not progressing.",
+		"merged_comment":         "Pull request was approved, passed all checks and was open for %[1]s, merged it using %[2]s method.",
+		"issue_assign_comment":   "Issue seem to need triage, assigning @%[1]s as the responsible developer.",
+		"ticket_comment":         "Pull request %[1]s implementing this issue is waiting for review for %[2]s, it is assigned to %[3]s.",
+		"description_nudge":      "@%[1]s, please help reviewers with more context in the description: %[2]s.",
+		"description_short":      "it is very short",
+		"description_section":    "the %[1]q section is missing or empty",
+		"link_missing":           "@%[1]s, please link the issue or ticket this pull request implements in its title or description.",
+		"link_missing_hold":      "@%[1]s, please link the issue or ticket this pull request implements in its title or description. Reviewers are assigned once it is linked.",
+		"nobody":                 "nobody",
+		"check_waiting":          "Waiting for review for %[1]s",
+		"check_overdue":          "Review overdue, waiting for %[1]s",
+		"check_summary":          "This pull request is stale for %[1]s and assigned to @%[2]s.",
+		"remind":                 "@%[1]s, please work on <%[2]s|Pull Request #%[3]d> (%[4]s)",
+		"remind_stale":           "@%[1]s, when you have a moment, please take a look at <%[2]s|Pull Request #%[3]d> (%[4]s)",
+		"remind_approvals":       "@%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) %[5]s",
+		"approvals_one":          "needs 1 more approval",
+		"approvals_many":         "needs %[1]d more approvals",
+		"escalate":               "%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) assigned to @%[5]s is not progressing",
+		"assigned":               "%[1]s, you were assigned to <%[2]s|Pull Request #%[3]d> (%[4]s)",
+		"author_notice":          "%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) %[5]s",
+		"changes_requested":      "has changes requested by %[1]s, please address the review comments",
+		"no_reviewers":           "has no reviewers, please request a review",
+		"ready":                  "collected all required approvals and is ready to merge",
+		"ci_failed":              "fails CI, please take a look",
+		"reviewed":               "%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) was reviewed by %[5]s after the last commit, please address the review or request another one",
+		"rerequested":            "%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) got new commits since you requested changes, please review it again",
+		"triage":                 "@%[1]s, please triage <%[2]s|Issue #%[3]d> (%[4]s)",
+		"batch_header":           "%[1]d pull requests are waiting for review:",
+		"batch_line":             "• %[1]s (stale for %[2]s)",
+		"no_team":                "No team",
+		"slo_breach":             ":chart_with_downwards_trend: SLO %[1]s is breached: %.1[2]f%% of the last %[4]d pull requests got their first review within %[5]s, the target is %.1[3]f%%.",
+		"slo_recovered":          ":chart_with_upwards_trend: SLO %[1]s is met again: %.1[2]f%% of pull requests got their first review in time, the target is %.1[3]f%%.",
+		"manager_header":         "%[1]d stale pull requests are waiting for review, %[2]d of them past their critical threshold.",
+		"manager_team":           "• %[1]s: %[2]d waiting, %[3]d critical",
+		"manager_longest":        "Waiting longest:",
+		"button_snooze":          "Snooze %[1]s",
+		"button_reassign":        "Reassign",
+		"button_ack":             "On it",
+		"branches_header":        "%[1]d branches have no open pull request and were not pushed to for more than %[2]s:",
+		"branches_line":          "• %[1]s:%[2]s by %[3]s, last push %[4]s ago",
+		"branches_unknown_owner": "unknown",
+		"branches_delete_button": "Delete %[1]s:%[2]s",
+		"branches_deleted":       "Branch %[1]s:%[2]s deleted by %[3]s.",
+		"paging_summary":         "Critical pull request %[1]s (%[2]s) is waiting for review for %[3]s",
+		"slash_no_stale":         "No stale pull requests.",
+		"slash_scan_started":     "Scan started.",
+		"slash_scan_finished":    "Scan finished.",
+		"slash_looking":          "Looking for stale pull requests...",
+		"report_title":           "Stale pull requests, week %[1]s",
+		"slash_scan_failed":      "Scan failed: %[1]s",
+		"slash_list_failed":      "Cannot list pull requests: %[1]s",
+		"slash_invalid_arg":      "Invalid argument %[1]q, expected key=value.",
+		"slash_usage":            "Usage: `run` to scan pull requests now, `list [repo=<name>]` to list stale pull requests.",
+		"unassigned":             "unassigned",
+		"summary_failed":         "Scan failed after %[1]s: %[2]s",
+		"summary":                "Scanned %[1]d pull requests in %[2]s: %[3]d stale, %[4]d newly assigned, %[5]d reminded, %[6]d errors, %[7]d failed API requests",
+		"vacation_comment":       "@%[1]s %[2]s, reassigning @%[3]s as the responsible developer.",
+		"on_vacation":            "is on vacation",
+		"left_team":              "is no longer a team member",
+		"sla":                    "%[1]s SLA: assigned after %[2]s, reminded after %[3]s",
+		"size":                   "[size %[1]s]",
+		"report_header":          "%[1]d stale pull requests as of %[2]s.",
+		"report_columns":         "| Pull request | Repository | Stale for | Assignee | State |",
+		"report_ci":              "CI %[1]s",
+		"field_repository":       "Repository",
+		"field_age":              "Age",
+		"field_assignee":         "Assignee",
+		"button_open":            "Open pull request",
+	},
+	"de": {
+		"assign_comment":         "Der Pull Request scheint liegen geblieben zu sein, @%[1]s ist jetzt dafür verantwortlich.",
+		"reassign_comment":       "Der Pull Request wird @%[1]s neu zugewiesen, @%[1]s ist jetzt dafür verantwortlich.",
+		"shadow_comment":         "@%[1]s begleitet das Review, um den Code kennenzulernen, ein eigenes Review ist optional.",
+		"remind_comment":         "@%[1]s, bitte schau dir diesen liegen gebliebenen Pull Request an.",
+		"escalate_comment":       "%[1]s, dieser Pull Request ist @%[2]s zugewiesen und kommt nicht voran.",
+		"merged_comment":         "Der Pull Request wurde freigegeben, hat alle Checks bestanden und war %[1]s offen, er wurde mit der Methode %[2]s gemergt.",
+		"issue_assign_comment":   "Das Issue muss triagiert werden, @%[1]s ist jetzt dafür verantwortlich.",
+		"ticket_comment":         "Der Pull Request %[1]s, der dieses Issue umsetzt, wartet seit %[2]s auf ein Review, er ist %[3]s zugewiesen.",
+		"description_nudge":      "@%[1]s, bitte gib den Reviewern mehr Kontext in der Beschreibung: %[2]s.",
+		"description_short":      "sie ist sehr kurz",
+		"description_section":    "der Abschnitt %[1]q fehlt oder ist leer",
+		"link_missing":           "@%[1]s, bitte verlinke das Issue oder Ticket, das dieser Pull Request umsetzt, im Titel oder in der Beschreibung.",
+		"link_missing_hold":      "@%[1]s, bitte verlinke das Issue oder Ticket, das dieser Pull Request umsetzt, im Titel oder in der Beschreibung. Reviewer werden zugewiesen, sobald es verlinkt ist.",
+		"nobody":                 "niemandem",
+		"check_waiting":          "Wartet seit %[1]s auf ein Review",
+		"check_overdue":          "Review überfällig, wartet seit %[1]s",
+		"check_summary":          "Dieser Pull Request liegt seit %[1]s und ist @%[2]s zugewiesen.",
+		"remind":                 "@%[1]s, bitte kümmere dich um <%[2]s|Pull Request #%[3]d> (%[4]s)",
+		"remind_stale":           "@%[1]s, wenn du einen Moment Zeit hast, schau dir bitte <%[2]s|Pull Request #%[3]d> (%[4]s) an",
+		"remind_approvals":       "@%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) %[5]s",
+		"approvals_one":          "braucht noch 1 Freigabe",
+		"approvals_many":         "braucht noch %[1]d Freigaben",
+		"escalate":               "%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) ist @%[5]s zugewiesen und kommt nicht voran",
+		"assigned":               "%[1]s, dir wurde <%[2]s|Pull Request #%[3]d> (%[4]s) zugewiesen",
+		"author_notice":          "%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) %[5]s",
+		"changes_requested":      "hat Änderungswünsche von %[1]s, bitte arbeite die Review-Kommentare ein",
+		"no_reviewers":           "hat keine Reviewer, bitte fordere ein Review an",
+		"ready":                  "hat alle nötigen Freigaben und kann gemergt werden",
+		"ci_failed":              "schlägt in der CI fehl, bitte schau es dir an",
+		"reviewed":               "%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) wurde nach dem letzten Commit von %[5]s reviewt, bitte arbeite das Review ein oder fordere ein neues an",
+		"rerequested":            "%[1]s, <%[2]s|Pull Request #%[3]d> (%[4]s) hat seit deinen Änderungswünschen neue Commits, bitte reviewe ihn noch einmal",
+		"triage":                 "@%[1]s, bitte triagiere <%[2]s|Issue #%[3]d> (%[4]s)",
+		"batch_header":           "%[1]d Pull Requests warten auf ein Review:",
+		"batch_line":             "• %[1]s (liegt seit %[2]s)",
+		"no_team":                "Ohne Team",
+		"slo_breach":             ":chart_with_downwards_trend: SLO %[1]s wird verfehlt: %.1[2]f%% der letzten %[4]d Pull Requests hatten ihr erstes Review innerhalb von %[5]s, das Ziel ist %.1[3]f%%.",
+		"slo_recovered":          ":chart_with_upwards_trend: SLO %[1]s wird wieder erreicht: %.1[2]f%% der Pull Requests hatten ihr erstes Review rechtzeitig, das Ziel ist %.1[3]f%%.",
+		"manager_header":         "%[1]d liegen gebliebene Pull Requests warten auf ein Review, %[2]d davon über ihrer kritischen Schwelle.",
+		"manager_team":           "• %[1]s: %[2]d wartend, %[3]d kritisch",
+		"manager_longest":        "Warten am längsten:",
+		"button_snooze":          "%[1]s schlummern",
+		"button_reassign":        "Neu zuweisen",
+		"button_ack":             "Bin dran",
+		"branches_header":        "%[1]d Branches haben keinen offenen Pull Request und wurden seit mehr als %[2]s nicht gepusht:",
+		"branches_line":          "• %[1]s:%[2]s von %[3]s, letzter Push vor %[4]s",
+		"branches_unknown_owner": "unbekannt",
+		"branches_delete_button": "%[1]s:%[2]s löschen",
+		"branches_deleted":       "Branch %[1]s:%[2]s wurde von %[3]s gelöscht.",
+		"paging_summary":         "Kritischer Pull Request %[1]s (%[2]s) wartet seit %[3]s auf ein Review",
+		"slash_no_stale":         "Keine liegen gebliebenen Pull Requests.",
+		"slash_scan_started":     "Scan gestartet.",
+		"slash_scan_finished":    "Scan beendet.",
+		"slash_looking":          "Suche liegen gebliebene Pull Requests...",
+		"report_title":           "Liegen gebliebene Pull Requests, Woche %[1]s",
+		"slash_scan_failed":      "Scan fehlgeschlagen: %[1]s",
+		"slash_list_failed":      "Pull Requests können nicht aufgelistet werden: %[1]s",
+		"slash_invalid_arg":      "Ungültiges Argument %[1]q, erwartet wird key=value.",
+		"slash_usage":            "Verwendung: `run` scannt Pull Requests sofort, `list [repo=<name>]` listet liegen gebliebene Pull Requests auf.",
+		"unassigned":             "nicht zugewiesen",
+		"summary_failed":         "Scan nach %[1]s fehlgeschlagen: %[2]s",
+		"summary":                "%[1]d Pull Requests in %[2]s gescannt: %[3]d liegen geblieben, %[4]d neu zugewiesen, %[5]d erinnert, %[6]d Fehler, %[7]d fehlgeschlagene API-Anfragen",
+		"vacation_comment":       "@%[1]s %[2]s, @%[3]s ist jetzt dafür verantwortlich.",
+		"on_vacation":            "ist im Urlaub",
+		"left_team":              "ist nicht mehr im Team",
+		"sla":                    "%[1]s SLA: zugewiesen nach %[2]s, erinnert nach %[3]s",
+		"size":                   "[Größe %[1]s]",
+		"report_header":          "%[1]d liegen gebliebene Pull Requests, Stand %[2]s.",
+		"report_columns":         "| Pull Request | Repository | Liegt seit | Zugewiesen | Status |",
+		"report_ci":              "CI %[1]s",
+		"field_repository":       "Repository",
+		"field_age":              "Alter",
+		"field_assignee":         "Zugewiesen",
+		"button_open":            "Pull Request öffnen",
+	},
+}
+
+// localeFor returns locale of messages about given issue, from its policy
+// or -locale. Nil issue gets -locale.
+func localeFor(issue *Issue) string {
+	if issue != nil {
+		if p := issuePolicy(issue); p != nil && p.Locale != "" {
+			return p.Locale
+		}
+	}
+	return *localeFl
+}
+
+// tr returns message with given ID about given issue, formatted with given
+// arguments. Messages configured in the configuration file take precedence
+// over bundled ones, English is used for messages missing in the locale.
+func tr(issue *Issue, id string, args ...interface{}) string {
+	locale := localeFor(issue)
+	format, ok := config.Messages[locale][id]
+	if !ok {
+		format, ok = catalogs[locale][id]
+	}
+	if !ok {
+		format = catalogs["en"][id]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// checkLocale verifies that given locale is bundled or configured.
+func checkLocale(locale string) error {
+	if _, ok := catalogs[locale]; ok {
+		return nil
+	}
+	if _, ok := config.Messages[locale]; ok {
+		return nil
+	}
+	var known []string
+	for l := range catalogs {
+		known = append(known, l)
+	}
+	sort.Strings(known)
+	return fmt.Errorf("unknown locale %q, expected one of: %s, or messages in the configuration file", locale, strings.Join(known, ", "))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ignoredKey is the state store key of pull requests ignored with the
+// ignore subcommand.
+const ignoredKey = "ignored"
+
+var (
+	ignoredMu sync.Mutex
+	// ignoredKeys are keys of ignored pull requests, both configured and
+	// stored, loaded at the beginning of each scan.
+	ignoredKeys = map[string]bool{}
+)
+
+// ignoreEntryKey returns pull request key, "repo#number", of given ignore
+// list entry. Entries are keys, keys prefixed with the organization, or
+// pull request URLs. GitLab keys keep the full project path, like
+// "group/subgroup/project#12", as GitLab projects are nested in groups.
+func ignoreEntryKey(entry string) (string, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.HasPrefix(entry, "http://") || strings.HasPrefix(entry, "https://") {
+		u, err := url.Parse(entry)
+		if err != nil {
+			return "", fmt.Errorf("invalid URL %q: %s", entry, err)
+		}
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		for i := len(parts) - 2; i > 0; i-- {
+			switch parts[i] {
+			case "pull", "pulls", "issues", "merge_requests", "pull-requests":
+				repo := parts[i-1]
+				if parts[i] == "merge_requests" {
+					// GitLab URLs contain the full project path, usually
+					// followed by /-/
+					end := i
+					if parts[i-1] == "-" {
+						end = i - 1
+					}
+					repo = strings.Join(parts[:end], "/")
+				}
+				if _, err := strconv.ParseInt(parts[i+1], 10, 64); err != nil {
+					return "", fmt.Errorf("invalid pull request number in %q", entry)
+				}
+				return repo + "#" + parts[i+1], nil
+			}
+		}
+		return "", fmt.Errorf("%q is not a pull request URL", entry)
+	}
+	i := strings.LastIndex(entry, "#")
+	if i <= 0 {
+		return "", fmt.Errorf("invalid entry %q, expected repo#number or URL", entry)
+	}
+	if _, err := strconv.ParseInt(entry[i+1:], 10, 64); err != nil {
+		return "", fmt.Errorf("invalid pull request number in %q", entry)
+	}
+	repo := entry[:i]
+	if j := strings.LastIndex(repo, "/"); j >= 0 && *providerFl != "gitlab" {
+		repo = repo[j+1:]
+	}
+	return repo + "#" + entry[i+1:], nil
+}
+
+// loadStoredIgnores returns keys of pull requests ignored with the ignore
+// subcommand.
+func loadStoredIgnores() ([]string, error) {
+	var keys []string
+	if _, err := loadDocument(ignoredKey, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// loadIgnoreList loads ignored pull requests from the configuration and the
+// state store.
+func loadIgnoreList() {
+	keys := map[string]bool{}
+	for _, entry := range config.Ignore {
+		key, err := ignoreEntryKey(entry)
+		if err != nil {
+			log.Printf("ignoring invalid ignore entry: %s", err)
+			continue
+		}
+		keys[key] = true
+	}
+	stored, err := loadStoredIgnores()
+	if err != nil {
+		log.Printf("cannot load ignore list: %s", err)
+	}
+	for _, key := range stored {
+		keys[key] = true
+	}
+	ignoredMu.Lock()
+	ignoredKeys = keys
+	ignoredMu.Unlock()
+}
+
+// ignored returns true if given pull request is on the ignore list and must
+// not be touched by the bot.
+func ignored(issue *Issue) bool {
+	ignoredMu.Lock()
+	defer ignoredMu.Unlock()
+	return ignoredKeys[issueKey(issue)]
+}
+
+// runIgnore runs the ignore subcommand with given arguments: "add" or
+// "remove" followed by entries, or "list".
+func runIgnore(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected add, remove or list")
+	}
+	stored, err := loadStoredIgnores()
+	if err != nil {
+		return err
+	}
+	keys := map[string]bool{}
+	for _, key := range stored {
+		keys[key] = true
+	}
+	switch args[0] {
+	case "list":
+		for _, entry := range config.Ignore {
+			fmt.Fprintf(w, "%s (configuration file)\n", entry)
+		}
+		for _, key := range stored {
+			fmt.Fprintln(w, key)
+		}
+		return nil
+	case "add", "remove":
+		for _, entry := range args[1:] {
+			key, err := ignoreEntryKey(entry)
+			if err != nil {
+				return err
+			}
+			keys[key] = args[0] == "add"
+		}
+	default:
+		return fmt.Errorf("unknown ignore command %q, expected add, remove or list", args[0])
+	}
+	updated := []string{}
+	for key, on := range keys {
+		if on {
+			updated = append(updated, key)
+		}
+	}
+	sort.Strings(updated)
+	return saveDocument(ignoredKey, updated)
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestIgnoreEntryKey(t *testing.T) {
+	defer func(provider string) { *providerFl = provider }(*providerFl)
+	tests := []struct {
+		provider, entry, want string
+		err                   bool
+	}{
+		{provider: "github", entry: "api#12", want: "api#12"},
+		{provider: "github", entry: " acme/api#12 ", want: "api#12"},
+		{provider: "github", entry: "https://github.com/acme/api/pull/12", want: "api#12"},
+		{provider: "github", entry: "https://github.com/acme/api/pull/12/files", want: "api#12"},
+		{provider: "gitlab", entry: "group/sub/api#12", want: "group/sub/api#12"},
+		{provider: "gitlab", entry: "https://gitlab.com/group/sub/api/-/merge_requests/12", want: "group/sub/api#12"},
+		{provider: "bitbucket", entry: "https://bitbucket.org/acme/api/pull-requests/12", want: "api#12"},
+		{provider: "github", entry: "https://github.com/acme/api", err: true},
+		{provider: "github", entry: "https://github.com/acme/api/pull/new", err: true},
+		{provider: "github", entry: "api", err: true},
+		{provider: "github", entry: "#12", err: true},
+		{provider: "github", entry: "api#twelve", err: true},
+	}
+	for _, tt := range tests {
+		*providerFl = tt.provider
+		got, err := ignoreEntryKey(tt.entry)
+		if (err != nil) != tt.err {
+			t.Errorf("ignoreEntryKey(%q) error = %v, want error %v", tt.entry, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ignoreEntryKey(%q) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+}
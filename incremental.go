@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// issueCache keeps open issues and pull requests between scans for
+// incremental scanning.
+type issueCache struct {
+	// open maps IDs of open issues to their last fetched version.
+	open map[int64]Issue
+	// lastUpdate is the latest update time of fetched issues, as reported
+	// by github, so that clock skew does not matter.
+	lastUpdate time.Time
+	// lastFullScan is the time all open issues were last fetched.
+	lastFullScan time.Time
+}
+
+var (
+	issueCachesMu sync.Mutex
+	// issueCaches maps scopes returned by issueScope to their caches, so
+	// that tenants do not share them.
+	issueCaches = map[string]*issueCache{}
+)
+
+// issueScope returns what the issues are listed for.
+func issueScope() string {
+	return tenantScope() + *ghAPIFl + "|" + *ownerTypeFl + "|" + *ghOrgFl + "|" + *reposFl
+}
+
+// incrementalIssues returns all open issues and pull requests, fetching
+// only those updated since the previous scan and merging them with open
+// issues cached from previous scans. Every -full-scan-interval, and when
+// there is no cache, all open issues are fetched, which also drops issues
+// that were transferred or whose repositories were removed or skipped.
+func incrementalIssues(now time.Time) ([]Issue, error) {
+	issueCachesMu.Lock()
+	defer issueCachesMu.Unlock()
+
+	c := issueCaches[issueScope()]
+	if c == nil || now.Sub(c.lastFullScan) >= *fullScanIntervalFl {
+		issues, err := fetchIssues("state=open")
+		if err != nil {
+			return nil, err
+		}
+		c = &issueCache{open: map[int64]Issue{}, lastFullScan: now}
+		c.merge(issues)
+		if c.lastUpdate.IsZero() {
+			// nothing open, changes are fetched from now on
+			c.lastUpdate = now
+		}
+		issueCaches[issueScope()] = c
+		return c.issues(), nil
+	}
+
+	// state=all, so that pull requests closed since the previous scan are
+	// dropped from the cache
+	since := c.lastUpdate
+	changed, err := fetchIssues("state=all&sort=updated&since=" + url.QueryEscape(since.UTC().Format(time.RFC3339)))
+	if err != nil {
+		return nil, err
+	}
+	c.merge(changed)
+	log.Printf("%d issues changed since %s, %d open", len(changed), since.Format(time.RFC3339), len(c.open))
+	return c.issues(), nil
+}
+
+// merge updates the cache with given fetched issues.
+func (c *issueCache) merge(issues []Issue) {
+	for _, issue := range issues {
+		if issue.UpdatedAt.After(c.lastUpdate) {
+			c.lastUpdate = issue.UpdatedAt
+		}
+		if issue.State == "closed" {
+			delete(c.open, issue.ID)
+			continue
+		}
+		c.open[issue.ID] = issue
+	}
+}
+
+// issues returns copy of the cached open issues, the newest first, like
+// the issues API lists them.
+func (c *issueCache) issues() []Issue {
+	issues := make([]Issue, 0, len(c.open))
+	for _, issue := range c.open {
+		issues = append(issues, issue)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].CreatedAt.After(issues[j].CreatedAt)
+	})
+	return issues
+}
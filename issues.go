@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// staleIssues return all issues (not pull requests) from given issues that
+// have one of the triage labels and were created, or active, longer ago than
+// the issue stale time.
+func staleIssues(issues []Issue) []Issue {
+	stale := make([]Issue, 0)
+	now := time.Now()
+	for _, issue := range issues {
+		if issue.isPullRequest() {
+			continue
+		}
+		if !hasTriageLabel(&issue) || optedOut(&issue) || ignored(&issue) {
+			continue
+		}
+		if issue.CreatedAt.Add(*issueStaleFl).After(now) {
+			continue
+		}
+		if staleSince(&issue).Add(*issueStaleFl).After(now) {
+			continue
+		}
+		stale = append(stale, issue)
+	}
+	return stale
+}
+
+// hasTriageLabel returns true if given issue has any of the labels marking
+// issues to triage, or if no such labels are configured.
+func hasTriageLabel(issue *Issue) bool {
+	if *issueLabelsFl == "" {
+		return true
+	}
+	for _, name := range strings.Split(*issueLabelsFl, ",") {
+		for _, label := range issue.Labels {
+			if label.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// issuePool returns members issues are assigned to.
+func issuePool() ([]User, error) {
+	if *issuePoolFl == "" {
+		return listMembers()
+	}
+	var pool []User
+	for _, login := range strings.Split(*issuePoolFl, ",") {
+		if login != "" {
+			pool = append(pool, User{Login: login})
+		}
+	}
+	return pool, nil
+}
+
+// nextIssueMember returns member from round robin of the issue triage pool,
+// that is not the author of given issue.
+func nextIssueMember(issue *Issue) (User, error) {
+	pool, err := issuePool()
+	if err != nil {
+		return User{}, fmt.Errorf("cannot list pool: %s", err)
+	}
+	if len(pool) == 0 {
+		return User{}, errors.New("empty pool")
+	}
+	return rotate("issues", pool, func(login string) bool {
+		return login != issue.User.Login && !isBot(login)
+	})
+}
+
+// handleIssue assigns a member of the triage pool to given stale issue, or
+// reminds the assignee if the issue is already assigned.
+func handleIssue(issue Issue, now time.Time) {
+	if issue.Assignee == nil {
+		user, err := nextIssueMember(&issue)
+		if err != nil {
+			log.Printf("cannot pick user for %d: %s", issue.ID, err)
+			return
+		}
+		if err := forge.Assign(&issue, &user); err != nil {
+			log.Printf("cannot assign %q to %d: %s", user.Login, issue.ID, err)
+			return
+		}
+		comment := tr(&issue, "issue_assign_comment", user.Login)
+		if err := forge.Comment(&issue, comment); err != nil {
+			log.Printf("cannot comment on #%d issue: %s", issue.Number, err)
+		}
+		return
+	}
+
+	if !notificationsEnabled() || getState(issueKey(&issue)).quiet(now) || quietAt(now, memberLocation(issue.Assignee.Login)) {
+		return
+	}
+	if staleSince(&issue).Add(*issueOldFl).Before(now) {
+		log.Printf("Reminding %s to triage issue #%d (%s)\n", issue.Assignee.Login, issue.Number, issue.Title)
+		text := tr(&issue, "triage", issue.Assignee.Login, issue.HTMLURL, issue.Number, issue.Title)
+		if err := postSlack(text); err != nil {
+			log.Printf("cannot write slack notification: %s", err)
+		}
+	}
+}
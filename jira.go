@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jiraKeyRegex matches JIRA issue keys, for example PAY-123.
+var jiraKeyRegex = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-[0-9]+\b`)
+
+// Ticket is the JIRA issue a pull request implements.
+type Ticket struct {
+	Key      string
+	Summary  string
+	Priority string
+}
+
+// URL returns browse URL of the ticket.
+func (t *Ticket) URL() string {
+	return strings.TrimSuffix(*jiraURLFl, "/") + "/browse/" + t.Key
+}
+
+var (
+	ticketsMu    sync.Mutex
+	ticketsCache = map[string]*Ticket{}
+)
+
+// jiraEnabled returns true if JIRA integration is configured.
+func jiraEnabled() bool {
+	return *jiraURLFl != "" && *jiraTokenFl != ""
+}
+
+// jiraRequest sends request to JIRA REST API and decodes JSON response into
+// given value, unless it is nil. Basic authentication is used when user is
+// set, personal access token otherwise.
+func jiraRequest(method, path string, body interface{}, v interface{}) error {
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return fmt.Errorf("cannot encode body: %s", err)
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(*jiraURLFl, "/")+"/rest/api/2"+path, &b)
+	if err != nil {
+		return fmt.Errorf("cannot create %s request: %s", method, err)
+	}
+	if *jiraUserFl != "" {
+		req.SetBasicAuth(*jiraUserFl, *jiraTokenFl)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+*jiraTokenFl)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("cannot decode response: %s", err)
+	}
+	return nil
+}
+
+// jiraProjectKey returns the first JIRA issue key in given text belonging
+// to one of -jira-projects, or empty string. Strings like UTF-8 or SHA-256
+// look like issue keys too.
+func jiraProjectKey(text string) string {
+	projects := map[string]bool{}
+	for _, p := range strings.Split(*jiraProjectsFl, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			projects[strings.ToUpper(p)] = true
+		}
+	}
+	for _, key := range jiraKeyRegex.FindAllString(text, -1) {
+		if projects[key[:strings.LastIndex(key, "-")]] {
+			return key
+		}
+	}
+	return ""
+}
+
+// ticketKey returns key of the JIRA issue mentioned in title or head branch
+// of given pull request, or empty string.
+func ticketKey(issue *Issue) string {
+	if key := jiraProjectKey(issue.Title); key != "" {
+		return key
+	}
+	pull, err := getPull(issue)
+	if err != nil {
+		return ""
+	}
+	return jiraProjectKey(strings.ToUpper(pull.Head.Ref))
+}
+
+// ticketFor returns JIRA issue of given pull request, nil if it has none.
+// Tickets are cached for the duration of the scan.
+func ticketFor(issue *Issue) (*Ticket, error) {
+	if !jiraEnabled() {
+		return nil, nil
+	}
+	key := ticketKey(issue)
+	if key == "" {
+		return nil, nil
+	}
+	ticketsMu.Lock()
+	t, ok := ticketsCache[key]
+	ticketsMu.Unlock()
+	if ok {
+		return t, nil
+	}
+
+	var result struct {
+		Fields struct {
+			Summary  string `json:"summary"`
+			Priority *struct {
+				Name string `json:"name"`
+			} `json:"priority"`
+		} `json:"fields"`
+	}
+	if err := jiraRequest("GET", "/issue/"+key+"?fields=summary,priority", nil, &result); err != nil {
+		return nil, fmt.Errorf("cannot get %s: %s", key, err)
+	}
+	t = &Ticket{Key: key, Summary: result.Fields.Summary}
+	if result.Fields.Priority != nil {
+		t.Priority = result.Fields.Priority.Name
+	}
+
+	ticketsMu.Lock()
+	ticketsCache[key] = t
+	ticketsMu.Unlock()
+	return t, nil
+}
+
+// ticketText returns link and priority of the JIRA issue of given pull
+// request to be included in Slack messages, or empty string.
+func ticketText(issue *Issue) string {
+	t, err := ticketFor(issue)
+	if err != nil {
+		log.Printf("cannot get ticket of #%d: %s", issue.Number, err)
+		return ""
+	}
+	if t == nil {
+		return ""
+	}
+	if t.Priority == "" {
+		return fmt.Sprintf(" [<%s|%s>]", t.URL(), t.Key)
+	}
+	return fmt.Sprintf(" [<%s|%s> %s]", t.URL(), t.Key, t.Priority)
+}
+
+// commentOnTicket adds comment about given pull request to its JIRA issue
+// once the pull request is stale for -jira-comment-after. Comment is added
+// only once.
+func commentOnTicket(issue *Issue, now time.Time) {
+	if !jiraEnabled() || *jiraCommentAfterFl == 0 || staleSince(issue).Add(*jiraCommentAfterFl).After(now) {
+		return
+	}
+	key := issueKey(issue)
+	if getState(key).TicketCommented {
+		return
+	}
+	t, err := ticketFor(issue)
+	if err != nil {
+		log.Printf("cannot get ticket of #%d: %s", issue.Number, err)
+		return
+	}
+	if t == nil {
+		return
+	}
+	assignee := tr(issue, "nobody")
+	if issue.Assignee != nil {
+		assignee = issue.Assignee.Login
+	}
+	link := fmt.Sprintf("[%s|%s]", issue.Title, issue.HTMLURL)
+	body := tr(issue, "ticket_comment", link, formatAge(now.Sub(staleSince(issue))), assignee)
+	if err := jiraRequest("POST", "/issue/"+t.Key+"/comment", map[string]string{"body": body}, nil); err != nil {
+		log.Printf("cannot comment on %s: %s", t.Key, err)
+		return
+	}
+	err = updateState(key, func(s *PRState) {
+		s.TicketCommented = true
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+}
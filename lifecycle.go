@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Phase is a step of the pull request's journey through the bot.
+type Phase string
+
+const (
+	// PhaseFresh pull requests are open but not stale yet.
+	PhaseFresh Phase = "fresh"
+	// PhaseStale pull requests are stale but nobody was assigned yet.
+	PhaseStale Phase = "stale"
+	// PhaseAssigned pull requests are stale and have an assignee.
+	PhaseAssigned Phase = "assigned"
+	// PhaseReminded pull requests are past the old threshold and their
+	// assignee was reminded.
+	PhaseReminded Phase = "reminded"
+	// PhaseEscalated pull requests were escalated to team lead.
+	PhaseEscalated Phase = "escalated"
+	// PhaseResolved pull requests are open and no longer stale.
+	PhaseResolved Phase = "resolved"
+	// PhaseClosed pull requests were closed or merged.
+	PhaseClosed Phase = "closed"
+)
+
+// phaseRank orders phases of a stale pull request. Stale pull requests only
+// advance to later phases.
+var phaseRank = map[Phase]int{
+	PhaseStale:     1,
+	PhaseAssigned:  2,
+	PhaseReminded:  3,
+	PhaseEscalated: 4,
+}
+
+// Transition is a change of pull request's phase.
+type Transition struct {
+	Phase Phase     `json:"phase"`
+	At    time.Time `json:"at"`
+}
+
+// enter moves pull request to given phase, unless it already is in it.
+func (s *PRState) enter(phase Phase, now time.Time) {
+	if s.Phase == phase {
+		return
+	}
+	s.Phase = phase
+	s.Transitions = append(s.Transitions, Transition{Phase: phase, At: now})
+}
+
+// advance moves stale pull request to given phase, unless it already is in
+// the same or later phase.
+func (s *PRState) advance(phase Phase, now time.Time) {
+	if phaseRank[s.Phase] >= phaseRank[phase] {
+		return
+	}
+	s.enter(phase, now)
+}
+
+// PhaseSince returns the time pull request entered its current phase.
+func (s PRState) PhaseSince() time.Time {
+	if len(s.Transitions) == 0 {
+		return time.Time{}
+	}
+	return s.Transitions[len(s.Transitions)-1].At
+}
+
+// phaseDurations returns total time pull request spent in each phase.
+func (s PRState) phaseDurations(now time.Time) map[Phase]time.Duration {
+	durations := map[Phase]time.Duration{}
+	for i, t := range s.Transitions {
+		end := now
+		if i+1 < len(s.Transitions) {
+			end = s.Transitions[i+1].At
+		}
+		if t.Phase == PhaseClosed {
+			break
+		}
+		durations[t.Phase] += end.Sub(t.At)
+	}
+	return durations
+}
+
+// notificationDue returns true if assignee or lead can be notified again.
+func (s PRState) notificationDue(now time.Time) bool {
+	return s.NotifiedAt.IsZero() || now.Sub(s.NotifiedAt) >= *remindEveryFl
+}
+
+// trackedKey is the key of the document listing pull requests that were
+// open during the last scan.
+const trackedKey = "lifecycle/open"
+
+// trackLifecycle records phases of open pull requests, moving those that
+// became stale to the stale phase, and of pull requests closed since the
+// last scan. All changed states are written at once.
+func trackLifecycle(issues []Issue, stale []Issue, now time.Time) {
+	isStale := map[string]bool{}
+	for i := range stale {
+		isStale[issueKey(&stale[i])] = true
+	}
+
+	open := map[string]*Issue{}
+	var keys []string
+	for i := range issues {
+		if !issues[i].isPullRequest() {
+			continue
+		}
+		key := issueKey(&issues[i])
+		open[key] = &issues[i]
+		keys = append(keys, key)
+	}
+	var tracked []string
+	if _, err := loadDocument(trackedKey, &tracked); err != nil {
+		log.Printf("cannot load tracked pull requests: %s", err)
+	}
+	for _, key := range tracked {
+		if open[key] == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	changed := map[string]Phase{}
+	err := updateStates(keys, func(key string, s *PRState) bool {
+		before := s.Phase
+		switch {
+		case open[key] == nil:
+			s.enter(PhaseClosed, now)
+		case isStale[key]:
+			if phaseRank[s.Phase] == 0 {
+				s.enter(PhaseStale, now)
+			}
+		case phaseRank[s.Phase] > 0:
+			s.enter(PhaseResolved, now)
+			s.NotifiedAt = time.Time{}
+		case s.Phase == "" || s.Phase == PhaseClosed:
+			s.enter(PhaseFresh, now)
+		}
+		if s.Phase == before {
+			return false
+		}
+		changed[key] = s.Phase
+		return true
+	})
+	if err != nil {
+		log.Printf("cannot update phases: %s", err)
+		return
+	}
+	for key, phase := range changed {
+		switch phase {
+		case PhaseStale:
+			emit(PhaseStale, key, open[key], now)
+		case PhaseResolved:
+			emit(PhaseResolved, key, open[key], now)
+			cleanupResolved(key, open[key], PhaseResolved)
+		case PhaseClosed:
+			emit(PhaseClosed, key, nil, now)
+			cleanupResolved(key, nil, PhaseClosed)
+		}
+	}
+
+	tracked = tracked[:0]
+	for key := range open {
+		tracked = append(tracked, key)
+	}
+	if err := saveDocument(trackedKey, tracked); err != nil {
+		log.Printf("cannot save tracked pull requests: %s", err)
+	}
+}
+
+// setPhase advances given pull request to given phase and returns true if
+// its phase changed. Errors are logged.
+func setPhase(issue *Issue, phase Phase, now time.Time) bool {
+	key := issueKey(issue)
+	changed := false
+	err := updateState(key, func(s *PRState) {
+		before := s.Phase
+		if phaseRank[s.Phase] == 0 {
+			s.enter(phase, now)
+		} else {
+			s.advance(phase, now)
+		}
+		changed = s.Phase != before
+	})
+	if err != nil {
+		log.Printf("cannot update phase of %s: %s", key, err)
+	}
+	return changed
+}
+
+// lifecycleHandler serves phases of all open pull requests, as of the last
+// scan, with time spent in each phase, in seconds.
+func lifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	type phaseInfo struct {
+		Phase     Phase           `json:"phase"`
+		Since     time.Time       `json:"since"`
+		Durations map[Phase]int64 `json:"durations"`
+	}
+	now := time.Now()
+	result := map[string]phaseInfo{}
+	for key, s := range currentView().States {
+		info := phaseInfo{Phase: s.Phase, Since: s.PhaseSince(), Durations: map[Phase]int64{}}
+		for phase, d := range s.phaseDurations(now) {
+			info.Durations[phase] = int64(d / time.Second)
+		}
+		result[key] = info
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
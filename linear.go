@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const linearAPIURL = "https://api.linear.app/graphql"
+
+var (
+	// linearURLRegex matches links to Linear issues, for example
+	// https://linear.app/acme/issue/ENG-123/title.
+	linearURLRegex = regexp.MustCompile(`linear\.app/[^/\s]+/issue/([A-Za-z][A-Za-z0-9]+-[0-9]+)`)
+	// linearBranchRegex matches Linear identifiers in branch names as
+	// generated by Linear, for example jane/eng-123-title.
+	linearBranchRegex = regexp.MustCompile(`(?:^|/)([A-Za-z][A-Za-z0-9]+-[0-9]+)`)
+)
+
+// LinearIssue is the Linear issue a pull request implements.
+type LinearIssue struct {
+	ID            string `json:"id"`
+	Identifier    string `json:"identifier"`
+	Title         string `json:"title"`
+	PriorityLabel string `json:"priorityLabel"`
+	URL           string `json:"url"`
+}
+
+var (
+	linearMu    sync.Mutex
+	linearCache = map[string]*LinearIssue{}
+)
+
+// linearGraphQL executes given query against Linear API and decodes the
+// response data into v.
+func linearGraphQL(query string, vars map[string]interface{}, v interface{}) error {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(map[string]interface{}{
+		"query":     query,
+		"variables": vars,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	req, err := http.NewRequest("POST", linearAPIURL, &body)
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	req.Header.Set("Authorization", *linearKeyFl)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("cannot decode response: %s", err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("query failed: %s", result.Errors[0].Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(result.Data, v); err != nil {
+		return fmt.Errorf("cannot decode data: %s", err)
+	}
+	return nil
+}
+
+// linearIdentifier returns identifier of the Linear issue linked in body or
+// head branch of given pull request, or empty string.
+func linearIdentifier(issue *Issue) string {
+	if m := linearURLRegex.FindStringSubmatch(issue.Body); m != nil {
+		return strings.ToUpper(m[1])
+	}
+	pull, err := getPull(issue)
+	if err != nil {
+		return ""
+	}
+	if m := linearBranchRegex.FindStringSubmatch(pull.Head.Ref); m != nil {
+		return strings.ToUpper(m[1])
+	}
+	return ""
+}
+
+// linearIssueFor returns Linear issue of given pull request, nil if it has
+// none. Issues are cached for the duration of the scan.
+func linearIssueFor(issue *Issue) (*LinearIssue, error) {
+	if *linearKeyFl == "" {
+		return nil, nil
+	}
+	id := linearIdentifier(issue)
+	if id == "" {
+		return nil, nil
+	}
+	linearMu.Lock()
+	li, ok := linearCache[id]
+	linearMu.Unlock()
+	if ok {
+		return li, nil
+	}
+
+	var data struct {
+		Issue *LinearIssue `json:"issue"`
+	}
+	query := `query($id: String!) { issue(id: $id) { id identifier title priorityLabel url } }`
+	err := linearGraphQL(query, map[string]interface{}{"id": id}, &data)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return nil, fmt.Errorf("cannot get %s: %s", id, err)
+	}
+	// branch names can look like identifiers of issues that do not exist
+	li = data.Issue
+
+	linearMu.Lock()
+	linearCache[id] = li
+	linearMu.Unlock()
+	return li, nil
+}
+
+// linearText returns link, title and priority of the Linear issue of given
+// pull request to be included in Slack messages, or empty string.
+func linearText(issue *Issue) string {
+	li, err := linearIssueFor(issue)
+	if err != nil {
+		log.Printf("cannot get Linear issue of #%d: %s", issue.Number, err)
+		return ""
+	}
+	if li == nil {
+		return ""
+	}
+	text := fmt.Sprintf(" [<%s|%s> %s", li.URL, li.Identifier, li.Title)
+	if li.PriorityLabel != "" && li.PriorityLabel != "No priority" {
+		text += ", " + li.PriorityLabel
+	}
+	return text + "]"
+}
+
+// commentOnLinear adds comment about given pull request to its Linear issue
+// once the pull request is stale for -linear-comment-after. Comment is added
+// only once.
+func commentOnLinear(issue *Issue, now time.Time) {
+	if *linearKeyFl == "" || *linearCommentAfterFl == 0 || staleSince(issue).Add(*linearCommentAfterFl).After(now) {
+		return
+	}
+	key := issueKey(issue)
+	if getState(key).LinearCommented {
+		return
+	}
+	li, err := linearIssueFor(issue)
+	if err != nil {
+		log.Printf("cannot get Linear issue of #%d: %s", issue.Number, err)
+		return
+	}
+	if li == nil {
+		return
+	}
+	assignee := tr(issue, "nobody")
+	if issue.Assignee != nil {
+		assignee = issue.Assignee.Login
+	}
+	link := fmt.Sprintf("[%s](%s)", issue.Title, issue.HTMLURL)
+	body := tr(issue, "ticket_comment", link, formatAge(now.Sub(staleSince(issue))), assignee)
+	var data struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+		} `json:"commentCreate"`
+	}
+	query := `mutation($issueId: String!, $body: String!) { commentCreate(input: {issueId: $issueId, body: $body}) { success } }`
+	err = linearGraphQL(query, map[string]interface{}{"issueId": li.ID, "body": body}, &data)
+	if err != nil {
+		log.Printf("cannot comment on %s: %s", li.Identifier, err)
+		return
+	}
+	err = updateState(key, func(s *PRState) {
+		s.LinearCommented = true
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"time"
+)
+
+// linkNotice is the author notice recorded once the author was asked to
+// link an issue.
+const linkNotice = "link-missing"
+
+// missingLink returns true if -require-link is set and neither title nor
+// description of given pull request reference an issue or ticket.
+func missingLink(issue *Issue) bool {
+	if *requireLinkFl == "" || !featureEnabled(issue, "require_link", true) {
+		return false
+	}
+	re, err := regexp.Compile(*requireLinkFl)
+	if err != nil {
+		log.Printf("invalid -require-link: %s", err)
+		return false
+	}
+	return !re.MatchString(issue.Title) && !re.MatchString(issue.Body)
+}
+
+// askForLink asks author of given pull request to link an issue or ticket,
+// once per pull request, unless link comments are switched off.
+func askForLink(issue *Issue, now time.Time) {
+	if !commentOn(issue, "link") {
+		return
+	}
+	key := issueKey(issue)
+	for _, n := range getState(key).AuthorNotices {
+		if n == linkNotice {
+			return
+		}
+	}
+	id := "link_missing"
+	if *requireLinkHoldFl {
+		id = "link_missing_hold"
+	}
+	if err := forge.Comment(issue, tr(issue, id, issue.User.Login)); err != nil {
+		log.Printf("cannot comment on #%d pull request: %s", issue.Number, err)
+		return
+	}
+	recordAction(AuditEntry{Time: now, Key: key, Login: issue.User.Login, Action: "commented", Detail: id})
+	err := updateState(key, func(s *PRState) {
+		s.AuthorNotices = append(s.AuthorNotices, linkNotice)
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locker is a lock shared between bot instances, so that only one of them
+// runs a scan at a time.
+type Locker interface {
+	// Acquire takes the lock for the lock TTL, or extends it if it is
+	// already held by this instance. It returns false if the lock is held
+	// by another instance.
+	Acquire() (bool, error)
+	// Release gives up the lock if it is held by this instance.
+	Release() error
+}
+
+// locker is the configured run lock, nil when instances are not coordinated.
+var locker Locker
+
+// lockHolder returns identity of this instance.
+func lockHolder() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// newLocker returns locker for given "redis://..." or
+// "kubernetes://[namespace]" URL.
+func newLocker(rawurl string) (Locker, error) {
+	switch {
+	case strings.HasPrefix(rawurl, "redis://"):
+		c, err := newRedisClient(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		return &redisLock{client: c, key: *lockNameFl, holder: lockHolder()}, nil
+	case strings.HasPrefix(rawurl, "kubernetes://"):
+		return newLeaseLock(strings.TrimPrefix(rawurl, "kubernetes://"))
+	}
+	return nil, fmt.Errorf("unsupported lock %q", rawurl)
+}
+
+// withLock runs fn while holding the run lock. The lock is extended in the
+// background for as long as fn runs. If the lock is held by another
+// instance, fn is not called.
+func withLock(fn func() error) error {
+	if locker == nil {
+		return fn()
+	}
+	ok, err := locker.Acquire()
+	if err != nil {
+		return fmt.Errorf("cannot acquire lock: %s", err)
+	}
+	if !ok {
+		log.Printf("lock %q held by another instance, skipping scan", *lockNameFl)
+		return nil
+	}
+	defer func() {
+		if err := locker.Release(); err != nil {
+			log.Printf("cannot release lock: %s", err)
+		}
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		tick := time.NewTicker(*lockTTLFl / 3)
+		defer tick.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-tick.C:
+				if ok, err := locker.Acquire(); err != nil {
+					log.Printf("cannot extend lock: %s", err)
+				} else if !ok {
+					log.Printf("lock %q lost to another instance", *lockNameFl)
+				}
+			}
+		}
+	}()
+	return fn()
+}
+
+// redisLock is a lock kept as a Redis key with expiration.
+type redisLock struct {
+	client *redisClient
+	key    string
+	holder string
+}
+
+const redisAcquireScript = `
+local v = redis.call('GET', KEYS[1])
+if v == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if v then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`
+
+const redisReleaseScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+func (l *redisLock) Acquire() (bool, error) {
+	ttl := strconv.FormatInt(int64(*lockTTLFl/time.Millisecond), 10)
+	reply, err := l.client.Do("EVAL", redisAcquireScript, "1", l.key, l.holder, ttl)
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
+
+func (l *redisLock) Release() error {
+	_, err := l.client.Do("EVAL", redisReleaseScript, "1", l.key, l.holder)
+	return err
+}
+
+// serviceAccountDir is where Kubernetes mounts the pod's service account
+// credentials.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// leaseLock is a lock kept as a Kubernetes coordination Lease. The bot must
+// run in the cluster, with a service account allowed to get, create and
+// update leases.
+type leaseLock struct {
+	url    string
+	token  string
+	client *http.Client
+	holder string
+}
+
+type lease struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		HolderIdentity       string `json:"holderIdentity"`
+		LeaseDurationSeconds int    `json:"leaseDurationSeconds"`
+		AcquireTime          string `json:"acquireTime,omitempty"`
+		RenewTime            string `json:"renewTime,omitempty"`
+	} `json:"spec"`
+}
+
+// leaseTimeFormat is the Kubernetes MicroTime format.
+const leaseTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+func newLeaseLock(namespace string) (*leaseLock, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in Kubernetes cluster")
+	}
+	if namespace == "" {
+		b, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("cannot read namespace: %s", err)
+		}
+		namespace = strings.TrimSpace(string(b))
+	}
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read service account token: %s", err)
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cluster CA: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid cluster CA")
+	}
+	return &leaseLock{
+		url: fmt.Sprintf("https://%s:%s/apis/coordination.k8s.io/v1/namespaces/%s/leases",
+			host, port, url.PathEscape(namespace)),
+		token: strings.TrimSpace(string(token)),
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+		holder: lockHolder(),
+	}, nil
+}
+
+func (l *leaseLock) do(method, u string, in interface{}) (*lease, int, error) {
+	var body []byte
+	if in != nil {
+		body, _ = json.Marshal(in)
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot create %s request: %s", method, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+l.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusNotFound, http.StatusConflict:
+		return nil, resp.StatusCode, nil
+	default:
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, resp.StatusCode, fmt.Errorf("unexpected response: %d, %s", resp.StatusCode, b)
+	}
+	var out lease
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("cannot decode response: %s", err)
+	}
+	return &out, resp.StatusCode, nil
+}
+
+// Acquire creates the lease, or takes it over when it is held by this
+// instance or expired. Concurrent updates are rejected by Kubernetes based
+// on the resource version.
+func (l *leaseLock) Acquire() (bool, error) {
+	now := time.Now()
+	current, status, err := l.do("GET", l.url+"/"+url.PathEscape(*lockNameFl), nil)
+	if err != nil {
+		return false, err
+	}
+
+	var next lease
+	if status == http.StatusNotFound {
+		next.APIVersion = "coordination.k8s.io/v1"
+		next.Kind = "Lease"
+		next.Metadata.Name = *lockNameFl
+	} else {
+		next = *current
+		if next.Spec.HolderIdentity != l.holder && next.Spec.HolderIdentity != "" {
+			renewed, err := time.Parse(leaseTimeFormat, next.Spec.RenewTime)
+			expires := renewed.Add(time.Duration(next.Spec.LeaseDurationSeconds) * time.Second)
+			if err == nil && now.Before(expires) {
+				return false, nil
+			}
+		}
+	}
+	if next.Spec.HolderIdentity != l.holder {
+		next.Spec.AcquireTime = now.Format(leaseTimeFormat)
+	}
+	next.Spec.HolderIdentity = l.holder
+	next.Spec.LeaseDurationSeconds = int(*lockTTLFl / time.Second)
+	next.Spec.RenewTime = now.Format(leaseTimeFormat)
+
+	if status == http.StatusNotFound {
+		_, status, err = l.do("POST", l.url, &next)
+	} else {
+		_, status, err = l.do("PUT", l.url+"/"+url.PathEscape(*lockNameFl), &next)
+	}
+	if err != nil {
+		return false, err
+	}
+	// conflict means another instance was faster
+	return status != http.StatusConflict, nil
+}
+
+// Release clears the lease holder, so that other instances do not have to
+// wait for the lease to expire.
+func (l *leaseLock) Release() error {
+	current, status, err := l.do("GET", l.url+"/"+url.PathEscape(*lockNameFl), nil)
+	if err != nil || status == http.StatusNotFound || current.Spec.HolderIdentity != l.holder {
+		return err
+	}
+	current.Spec.HolderIdentity = ""
+	current.Spec.RenewTime = ""
+	_, _, err = l.do("PUT", l.url+"/"+url.PathEscape(*lockNameFl), current)
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+// fakeLocker is a Locker held by another instance unless free is set.
+type fakeLocker struct {
+	free     bool
+	held     bool
+	released bool
+}
+
+func (l *fakeLocker) Acquire() (bool, error) {
+	l.held = l.free
+	return l.free, nil
+}
+
+func (l *fakeLocker) Release() error {
+	l.released = l.held
+	l.held = false
+	return nil
+}
+
+func TestWithLock(t *testing.T) {
+	defer func(l Locker) { locker = l }(locker)
+
+	tests := []struct {
+		free bool
+		want bool
+	}{
+		{free: true, want: true},
+		// another instance is scanning
+		{free: false, want: false},
+	}
+	for _, tt := range tests {
+		l := &fakeLocker{free: tt.free}
+		locker = l
+		ran := false
+		err := withLock(func() error {
+			ran = true
+			if !l.held {
+				t.Errorf("free %v: scan ran without the lock", tt.free)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("free %v: withLock() error = %v", tt.free, err)
+		}
+		if ran != tt.want {
+			t.Errorf("free %v: scan ran = %v, want %v", tt.free, ran, tt.want)
+		}
+		if l.held || l.released != tt.want {
+			t.Errorf("free %v: lock held %v and released %v after the scan", tt.free, l.held, l.released)
+		}
+	}
+}
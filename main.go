@@ -3,46 +3,222 @@ package main
 import (
 	"bytes"
 	"container/ring"
-	"crypto/rand"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math/big"
 	"net/http"
 	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	ghAPIFl    = flag.String("github-api", "https://api.github.com", "Github API url")
-	ghUserFl   = flag.String("user", "", "Github user name")
-	ghPassFl   = flag.String("pass", "", "Github password")
-	ghAuthKey  = flag.String("auth-key", "", "Github auth key")
-	ghOrgFl    = flag.String("organization", "optiopay", "Organization name as known on github")
-	ghTeamFl   = flag.String("team-id", "1070941", "The ID of the team that should get PRs assigned")
-	slackURLFl = flag.String("slack-url", "", "Slack Incomming WebHooks API URL")
+	ghAPIFl         = flag.String("github-api", "https://api.github.com", "Github API url")
+	ghUserFl        = flag.String("user", "", "Github user name")
+	ghPassFl        = flag.String("pass", "", "Github password")
+	ghAuthKey       = flag.String("auth-key", "", "Github auth key")
+	ghOrgFl         = flag.String("organization", "optiopay", "Organization name as known on github")
+	ownerTypeFl     = flag.String("owner-type", "org", "Type of the -organization account, org or user for personal accounts")
+	reposFl         = flag.String("repos", "", "Comma separated repositories of the account to scan instead of all its repositories")
+	collaboratorsFl = flag.String("collaborators", "", "Comma separated logins pull requests of a personal account are assigned to")
+	ghTeamFl        = flag.String("team-id", "1070941", "The ID of the team that should get PRs assigned")
+	slackURLFl      = flag.String("slack-url", "", "Slack Incomming WebHooks API URL")
+
+	fetcherFl     = flag.String("fetcher", "rest", "How open pull requests are fetched from github, one of: rest, graphql")
+	providerFl    = flag.String("provider", "github", "Code hosting provider, one of: github, gitlab, gitea, bitbucket")
+	gitlabURLFl   = flag.String("gitlab-url", "https://gitlab.com", "GitLab base url")
+	gitlabTokenFl = flag.String("gitlab-token", "", "GitLab access token")
+	giteaURLFl    = flag.String("gitea-url", "", "Gitea or Forgejo base url")
+	giteaTokenFl  = flag.String("gitea-token", "", "Gitea or Forgejo access token")
+
+	bitbucketTokenFl = flag.String("bitbucket-token", "", "Bitbucket Cloud workspace access token")
+
+	appIDFl           = flag.String("app-id", "", "Github App ID, authenticates as the App installation instead of the user")
+	appInstallationFl = flag.String("app-installation-id", "", "Github App installation ID")
+	appKeyFl          = flag.String("app-private-key", "", "Path to PEM encoded github App private key")
+
+	slackTokenFl        = flag.String("slack-token", "", "Slack bot token, enables posting interactive messages using Slack Web API instead of the webhook")
+	slackChannelFl      = flag.String("slack-channel", "", "Slack channel messages are posted to when using Slack Web API")
+	mattermostURLFl     = flag.String("mattermost-url", "", "Mattermost incoming webhook URL, or server URL when -mattermost-token is set, reminders are posted to")
+	mattermostTokenFl   = flag.String("mattermost-token", "", "Mattermost bot access token, enables posting using Mattermost REST API instead of the webhook")
+	mattermostChannelFl = flag.String("mattermost-channel", "", "Mattermost channel, name for the webhook or ID for the REST API, reminders are posted to")
+	telegramTokenFl     = flag.String("telegram-token", "", "Telegram bot token, enables posting reminders to Telegram chats")
+	telegramChatsFl     = flag.String("telegram-chats", "", "Comma separated IDs of Telegram chats reminders are posted to")
+	googleChatWebhookFl = flag.String("google-chat-webhook-url", "", "Google Chat space webhook URL reminders are posted to, alongside or instead of Slack")
+	discordWebhookFl    = flag.String("discord-webhook-url", "", "Discord webhook URL reminders are posted to, alongside or instead of Slack")
+	stateFileFl         = flag.String("state-file", "", "Path to JSON file the state is kept in")
+	stateStoreFl        = flag.String("state-store", "", "URL of store the state is kept in instead of state file, redis://[:password@]host:port[/db], postgres://..., bolt:///path or sqlite:///path, all but redis need builds with their tags")
+	snoozeFl            = flag.Duration("snooze", time.Hour*48, "Time reminders are suppressed for when snoozed from Slack")
+	ackReactionsFl      = flag.String("ack-reactions", "eyes,+1", "Comma separated reactions of the assignee on the assignment comment that acknowledge working on pull request, empty disables reading reactions")
+	ackGraceFl          = flag.Duration("ack-grace", time.Hour*24, "Time reminders are suppressed for after the assignee acknowledged working on pull request")
+	slackIntervalFl     = flag.Duration("slack-interval", time.Second, "Minimum time between Slack messages")
+	quietHoursFl        = flag.String("quiet-hours", "", "Time range no reminders are sent in, for example 18:00-09:00")
+	quietDaysFl         = flag.String("quiet-days", "", "Comma separated week days no reminders are sent on, for example Sat,Sun")
+	timezoneFl          = flag.String("timezone", "", "Timezone quiet hours are evaluated in, for example Europe/Berlin, local timezone by default")
+	holidayRegionFl     = flag.String("holiday-region", "", "Region whose public holidays do not count towards staleness, one of the regions configured in the holidays section of the configuration file")
+	remindAtFl          = flag.String("remind-at", "", "Local time of the assignee reminders are sent at once a day, for example 09:00, empty means on every scan")
+	batchRemindersFl    = flag.Bool("batch-reminders", false, "Send all reminders of a scan as a single Slack message, grouped by assignee")
 
 	staleTimeFl = flag.Duration("stale", time.Hour*24, "Time after which person is assigned to pull request")
 	oldTimeFl   = flag.Duration("old", time.Hour*24*3, "Time after which pull request is notified on slack to work on pull request")
 
+	includeIssuesFl = flag.Bool("include-issues", false, "Also assign and remind about stale issues that need triage")
+	issueStaleFl    = flag.Duration("issue-stale", time.Hour*24*7, "Time after which person is assigned to triage issue")
+	issueOldFl      = flag.Duration("issue-old", time.Hour*24*14, "Time after which issue is notified on slack to be triaged")
+	issueLabelsFl   = flag.String("issue-labels", "needs-triage", "Comma separated labels of issues that need triage, empty means all issues")
+	issuePoolFl     = flag.String("issue-pool", "", "Comma separated logins issues are assigned to, team members by default")
+
+	projectNumberFl       = flag.Int("project-number", 0, "Number of the organization project (v2) stale pull requests are moved on, 0 disables the board integration")
+	projectFieldFl        = flag.String("project-field", "Status", "Name of the project single select field representing columns")
+	projectStaleColumnFl  = flag.String("project-stale-column", "Needs review", "Project column stale pull requests are moved to")
+	projectActiveColumnFl = flag.String("project-active-column", "In progress", "Project column pull requests are moved back to when no longer stale, empty to keep them")
+
+	criticalTimeFl    = flag.Duration("critical", 0, "Time after which reminders about pull request are sent with critical severity, 0 disables critical reminders except for -page-labels")
+	criticalMentionFl = flag.String("critical-mention", "here", "Slack mention added to critical reminders, one of: here, channel, none")
+	escalateTimeFl    = flag.Duration("escalate", 0, "Time after which team lead is notified on slack about pull request, 0 disables escalation")
+	escalateInsteadFl = flag.Bool("escalate-instead", false, "Do not remind the assignee when pull request is escalated to the team lead")
+	remindEveryFl     = flag.Duration("remind-every", 0, "Minimum time between reminders about the same pull request, 0 means on every scan")
+
+	listenFl              = flag.String("listen", "", "Run as a daemon serving HTTP on given address, for example :8080")
+	secretRefreshFl       = flag.Duration("secret-refresh", time.Hour, "Time after which secrets given as references are read again in daemon mode, 0 disables refreshing")
+	shutdownTimeoutFl     = flag.Duration("shutdown-timeout", 30*time.Second, "Time given to pull requests and requests being handled to finish on SIGINT or SIGTERM before exiting")
+	workersFl             = flag.Int("workers", 8, "Number of stale pull requests and issues handled at the same time")
+	intervalFl            = flag.Duration("interval", 0, "Time between scans in daemon mode, 0 means scans are only triggered on demand")
+	slackSigningSecretFl  = flag.String("slack-signing-secret", "", "Slack app signing secret, used to verify slash commands")
+	githubWebhookSecretFl = flag.String("github-webhook-secret", "", "Secret of the github webhook delivering events to /github/webhook")
+	skipReviewedFl        = flag.Bool("skip-reviewed", false, "Do not assign or remind reviewers of pull requests reviewed since their last commit, remind their authors instead")
+	authorRemindEveryFl   = flag.Duration("author-remind-every", 0, "Time between Slack reminders to authors of stale pull requests that have changes requested or no reviewers, 0 disables author reminders")
+	authorRemindAfterFl   = flag.Duration("author-remind-after", 24*time.Hour, "Time a pull request must be stale for before its author is reminded")
+	rerequestReviewFl     = flag.Bool("rerequest-review", false, "Request review again from members the bot made responsible for a pull request when it gets new commits after they requested changes")
+	rerequestNotifyFl     = flag.Bool("rerequest-notify", false, "Notify reviewers on Slack when their review is requested again")
+	requireLinkFl         = flag.String("require-link", "", "Regular expression stale pull requests must match in their title or description to reference an issue or ticket, for example [A-Z]+-[0-9]+|#[0-9]+, authors are asked to add the link")
+	requireLinkHoldFl     = flag.Bool("require-link-hold", false, "Do not assign reviewers to pull requests missing the link required by -require-link until it is added")
+	localeFl              = flag.String("locale", "en", "Locale of comments and notifications, one of the bundled en and de, or a locale with messages in the configuration file")
+	commentFl             = flag.String("comment", "assign=on,remind=off,escalate=off", "Actions announced with a pull request comment, on or off for all actions, or comma separated action=on|off pairs of assign, remind and escalate")
+	notifyFl              = flag.String("notify", "assign=off,remind=on,escalate=on", "Actions announced with a chat notification, on or off for all actions, or comma separated action=on|off pairs of assign, remind and escalate")
+	notifyAuthorsFl       = flag.Bool("notify-authors", false, "Notify authors on Slack when their pull request is ready to merge or fails CI, requires the github webhook")
+	lockFl                = flag.String("lock", "", "Lock coordinating scans of multiple instances, redis://[:password@]host:port[/db] or kubernetes://[namespace] for a Lease")
+	lockNameFl            = flag.String("lock-name", "github-stale-pr-bot", "Name of the Redis key or Kubernetes Lease used as the lock")
+	lockTTLFl             = flag.Duration("lock-ttl", time.Minute*5, "Time the lock is held for before it expires if not extended")
+	publicDashboardFl     = flag.Bool("public-dashboard", false, "Serve the dashboard, /lifecycle and /metrics without the API token")
+	apiTokenFl            = flag.String("api-token", "", "Bearer token required by the REST API, empty disables the API")
+
+	eventsFl           = flag.String("events", "", "Comma separated URLs events about decisions are published to as CloudEvents, nats://[user:pass@]host:port/<subject> or kafka+http(s)://<rest proxy>/<topic>")
+	webhookURLFl       = flag.String("webhook-url", "", "Comma separated URLs events about decisions are posted to as JSON")
+	webhookSecretFl    = flag.String("webhook-secret", "", "Secret outbound webhook payloads are signed with")
+	execFl             = flag.String("exec", "", "Command run with sh for every event about decisions, receiving the event as JSON on stdin")
+	execTimeoutFl      = flag.Duration("exec-timeout", 30*time.Second, "Time after which the -exec command is killed")
+	exportFl           = flag.String("export", "", "Destination snapshots of open pull requests are written to after each scan, bigquery://<project>/<dataset>/<table>, postgres://.../<database>?table=<table> or file:///<path>")
+	publishFl          = flag.String("publish", "", "Destination JSON and Markdown reports of stale pull requests are published to after each scan, gist://<id>, s3://<bucket>/<prefix> or gs://<bucket>/<prefix>")
+	publishRetentionFl = flag.Duration("publish-retention", 0, "Time published reports are kept for, 0 keeps them forever")
+
+	jiraURLFl          = flag.String("jira-url", "", "JIRA base url, for example https://example.atlassian.net, empty disables JIRA integration")
+	jiraUserFl         = flag.String("jira-user", "", "JIRA user email, used with API token on JIRA Cloud, empty means the token is a personal access token")
+	jiraTokenFl        = flag.String("jira-token", "", "JIRA API token or personal access token")
+	jiraProjectsFl     = flag.String("jira-projects", "", "Comma separated keys of JIRA projects issue keys are looked for, for example PAY,OPS, required with -jira-url")
+	jiraCommentAfterFl = flag.Duration("jira-comment-after", 0, "Time after which the JIRA issue of a stale pull request is commented on, 0 disables commenting")
+
+	linearKeyFl          = flag.String("linear-api-key", "", "Linear API key, empty disables Linear integration")
+	linearCommentAfterFl = flag.Duration("linear-comment-after", 0, "Time after which the Linear issue of a stale pull request is commented on, 0 disables commenting")
+
+	pagerDutyKeyFl     = flag.String("pagerduty-routing-key", "", "PagerDuty Events API v2 routing key critical pull requests are paged to")
+	opsgenieKeyFl      = flag.String("opsgenie-api-key", "", "Opsgenie API key critical pull requests are paged to")
+	opsgenieScheduleFl = flag.String("opsgenie-schedule", "", "Opsgenie on-call schedule alerts are routed to")
+	pageLabelsFl       = flag.String("page-labels", "hotfix,security", "Comma separated labels of critical pull requests that are paged")
+	pageAfterFl        = flag.Duration("page-after", time.Hour*2, "Time after which on-call reviewers are paged about stale critical pull requests")
+	pageInsteadFl      = flag.Bool("page-instead", false, "Do not remind on Slack about paged pull requests")
+
+	otlpEndpointFl    = flag.String("otlp-endpoint", "", "OTLP HTTP endpoint scan traces are exported to, for example http://localhost:4318, empty disables tracing")
+	otlpHeadersFl     = flag.String("otlp-headers", "", "Comma separated key=value headers sent with exported traces")
+	otlpServiceFl     = flag.String("otlp-service-name", "github-stale-pr-bot", "Service name traces are reported under")
+	managerChannelsFl = flag.String("manager-channels", "", "Comma separated Slack channel or user IDs a summary of batched reminders for managers is sent to, requires -batch-reminders and -slack-token")
+	managerLongestFl  = flag.Int("manager-longest", 5, "Number of longest waiting pull requests listed in the manager summary")
+	summaryChannelFl  = flag.String("summary-channel", "", "Slack channel a summary of each scan that assigned, reminded or failed anything is posted to, requires -slack-token")
+	statsdFl          = flag.String("statsd", "", "Address, host:port, of StatsD server metrics of each scan are sent to, empty disables metrics")
+	statsdPrefixFl    = flag.String("statsd-prefix", "stale_pr_bot.", "Prefix of StatsD metric names")
+	statsdTagsFl      = flag.String("statsd-tags", "", "Comma separated DogStatsD tags, for example env:prod,team:backend, sent with all metrics")
+
+	proxyFl           = flag.String("proxy", "", "HTTP(S) proxy url of all outbound requests, proxy environment variables are used by default")
+	caBundleFl        = flag.String("ca-bundle", "", "Path to PEM encoded CA certificates trusted in addition to the system ones")
+	clientCertFl      = flag.String("client-cert", "", "Path to PEM encoded TLS client certificate")
+	clientKeyFl       = flag.String("client-key", "", "Path to PEM encoded TLS client certificate key")
+	connectTimeoutFl  = flag.Duration("connect-timeout", time.Second*10, "Timeout of establishing outbound connections, including TLS handshake")
+	pageConcurrencyFl = flag.Int("page-concurrency", 4, "Number of pages of github list responses fetched at the same time once the number of pages is known, 1 fetches them one by one")
+	maxAPICallsFl     = flag.Int("max-api-calls-per-run", 0, "Maximum number of github API calls of a scan, no further pull requests are handled once it is reached, 0 means no limit")
+	apiQuotasFl       = flag.String("api-quotas", "", "Comma separated maximum numbers of github API calls of a scan per feature, for example reviews=500,files=200, features are core, graphql, reviews, checks and files")
+	apiReserveFl      = flag.Int("api-reserve", 100, "Number of github API calls kept for core and GraphQL calls, fetching reviews, checks and files stops when only this many remain in -max-api-calls-per-run or the rate limit")
+	httpTimeoutFl     = flag.Duration("http-timeout", time.Minute, "Timeout of outbound HTTP requests, including reading the response, 0 means no timeout")
+	keepAliveFl       = flag.Duration("keep-alive", time.Second*30, "Keep-alive period of outbound connections, negative disables keep-alives")
+	tlsMinVersionFl   = flag.String("tls-min-version", "1.2", "Minimum TLS version of outbound connections, one of: 1.0, 1.1, 1.2, 1.3")
+	recordFl          = flag.String("record", "", "Directory all outbound HTTP exchanges are saved to, with secrets redacted")
+	replayFl          = flag.String("replay", "", "Directory with exchanges saved with -record, responses to outbound requests are read from it instead of the network")
+	debugHTTPFl       = flag.Bool("debug-http", false, "Log method, URL, status and rate limit headers of all outbound requests, with secrets redacted")
+	debugHTTPBodyFl   = flag.Int("debug-http-body", 0, "Number of bytes of request and response bodies logged with -debug-http, 0 disables logging of bodies")
+
+	versionFl             = flag.Bool("version", false, "Print version and exit")
+	updateCheckFl         = flag.Bool("update-check", true, "Log if a newer release is available on startup")
+	startupCheckFl        = flag.Bool("startup-check", true, "Validate configuration, credentials and connectivity before running")
+	slackProbeFl          = flag.Bool("slack-probe", false, "Verify -slack-url in startup and readiness checks by sending it an empty message, by default only the URL is checked")
+	configFl              = flag.String("config", "", "Path to JSON configuration file")
+	policyScriptFl        = flag.String("policy-script", "", "Path to policy script, a Go template returning decisions about each pull request")
+	fullScanIntervalFl    = flag.Duration("full-scan-interval", 0, "Time between scans listing all open pull requests in daemon mode, scans in between list only those changed since the previous scan, 0 lists all of them on every scan")
+	configReloadFl        = flag.Duration("config-reload", 30*time.Second, "Time between checks of the configuration file for changes in daemon mode, 0 disables reloading")
+	strategyFl            = flag.String("strategy", "round-robin", "Assignment strategy, one of: round-robin, expertise, blame")
+	staleFromFl           = flag.String("stale-from", "created", "Measure staleness from pull request creation or last activity, one of: created, activity")
+	ignoreActivityFromFl  = flag.String("ignore-activity-from", "dependabot[bot]", "Comma separated logins whose activity does not reset staleness")
+	ignoreActivityTypesFl = flag.String("ignore-activity-types", "Bot", "Comma separated user types whose activity does not reset staleness")
+	checkRunFl            = flag.Bool("check-run", false, "Publish review-freshness check run on stale pull requests, requires github App authentication")
+	staleLabelFl          = flag.String("stale-label", "", "Label added to stale pull requests and removed once they are resolved, empty disables labeling")
+	cleanupRemindersFl    = flag.Bool("cleanup-reminders", false, "Strike through Slack reminders and remove their buttons once the pull request is resolved, requires -slack-token")
+	staleBranchesFl       = flag.Duration("stale-branches", 0, "Time without push after which branches without open pull request are reported, 0 disables the report")
+	branchReportEveryFl   = flag.Duration("branch-report-every", time.Hour*24*7, "Time between stale branch reports")
+	historyRetentionFl    = flag.Duration("history-retention", time.Hour*24*7*8, "Time snapshots of scans are kept for trends in reports, 0 disables the history")
+	reportRepoFl          = flag.String("report-repo", "", "Repository of the organization a weekly tracking issue listing stale pull requests is kept in, empty disables the report")
+	deleteBranchesAfterFl = flag.Duration("delete-branches-after", 0, "Time without push after which stale branches can be deleted by their owner from the Slack report, 0 disables deleting")
+	sizeLabelFl           = flag.Bool("size-label", false, "Label stale pull requests with their size class, for example size/M")
+	excludeMembersFl      = flag.String("exclude-members", "", "Comma separated logins that are never assigned, in addition to BLACKLIST environment variable")
+	reassignUnavailableFl = flag.Bool("reassign-unavailable", false, "Reassign stale pull requests whose assignee is on vacation or no longer a team member")
+	membersTTLFl          = flag.Duration("members-ttl", time.Hour, "Time after which team members are fetched again, 0 caches them forever")
+	extraMembersFl        = flag.String("extra-members", "", "Comma separated logins that are assigned pull requests in addition to team members")
+	skipArchivedFl        = flag.Bool("skip-archived", true, "Skip pull requests of archived repositories")
+	skipForksFl           = flag.Bool("skip-forks", false, "Skip pull requests of forked repositories")
+	topicsFl              = flag.String("topics", "", "Comma separated repository topics, only pull requests of repositories with at least one of them are processed")
+	visibilityFl          = flag.String("visibility", "all", "Visibility of repositories whose pull requests are processed, one of: all, public, private")
+	baseBranchesFl        = flag.String("base-branches", "", "Comma separated glob patterns of base branches, only pull requests targeting matching branches are processed, for example main,develop")
+	requiredApprovalsFl   = flag.Bool("required-approvals", false, "Read required approvals from branch protection and rulesets, do not remind about pull requests that have enough approvals and mention missing approvals in reminders")
+	auditRetentionFl      = flag.Duration("audit-retention", time.Hour*24*90, "Time assignments are kept in the audit log for, 0 keeps them forever")
+	fairnessWindowFl      = flag.Duration("fairness-window", time.Hour*24*30, "Time window of the fairness report")
+	simulateWindowFl      = flag.Duration("simulate-window", time.Hour*24*30, "Time window of pull requests replayed by the simulate command")
+	simulateStaleFl       = flag.String("simulate-stale", "12h,24h,48h", "Comma separated stale thresholds compared by the simulate command")
+	simulateStrategiesFl  = flag.String("simulate-strategies", "round-robin", "Comma separated assignment strategies compared by the simulate command")
+	assignAsFl            = flag.String("assign-as", "both", "How the picked member is made responsible for a pull request, one of: assignee, reviewer (review request only), both")
+	optOutFl              = flag.String("opt-out", "<!-- stale-bot: ignore -->,[no-bot]", "Comma separated markers that exempt a pull request from all bot actions when found in its title or description")
+	reviewersPerPRFl      = flag.Int("reviewers-per-pr", 1, "Number of distinct members made responsible for a stale pull request, members other than the first one are only requested to review")
+	rampUpFl              = flag.Duration("ramp-up", 0, "Time after their start date members are assigned at reduced capacity, 0 disables ramp-up")
+	rampUpCapacityFl      = flag.Float64("ramp-up-capacity", 0.3, "Capacity factor of members ramping up")
+	rampUpPairFl          = flag.Bool("ramp-up-pair", false, "Request review from an experienced member too when assigning a member ramping up")
+	shadowPoolFl          = flag.String("shadow-pool", "", "Comma separated logins of members shadowing reviews to learn, one of them is requested to review along with the assigned member")
+	teammatesFl           = flag.String("teammates", "any", "How members of the pull request author's team are picked, one of: any, avoid, prefer")
+	maxAssignmentsFl      = flag.Int("max-assignments-per-user", 0, "Maximum number of open pull requests a member can be assigned to or requested to review before being skipped, 0 means no limit")
+
 	repoRegex = regexp.MustCompile("https://github.com/(.+?)/(.+?)/.*")
-	linkRegex = regexp.MustCompile(`.*<(.+?)>; rel="next".*`)
 )
 
-var botNames = map[string]struct{}{
-	"optiopay-backend-helper": struct{}{},
-	"optiopay-helper":         struct{}{},
-}
-
 type User struct {
 	ID    int64  `json:"id"`
 	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
+type Label struct {
+	Name string `json:"name"`
 }
 
 type Issue struct {
@@ -50,13 +226,28 @@ type Issue struct {
 	Number      int64        `json:"number"`
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
+	ClosedAt    *time.Time   `json:"closed_at"`
 	User        *User        `json:"user"`
 	Assignee    *User        `json:"assignee"`
 	URL         string       `json:"url"`
 	HTMLURL     string       `json:"html_url"`
 	Title       string       `json:"title"`
+	Body        string       `json:"body"`
 	State       string       `json:"state"`
+	Labels      []Label      `json:"labels"`
 	PullRequest *PullRequest `json:"pull_request"`
+	Repository  *Repository  `json:"repository"`
+
+	// AuthorAssociation is the relation of the author to the repository,
+	// for example MEMBER or FIRST_TIME_CONTRIBUTOR.
+	AuthorAssociation string `json:"author_association"`
+
+	// Repo is the repository name, set by providers that cannot derive
+	// it from the URL.
+	Repo string `json:"-"`
+	// Reviewers are additional members requested to review the pull
+	// request during this scan.
+	Reviewers []User `json:"-"`
 }
 
 type PullRequest struct {
@@ -64,6 +255,9 @@ type PullRequest struct {
 }
 
 func (i *Issue) GetRepository() (string, error) {
+	if i.Repo != "" {
+		return i.Repo, nil
+	}
 	list := repoRegex.FindStringSubmatch(i.HTMLURL)
 	if len(list) != 3 {
 		return "", errors.New("URL has unexpected format")
@@ -75,111 +269,127 @@ func (i *Issue) isPullRequest() bool {
 	return i.PullRequest != nil
 }
 
-// stalePullRequests return all pull requests that were created more than
-// staleTime ago.
-func stalePullRequests(staleTime time.Duration) (stale []Issue, err error) {
-	stale = make([]Issue, 0)
-
-	url := fmt.Sprintf("%s/orgs/%s/issues?filter=all&state=open", *ghAPIFl, *ghOrgFl)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("cannot create GET request: %s", err)
+// openIssues return all open issues and pull requests of the organization,
+// the personal account or repositories given by -repos. With
+// -full-scan-interval only those changed since the previous scan are
+// fetched.
+func openIssues() ([]Issue, error) {
+	if *fullScanIntervalFl > 0 {
+		return incrementalIssues(time.Now())
 	}
-	addAuthentication(req)
+	return fetchIssues("state=open")
+}
 
-	loadIssues := func(r *http.Request) ([]Issue, string, error) {
-		resp, err := http.DefaultClient.Do(r)
+// fetchIssues returns issues and pull requests of the organization, the
+// personal account or repositories given by -repos, matching given query
+// parameters of the issues API.
+func fetchIssues(query string) ([]Issue, error) {
+	if userAccount() || *reposFl != "" {
+		issues, err := repositoryIssues(query)
 		if err != nil {
-			return nil, "", fmt.Errorf("cannot fetch response: %s", err)
-		}
-		defer resp.Body.Close()
-		var issues []Issue
-		if err := json.NewDecoder(resp.Body).Decode(&issues); err != nil {
-			return nil, "", fmt.Errorf("cannot decode response: %s", err)
+			return nil, err
 		}
-		list := linkRegex.FindStringSubmatch(resp.Header.Get("Link"))
-		nextURL := ""
-		if len(list) == 2 {
-			nextURL = list[1]
-		}
-		return issues, nextURL, nil
+		return filterRepositories(issues), nil
 	}
-
+	url := fmt.Sprintf("%s/orgs/%s/issues?filter=all&%s&per_page=100", *ghAPIFl, *ghOrgFl, query)
 	var issues []Issue
-	loadMore := true
-	for loadMore == true {
-		newIssues, nextURL, loadErr := loadIssues(req)
-		if loadErr != nil {
-			panic("Failed to load: " + loadErr.Error())
-		}
-		issues = append(issues, newIssues...)
-		if nextURL == "" {
-			loadMore = false
-		} else {
-			req, err = http.NewRequest("GET", nextURL, nil)
-			if err != nil {
-				return nil, fmt.Errorf("cannot create GET request: %s", err)
-			}
-			addAuthentication(req)
-		}
+	if err := githubGetAll(url, &issues); err != nil {
+		return nil, fmt.Errorf("failed to load: %s", err)
 	}
+	return filterRepositories(issues), nil
+}
+
+// stalePullRequests return all pull requests from given issues that were
+// created, or active, longer ago than stale time of their policy.
+func stalePullRequests(issues []Issue) []Issue {
+	stale := make([]Issue, 0)
 
 	now := time.Now()
 	for _, issue := range issues {
 		if !issue.isPullRequest() {
 			continue
 		}
-		if issue.Assignee.Login == issue.User.Login {
+		if issue.Assignee != nil && issue.Assignee.Login == issue.User.Login {
 			// Dev's assign PR's to themselves to signal it is not ready for
 			// being merged.
 			continue
 		}
+		// skip early, staleness of pull requests costs API calls
+		if optedOut(&issue) || ignored(&issue) {
+			continue
+		}
+		staleTime := policyFor(&issue).Stale
+		// last activity cannot be older than creation, so check the cheap
+		// condition first
 		if issue.CreatedAt.Add(staleTime).After(now) {
 			continue
 		}
+		if staleSince(&issue).Add(staleTime).After(now) {
+			continue
+		}
+		if !processBranch(&issue) {
+			continue
+		}
+		if runScript(&issue).Skip {
+			continue
+		}
 
 		stale = append(stale, issue)
 	}
-	return stale, nil
+	return stale
+}
+
+// memberList is a cached list of team members.
+type memberList struct {
+	users     []User
+	fetchedAt time.Time
 }
 
 var (
-	membersMu    sync.Mutex
-	membersCache []User
+	membersMu sync.Mutex
+	// membersCache maps tenant scopes to their members.
+	membersCache = map[string]*memberList{}
 )
 
 // blacklistedMemebers returns a list of login names of members which should
 // not be included in pull requests.
 func blacklistedMembers() map[string]bool {
 	ret := map[string]bool{}
-	buf := os.Getenv("BLACKLIST")
+	buf := os.Getenv("BLACKLIST") + "," + *excludeMembersFl
 	for _, login := range strings.Split(buf, ",") {
-		ret[login] = true
+		ret[strings.TrimSpace(login)] = true
 	}
 	return ret
 }
 
+// extraMembers returns logins that are assigned pull requests in addition to
+// team members.
+func extraMembers() []string {
+	var logins []string
+	for _, login := range strings.Split(*extraMembersFl, ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			logins = append(logins, login)
+		}
+	}
+	return logins
+}
+
 // listMembers return all members of a given team (configured by flag).
-// Globally cached.
+// Cached per tenant, the cache is refreshed after -members-ttl.
 func listMembers() (members []User, err error) {
 	membersMu.Lock()
 	defer membersMu.Unlock()
 
-	if membersCache == nil {
-		url := fmt.Sprintf("%s/teams/%s/members", *ghAPIFl, *ghTeamFl)
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("cannot create GET request: %s", err)
-		}
-		addAuthentication(req)
-		resp, err := http.DefaultClient.Do(req)
+	cached := membersCache[tenantScope()]
+	if cached == nil || (*membersTTLFl > 0 && time.Since(cached.fetchedAt) > *membersTTLFl) {
+		members, err := forge.Members()
 		if err != nil {
-			return nil, fmt.Errorf("cannot fetch response: %s", err)
-		}
-		defer resp.Body.Close()
-		var members []User
-		if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
-			return nil, fmt.Errorf("cannot decode response: %s", err)
+			if cached != nil {
+				log.Printf("cannot refresh members, using cached: %s", err)
+				cached.fetchedAt = time.Now()
+				return cached.users, nil
+			}
+			return nil, err
 		}
 		isBlacklisted := blacklistedMembers()
 		for i := len(members) - 1; i >= 0; i-- {
@@ -187,69 +397,257 @@ func listMembers() (members []User, err error) {
 				members = append(members[:i], members[(i+1):]...)
 			}
 		}
-		membersCache = members
+		for _, login := range extraMembers() {
+			found := false
+			for _, m := range members {
+				if m.Login == login {
+					found = true
+				}
+			}
+			if !found && !isBlacklisted[login] {
+				members = append(members, User{Login: login})
+			}
+		}
+		rememberMembers(members)
+		cached = &memberList{users: members, fetchedAt: time.Now()}
+		membersCache[tenantScope()] = cached
 	}
 
-	return membersCache, nil
+	return cached.users, nil
 }
 
-var (
-	membersRMu  sync.Mutex
-	membersRing *ring.Ring
-)
+// githubTeamMembers return all members of the team configured by flag.
+func githubTeamMembers() ([]User, error) {
+	url := fmt.Sprintf("%s/teams/%s/members?per_page=100", *ghAPIFl, *ghTeamFl)
+	var members []User
+	if err := githubGetAll(url, &members); err != nil {
+		if e, ok := err.(*responseError); ok && e.Status == http.StatusNotFound {
+			return nil, fmt.Errorf("team %s not found", *ghTeamFl)
+		}
+		return nil, err
+	}
+	return members, nil
+}
 
-// nextRandomMember returns random member, selected from round robin of all
-// members.
-//
-// Because assigning randomly may not always produce best result, use round
-// robin of random order members to get assignment user.
-func nextRandomMember() (User, error) {
-	membersRMu.Lock()
-	defer membersRMu.Unlock()
-
-	if membersRing == nil {
-		members, err := listMembers()
-		if err != nil {
-			return User{}, fmt.Errorf("cannot list members: %s", err)
+// ringMatches returns true if given ring holds exactly given members.
+func ringMatches(r *ring.Ring, members []User) bool {
+	if r.Len() != len(members) {
+		return false
+	}
+	logins := map[string]bool{}
+	for _, m := range members {
+		logins[m.Login] = true
+	}
+	match := true
+	r.Do(func(v interface{}) {
+		if !logins[v.(*User).Login] {
+			match = false
 		}
-		membersRing = ring.New(len(members))
-		for key := range members {
-			membersRing.Value = &members[key]
-			membersRing = membersRing.Next()
+	})
+	return match
+}
+
+// updateRing returns ring of given members, keeping the rotation order of
+// members that were in the old ring. Slots of members that left are taken
+// by the members following them, and new members join at the end of the
+// rotation.
+func updateRing(r *ring.Ring, members []User) *ring.Ring {
+	current := map[string]*User{}
+	for i := range members {
+		current[members[i].Login] = &members[i]
+	}
+	var order []*User
+	for i := 0; i < r.Len(); i++ {
+		if m, ok := current[r.Value.(*User).Login]; ok {
+			order = append(order, m)
+			delete(current, m.Login)
+		}
+		r = r.Next()
+	}
+	for i := range members {
+		if _, ok := current[members[i].Login]; ok {
+			order = append(order, &members[i])
 		}
+	}
+	log.Printf("team membership changed, rotation has %d members", len(order))
 
-		// skip random number of users, to not always start from the same place
-		skip, _ := rand.Int(rand.Reader, big.NewInt(int64(len(members))))
-		for i := int64(0); i < skip.Int64(); i++ {
-			membersRing = membersRing.Next()
+	updated := ring.New(len(order))
+	for _, m := range order {
+		updated.Value = m
+		updated = updated.Next()
+	}
+	return updated
+}
+
+// pickMember returns the next member from the round robin of the pull
+// request's rotation that can handle it. Author of the pull request, bots and members that
+// reached the assignments limit are skipped. Default reviewers of the
+// repository, on providers that have them, are picked first. Members of
+// the author's team are skipped or picked first, depending on the
+// teammates mode.
+func pickMember(issue *Issue) (User, error) {
+	members, err := listMembers()
+	if err != nil {
+		return User{}, fmt.Errorf("cannot list members: %s", err)
+	}
+	if defaults := defaultReviewers(issue); defaults != nil {
+		user, err := rotate(rotationFor(issue), members, func(login string) bool {
+			return defaults[login] && canReview(issue, login)
+		})
+		if err == nil {
+			return user, nil
+		}
+	}
+	team := config.Members[issue.User.Login].Team
+	mode := teammatesMode(issue)
+	if team != "" && mode == "prefer" {
+		user, err := rotate(rotationFor(issue), members, func(login string) bool {
+			return config.Members[login].Team == team && canReview(issue, login)
+		})
+		if err == nil {
+			return user, nil
 		}
 	}
+	return rotate(rotationFor(issue), members, func(login string) bool {
+		if team != "" && mode == "avoid" && config.Members[login].Team == team {
+			return false
+		}
+		return canReview(issue, login)
+	})
+}
 
-	member := membersRing.Value.(*User)
-	membersRing = membersRing.Next()
-	return *member, nil
+// canReview returns true if user with given login can be assigned to given
+// pull request. If so, assignment slot of that user is reserved.
+func canReview(issue *Issue, login string) bool {
+	if login == issue.User.Login {
+		return false
+	}
+	if issue.Assignee != nil && login == issue.Assignee.Login {
+		return false
+	}
+	for _, r := range issue.Reviewers {
+		if login == r.Login {
+			return false
+		}
+	}
+	if isBot(login) {
+		return false
+	}
+	if onVacation(login, time.Now()) {
+		log.Printf("%s is on vacation, skipping", login)
+		return false
+	}
+	if onHoliday(login, time.Now()) {
+		log.Printf("%s has a public holiday, skipping", login)
+		return false
+	}
+	if !scriptAllows(issue, login) {
+		log.Printf("%s excluded by policy script, skipping", login)
+		return false
+	}
+	ok, err := reserveAssignment(login)
+	if err != nil {
+		log.Printf("cannot count assignments of %q: %s", login, err)
+		return false
+	}
+	if !ok {
+		log.Printf("%s reached the limit of %d assignments, skipping", login, assignmentLimit(login))
+		return false
+	}
+	return true
+}
+
+// pickReviewer returns member that should be assigned to given pull request,
+// using configured assignment strategy. Round robin is used when strategy
+// cannot find anybody.
+func pickReviewer(issue *Issue) (User, error) {
+	if p := externalPolicy(issue); p != nil && len(p.Triagers) > 0 {
+		return nextTriager(issue)
+	}
+	if p := issuePolicy(issue); p != nil && len(p.Pool) > 0 {
+		return nextFromPool("pool/"+strings.Join(p.Repos, ","), p.Pool, issue)
+	}
+	switch *strategyFl {
+	case "expertise":
+		user, ok, err := expertReviewer(issue)
+		if err != nil {
+			log.Printf("cannot find expert for #%d: %s", issue.Number, err)
+		} else if ok {
+			return user, nil
+		}
+	case "blame":
+		user, ok, err := blameReviewer(issue)
+		if err != nil {
+			log.Printf("cannot find recent committer for #%d: %s", issue.Number, err)
+		} else if ok {
+			return user, nil
+		}
+	}
+	return pickMember(issue)
 }
 
 func writeGithubComment(issue *Issue, comment string) error {
+	_, err := postGithubComment(issue, comment)
+	return err
+}
+
+// postGithubComment writes comment on given issue and returns its ID.
+func postGithubComment(issue *Issue, comment string) (int64, error) {
 	var body bytes.Buffer
 	err := json.NewEncoder(&body).Encode(map[string]interface{}{
 		"body":        comment,
 		"in_reply-to": issue.Number,
 	})
 	if err != nil {
-		return fmt.Errorf("cannot JSON encode body: %s", err)
+		return 0, fmt.Errorf("cannot JSON encode body: %s", err)
 	}
 	repo, repoErr := issue.GetRepository()
 	if repoErr != nil {
-		return fmt.Errorf("Cannot extract repo name from URL: %s", repoErr)
+		return 0, fmt.Errorf("Cannot extract repo name from URL: %s", repoErr)
 	}
 	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", *ghAPIFl, *ghOrgFl, repo, issue.Number)
 	req, err := http.NewRequest("POST", url, &body)
+	if err != nil {
+		return 0, fmt.Errorf("cannot create POST request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return 0, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return 0, fmt.Errorf("cannot decode response: %s", err)
+	}
+	return created.ID, nil
+}
+
+// requestGithubReview requests review of given pull request from given user.
+func requestGithubReview(issue *Issue, user *User) error {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var body bytes.Buffer
+	err = json.NewEncoder(&body).Encode(map[string]interface{}{
+		"reviewers": []string{user.Login},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/requested_reviewers", *ghAPIFl, *ghOrgFl, repo, issue.Number)
+	req, err := http.NewRequest("POST", url, &body)
 	if err != nil {
 		return fmt.Errorf("cannot create POST request: %s", err)
 	}
 	addAuthentication(req)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("cannot do request: %s", err)
 	}
@@ -257,28 +655,158 @@ func writeGithubComment(issue *Issue, comment string) error {
 	if resp.StatusCode != http.StatusCreated {
 		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
 	}
+	log.Printf("review of #%d pull request of %q requested from %s", issue.Number, repo, user.Login)
 	return nil
 }
 
+// requestReviewers requests review of given pull request from given number
+// of additional members, distinct from the assignee and the author.
+func requestReviewers(issue *Issue, n int, now time.Time) {
+	for i := 0; i < n; i++ {
+		user, err := pickReviewer(issue)
+		if err != nil {
+			log.Printf("cannot pick additional reviewer for %d: %s", issue.ID, err)
+			return
+		}
+		if !addReviewer(issue, user, now) {
+			return
+		}
+	}
+}
+
+// addReviewer requests review of given pull request from given member and
+// returns true if the request succeeded.
+func addReviewer(issue *Issue, user User, now time.Time) bool {
+	if err := forge.RequestReview(issue, &user); err != nil {
+		log.Printf("cannot request review of #%d from %q: %s", issue.Number, user.Login, err)
+		releaseAssignment(user.Login)
+		return false
+	}
+	issue.Reviewers = append(issue.Reviewers, user)
+	recordAssignment(issueKey(issue), user.Login, "requested", now)
+	return true
+}
+
+// requestedReviewer returns the first user whose review of given pull
+// request is pending, nil if there is none.
+func requestedReviewer(issue *Issue) *User {
+	pull, err := getPull(issue)
+	if err != nil {
+		if err != errNotSupported {
+			log.Printf("cannot get pull request #%d: %s", issue.Number, err)
+		}
+		return nil
+	}
+	if len(pull.RequestedReviewers) == 0 {
+		return nil
+	}
+	return &pull.RequestedReviewers[0]
+}
+
 func remindOnSlack(issue *Issue) error {
-	if *slackURLFl == "" {
+	if !notificationsEnabled() {
 		return errors.New("not supported")
 	}
+	if *batchRemindersFl {
+		repo, _ := issue.GetRepository()
+		line := fmt.Sprintf("<%s|%s#%d> %s", issue.HTMLURL, repo, issue.Number, issue.Title)
+		if *requiredApprovalsFl {
+			if missing, err := missingApprovals(issue); err == nil && missing > 0 {
+				line += " " + approvalsText(issue, missing)
+			}
+		}
+		line += ticketText(issue) + linearText(issue)
+		policy := policyFor(issue)
+		if sla := policy.describe(issue); sla != "" {
+			line += fmt.Sprintf(" [%s]", sla)
+		}
+		critical := policy.severity(issue, staleSince(issue), time.Now()) == SeverityCritical
+		if critical {
+			line = ":rotating_light: " + line
+		}
+		addToBatch(issue, line, critical)
+		return nil
+	}
 	log.Printf("Reminding %s to work on PR #%d (%s)\n", issue.Assignee.Login, issue.Number, issue.Title)
+	policy := policyFor(issue)
+	severity := policy.severity(issue, staleSince(issue), time.Now())
 	// github login doesn't have to be slack login as well...
+	text := tr(issue, "remind", issue.Assignee.Login, issue.HTMLURL, issue.Number, issue.Title)
+	if severity == SeverityStale {
+		text = tr(issue, "remind_stale", issue.Assignee.Login, issue.HTMLURL, issue.Number, issue.Title)
+	}
+	if *requiredApprovalsFl {
+		if missing, err := missingApprovals(issue); err != nil && err != errNoRequiredApprovals {
+			log.Printf("cannot check approvals of #%d: %s", issue.Number, err)
+		} else if missing > 0 {
+			text = tr(issue, "remind_approvals", issue.Assignee.Login, issue.HTMLURL, issue.Number, issue.Title, approvalsText(issue, missing))
+		}
+	}
+	if size, err := sizeOf(issue); err != nil {
+		log.Printf("cannot get size of #%d: %s", issue.Number, err)
+	} else {
+		text += " " + tr(issue, "size", size)
+	}
+	text += ticketText(issue) + linearText(issue)
+	if sla := policy.describe(issue); sla != "" {
+		text += fmt.Sprintf(" [%s]", sla)
+	}
+	msg := slackMessage{Text: text, Color: severityColors[severity], Issue: issue}
+	if severity == SeverityCritical {
+		msg.Text = ":rotating_light: " + text
+		if *criticalMentionFl != "none" {
+			msg.Text = fmt.Sprintf("<!%s> %s", *criticalMentionFl, msg.Text)
+		}
+	}
+	if *slackTokenFl != "" {
+		msg.Blocks = reminderBlocks(issue, msg.Text)
+		msg.Key = issueKey(issue)
+	}
+	return notify(msg)
+}
+
+// severityColors maps severities to Slack attachment colors.
+var severityColors = map[Severity]string{
+	SeverityOld:      "warning",
+	SeverityCritical: "danger",
+}
+
+// slackEnabled returns true if Slack notifications are configured.
+func slackEnabled() bool {
+	return *slackURLFl != "" || *slackTokenFl != ""
+}
+
+// postSlack sends given text to Slack, using the Web API if configured, or
+// the incoming webhook.
+func postSlack(text string) error {
+	return notify(slackMessage{Text: text})
+}
+
+// sendSlackWebhook sends given message to the Slack incoming webhook.
+// Blocks are not sent, because they contain interactive elements.
+func sendSlackWebhook(m slackMessage) error {
 	msg := map[string]interface{}{
 		"username":   "github-pr",
 		"icon_emoji": ":octocat:",
-		"text": fmt.Sprintf(`@%s, please work on <%s|Pull Request #%d> (%s)`,
-			issue.Assignee.Login, issue.HTMLURL, issue.Number, issue.Title),
+		"text":       m.Text,
+	}
+	if m.Color != "" {
+		msg["text"] = ""
+		msg["attachments"] = []interface{}{
+			map[string]interface{}{"color": m.Color, "text": m.Text, "fallback": m.Text},
+		}
 	}
 	b, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("cannot JSON encode data: %s", err)
 	}
-	resp, err := http.Post(*slackURLFl, "application/json", bytes.NewBuffer(b))
+	resp, err := httpClient.Post(*slackURLFl, "application/json", bytes.NewBuffer(b))
 	if err != nil {
-		return fmt.Errorf("cannot POST data: %s", err)
+		return &transientError{err: fmt.Errorf("cannot POST data: %s", err)}
+	}
+	defer resp.Body.Close()
+	if err := checkSlackResponse(resp); err != nil {
+		return err
 	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
@@ -288,7 +816,66 @@ func remindOnSlack(issue *Issue) error {
 }
 
 // assignUser assign user to given pull request issue
-func assignUser(issue *Issue, user *User) error {
+func assignUser(issue *Issue, user *User, shadow *User, now time.Time) error {
+	if *assignAsFl != "reviewer" {
+		if err := forge.Assign(issue, user); err != nil {
+			return err
+		}
+	}
+	if *assignAsFl != "assignee" {
+		err := forge.RequestReview(issue, user)
+		if err != nil && *assignAsFl == "reviewer" {
+			return err
+		}
+		if err != nil {
+			log.Printf("cannot request review of #%d from %q: %s", issue.Number, user.Login, err)
+		}
+	}
+	comment := tr(issue, "assign_comment", user.Login)
+	if welcome, err := welcomeComment(issue, user.Login); err != nil {
+		log.Printf("cannot create welcome comment for #%d: %s", issue.Number, err)
+	} else if welcome != "" {
+		comment = welcome
+	}
+	if nudge := descriptionNudge(issue); nudge != "" {
+		comment += "\n\n" + nudge
+	}
+	if shadow != nil {
+		if err := forge.RequestReview(issue, shadow); err != nil {
+			log.Printf("cannot request review of #%d from %q: %s", issue.Number, shadow.Login, err)
+			releaseAssignment(shadow.Login)
+		} else {
+			issue.Reviewers = append(issue.Reviewers, *shadow)
+			recordAssignment(issueKey(issue), shadow.Login, "shadow", now)
+			comment += "\n\n" + tr(issue, "shadow_comment", shadow.Login)
+		}
+	}
+	if !commentOn(issue, "assign") {
+		return nil
+	}
+	if githubOnly() != nil {
+		if err := forge.Comment(issue, comment); err != nil {
+			log.Printf("cannot comment on #%d pull request: %s", issue.Number, err)
+		}
+		return nil
+	}
+	id, err := postGithubComment(issue, comment)
+	if err != nil {
+		log.Printf("cannot comment on #%d pull request: %s", issue.Number, err)
+		return nil
+	}
+	// remembered, so that reactions of the assignee can be read
+	err = updateState(issueKey(issue), func(s *PRState) {
+		s.AssignmentComment = id
+	})
+	if err != nil {
+		log.Printf("cannot save state: %s", err)
+	}
+	return nil
+}
+
+// setAssignee replaces assignee of given issue
+func setAssignee(issue *Issue, user *User) error {
 	repo, repoErr := issue.GetRepository()
 	if repoErr != nil {
 		return fmt.Errorf("Cannot extract repo name from URL: %s", repoErr)
@@ -307,7 +894,7 @@ func assignUser(issue *Issue, user *User) error {
 		return fmt.Errorf("cannot create PATCH request: %s", err)
 	}
 	addAuthentication(req)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("cannot do request: %s", err)
 	}
@@ -316,15 +903,42 @@ func assignUser(issue *Issue, user *User) error {
 		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
 	}
 	log.Printf("%s assigned to #%d issue of %q", user.Login, issue.Number, repo)
-	comment := fmt.Sprintf("Pull request seem to be stale, assigning @%s as the responsible developer.", user.Login)
-	if err := writeGithubComment(issue, comment); err != nil {
-		log.Printf("cannot comment on %s's #%d pull request: %s", repo, issue.Number, err)
-	}
 	return nil
 }
 
+// fetchIssue returns issue with given number from given repository.
+func fetchIssue(repo string, number int64) (*Issue, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", *ghAPIFl, *ghOrgFl, repo, number)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch response: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var issue Issue
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %s", err)
+	}
+	return &issue, nil
+}
+
 // addAuthentication adds to given HTTP request authentication credentials
 func addAuthentication(req *http.Request) {
+	if appEnabled() {
+		token, err := installationToken()
+		if err == nil {
+			req.Header.Set("Authorization", fmt.Sprintf("token %s", token))
+			return
+		}
+		log.Printf("cannot get installation token: %s", err)
+	}
 	if *ghAuthKey != "" {
 		req.Header.Set("Authorization", fmt.Sprintf("token %s", *ghAuthKey))
 	} else {
@@ -332,51 +946,523 @@ func addAuthentication(req *http.Request) {
 	}
 }
 
-func main() {
-	flag.Parse()
+var scanMu sync.Mutex
+
+// runScan fetches all open pull requests and handles the stale ones. Only
+// one scan runs at a time, none once shutdown started.
+func runScan() error {
+	scanMu.Lock()
+	defer scanMu.Unlock()
+	if shuttingDown() {
+		return errShuttingDown
+	}
+
+	return measuredScan()
+}
+
+// measuredScan runs traced scan and reports its metrics.
+func measuredScan() error {
+	resetStats()
+	budget.reset()
+	defer budget.stop()
+	start := time.Now()
+	err := traceScan(func() error {
+		return withLock(scan)
+	})
+	reportScan(time.Since(start), err)
+	return err
+}
 
-	stale, err := stalePullRequests(*staleTimeFl)
+// scan assigns and reminds about all stale pull requests.
+func scan() error {
+	resetCaches()
+	loadIgnoreList()
+	if *listenFl != "" && slackEnabled() {
+		deliverPending()
+	}
+	list := startSpan(currentScanSpan(), "list issues", spanKindInternal)
+	issues, err := forge.OpenIssues()
+	list.setAttr("issues", len(issues))
+	list.finish(err)
 	if err != nil {
-		log.Fatalf("cannot fetch stale pull requests: %s", err)
+		return fmt.Errorf("cannot fetch stale pull requests: %s", err)
+	}
+	issues = autoMerge(issues, time.Now())
+	loadAssignments(issues)
+	stale := stalePullRequests(issues)
+	for i := range issues {
+		if issues[i].isPullRequest() {
+			countStat(&stats.Scanned)
+		}
+	}
+	atomic.StoreInt64(&stats.Stale, int64(len(stale)))
+
+	if *projectNumberFl > 0 {
+		if err := syncProjectBoard(stale); err != nil {
+			log.Printf("cannot update project board: %s", err)
+			countStat(&stats.Errors)
+		}
 	}
 
 	now := time.Now()
+	trackLifecycle(issues, stale, now)
+	rerequestReviews(issues, now)
+	trackSLOs(issues, now)
 
-	var wg sync.WaitGroup
-	for _, pr := range stale {
-		wg.Add(1)
-
-		go func(issue Issue) {
-			defer wg.Done()
-
-			if issue.Assignee == nil {
-				// pick random user, but do not assing owner to handle his own pull
-				// request
-				var user User
-				for {
-					user, err = nextRandomMember()
-					_, ok := botNames[user.Login]
-					if user.ID != issue.User.ID && !ok {
-						break
-					}
-				}
+	var jobs []func()
+	for i := range stale {
+		issue := &stale[i]
+		jobs = append(jobs, func() {
+			s := startSpan(currentScanSpan(), "handle pull request", spanKindInternal)
+			s.setAttr("pull_request", issueKey(issue))
+			handlePullRequest(issue, now)
+			remindAuthor(issue, now)
+			s.finish(nil)
+		})
+	}
+	if *includeIssuesFl {
+		for _, issue := range staleIssues(issues) {
+			issue := issue
+			jobs = append(jobs, func() {
+				s := startSpan(currentScanSpan(), "handle issue", spanKindInternal)
+				s.setAttr("issue", issueKey(&issue))
+				handleIssue(issue, now)
+				s.finish(nil)
+			})
+		}
+	}
+	runWorkers(jobs)
 
-				if err != nil {
-					log.Fatalf("cannot pick user: %s", err)
-				}
-				if err := assignUser(&issue, &user); err != nil {
-					log.Printf("cannot assign %q to %d: %s", user.Login, issue.ID, err)
-				}
-				return
+	if *batchRemindersFl {
+		if err := flushBatch(); err != nil {
+			log.Printf("cannot write slack notification: %s", err)
+			countStat(&stats.Errors)
+		}
+	}
+	if *listenFl != "" {
+		recordView(stale, now)
+	}
+	if *historyRetentionFl > 0 {
+		recordSnapshot(stale, now)
+	}
+	if shuttingDown() {
+		log.Print("shutting down, skipping reports")
+		return nil
+	}
+	if *staleBranchesFl > 0 && githubOnly() == nil {
+		if err := reportStaleBranches(now); err != nil {
+			log.Printf("cannot report stale branches: %s", err)
+			countStat(&stats.Errors)
+		}
+	}
+	if *reportRepoFl != "" && githubOnly() == nil {
+		if err := updateReportIssue(stale, now); err != nil {
+			log.Printf("cannot update report issue: %s", err)
+			countStat(&stats.Errors)
+		}
+	}
+	if exporter != nil {
+		if err := exportSnapshot(issues, now); err != nil {
+			log.Printf("cannot export snapshot: %s", err)
+			countStat(&stats.Errors)
+		}
+	}
+	if reportDestination != nil {
+		if err := publishReport(stale, now); err != nil {
+			log.Printf("cannot publish report: %s", err)
+			countStat(&stats.Errors)
+		}
+	}
+	return nil
+}
+
+// resetCaches drops all data cached during the previous scan.
+func resetCaches() {
+	pullsMu.Lock()
+	pullsCache = map[int64]*Pull{}
+	pullsMu.Unlock()
+
+	activityMu.Lock()
+	activityCache = map[int64]time.Time{}
+	activityMu.Unlock()
+
+	requiredMu.Lock()
+	requiredCache = map[string]int{}
+	requiredMu.Unlock()
+
+	ticketsMu.Lock()
+	ticketsCache = map[string]*Ticket{}
+	ticketsMu.Unlock()
+
+	linearMu.Lock()
+	linearCache = map[string]*LinearIssue{}
+	linearMu.Unlock()
+
+	assignmentsMu.Lock()
+	assignedPRs = map[string]map[int64]bool{}
+	requestsLoaded = map[string]bool{}
+	allRequestsLoaded = false
+	assignmentsMu.Unlock()
+
+	ciMu.Lock()
+	ciCache = map[int64]string{}
+	ciMu.Unlock()
+
+	reviewsMu.Lock()
+	reviewsCache = map[int64]map[string]string{}
+	reviewsMu.Unlock()
+
+	reviewedMu.Lock()
+	reviewedCache = map[int64][]string{}
+	reviewedMu.Unlock()
+
+	scriptMu.Lock()
+	scriptCache = map[int64]scriptDecision{}
+	scriptMu.Unlock()
+}
+
+// handlePullRequest assigns a member to given stale pull request, or reminds
+// the assignee if the pull request is already assigned.
+func handlePullRequest(issue *Issue, now time.Time) {
+	if shuttingDown() {
+		// the pull request may have waited for a free worker since the check
+		return
+	}
+	if featureEnabled(issue, "size_label", *sizeLabelFl) {
+		if err := applySizeLabel(issue); err != nil {
+			log.Printf("cannot label size of %d: %s", issue.ID, err)
+		}
+	}
+
+	if *staleLabelFl != "" && !hasLabel(issue, *staleLabelFl) {
+		if err := addLabel(issue, *staleLabelFl); err != nil {
+			log.Printf("cannot label #%d as stale: %s", issue.Number, err)
+		}
+	}
+
+	if issue.Assignee == nil && *assignAsFl == "reviewer" {
+		// without assignees, the requested reviewer is responsible
+		issue.Assignee = requestedReviewer(issue)
+	}
+
+	key := issueKey(issue)
+
+	if reviewers := awaitingAuthor(issue); len(reviewers) > 0 {
+		// the ball is in the author's court, nobody else needs to act
+		remindAuthorOfReview(issue, reviewers, now)
+		return
+	}
+
+	if missingLink(issue) {
+		askForLink(issue, now)
+		if *requireLinkHoldFl && issue.Assignee == nil {
+			return
+		}
+	}
+
+	if issue.Assignee == nil {
+		if !featureEnabled(issue, "assign", true) {
+			return
+		}
+		// pick random user, but do not assing owner to handle his own pull
+		// request
+		user, err := pickReviewer(issue)
+		if err != nil {
+			log.Printf("cannot pick user for %d: %s", issue.ID, err)
+			countStat(&stats.Errors)
+			return
+		}
+		shadow := pickShadow(issue, user.Login)
+		if err := assignUser(issue, &user, shadow, now); err != nil {
+			log.Printf("cannot assign %q to %d: %s", user.Login, issue.ID, err)
+			countStat(&stats.Errors)
+			releaseAssignment(user.Login)
+			if shadow != nil {
+				releaseAssignment(shadow.Login)
+			}
+			return
+		}
+		issue.Assignee = &user
+		recordAssignment(key, user.Login, "assigned", now)
+		countStat(&stats.Assigned)
+		if notifyOn(issue, "assign") {
+			notifyAssignment(issue, user.Login)
+		}
+		requestReviewers(issue, *reviewersPerPRFl-1, now)
+		if *rampUpPairFl && rampingUp(user.Login, now) {
+			pairWithExperienced(issue, now)
+		}
+		if setPhase(issue, PhaseAssigned, now) {
+			emit(PhaseAssigned, key, issue, now)
+		}
+		if featureEnabled(issue, "check_run", *checkRunFl) {
+			if err := postFreshnessCheck(issue, user.Login, now); err != nil {
+				log.Printf("cannot publish check of #%d: %s", issue.Number, err)
 			}
+		}
+		return
+	}
+	setPhase(issue, PhaseAssigned, now)
+	commentOnTicket(issue, now)
+	commentOnLinear(issue, now)
 
-			if *slackURLFl != "" && issue.CreatedAt.Add(*oldTimeFl).Before(now) {
-				if err := remindOnSlack(&issue); err != nil {
-					log.Printf("cannot write slack notification: %s", err)
-				}
+	if *reassignUnavailableFl && reassignUnavailable(issue, now) {
+		recordAssignment(key, issue.Assignee.Login, "reassigned", now)
+		countStat(&stats.Assigned)
+		emit(PhaseAssigned, key, issue, now)
+		if featureEnabled(issue, "check_run", *checkRunFl) {
+			if err := postFreshnessCheck(issue, issue.Assignee.Login, now); err != nil {
+				log.Printf("cannot publish check of #%d: %s", issue.Number, err)
 			}
+		}
+		return
+	}
+
+	if featureEnabled(issue, "check_run", *checkRunFl) {
+		if err := postFreshnessCheck(issue, issue.Assignee.Login, now); err != nil {
+			log.Printf("cannot publish check of #%d: %s", issue.Number, err)
+		}
+	}
+
+	if pageOnCall(issue, now) && *pageInsteadFl {
+		return
+	}
 
-		}(pr)
+	if !announced(issue, "remind") && !announced(issue, "escalate") {
+		return
+	}
+	checkAckReaction(issue, key)
+	st := getState(key)
+	loc := memberLocation(issue.Assignee.Login)
+	if st.quiet(now) || !st.notificationDue(now) || quietAt(now, loc) || !scheduledDue(st.NotifiedAt, now, loc) {
+		return
+	}
+	if *requiredApprovalsFl {
+		missing, err := missingApprovals(issue)
+		switch {
+		case err == errNoRequiredApprovals:
+		case err != nil:
+			log.Printf("cannot check approvals of #%d: %s", issue.Number, err)
+		case missing == 0:
+			// approved pull requests wait for the author, not the reviewer
+			return
+		}
+	}
+	policy := policyFor(issue)
+	since := staleSince(issue)
+	notified := false
+	escalated := false
+	// leads hear about pull requests only after their assignee was
+	// reminded, unless nobody is reminded about them
+	reminding := featureEnabled(issue, "remind", true) && announced(issue, "remind")
+	remindedBefore := st.Phase == PhaseReminded || st.Phase == PhaseEscalated || !reminding
+	if policy.Escalate > 0 && since.Add(policy.Escalate).Before(now) && remindedBefore && featureEnabled(issue, "escalate", true) && announced(issue, "escalate") {
+		if err := escalateToLead(issue); err != nil {
+			log.Printf("cannot escalate #%d: %s", issue.Number, err)
+			countStat(&stats.Errors)
+		} else {
+			escalated = true
+			recordAction(AuditEntry{Time: now, Key: key, Login: issue.Assignee.Login, Action: "escalated"})
+			notified = true
+			emit(PhaseEscalated, key, issue, now)
+		}
+	}
+	if !(escalated && *escalateInsteadFl) && since.Add(policy.Old).Before(now) && reminding {
+		if err := remind(issue); err != nil {
+			log.Printf("cannot remind about #%d: %s", issue.Number, err)
+			countStat(&stats.Errors)
+		} else {
+			setPhase(issue, PhaseReminded, now)
+			emit(PhaseReminded, key, issue, now)
+			countStat(&stats.Reminded)
+			recordAction(AuditEntry{Time: now, Key: key, Login: issue.Assignee.Login, Action: "reminded"})
+			// batched reminders are recorded once the batch is sent
+			notified = notified || !(*batchRemindersFl && notifyOn(issue, "remind"))
+		}
+	}
+	if escalated {
+		setPhase(issue, PhaseEscalated, now)
+	}
+	if notified {
+		err := updateState(key, func(s *PRState) {
+			s.NotifiedAt = now
+		})
+		if err != nil {
+			log.Printf("cannot update state of %s: %s", key, err)
+		}
+	}
+}
+
+func main() {
+	documentEnv()
+	flag.Parse()
+	if *versionFl {
+		fmt.Println(versionString())
+		return
+	}
+	log.Printf("github-stale-pr-bot %s", versionString())
+	if err := applyEnv(); err != nil {
+		log.Fatalf("cannot read environment: %s", err)
+	}
+	if err := setupHTTP(); err != nil {
+		log.Fatalf("cannot configure HTTP client: %s", err)
+	}
+	if err := resolveSecrets(); err != nil {
+		log.Fatalf("cannot resolve secrets: %s", err)
+	}
+	if *updateCheckFl {
+		go checkUpdate()
+	}
+
+	if err := loadConfig(*configFl); err != nil {
+		log.Fatalf("cannot load configuration: %s", err)
+	}
+	if err := loadScript(); err != nil {
+		log.Fatalf("cannot load policy script: %s", err)
+	}
+	if err := checkTenants(); err != nil {
+		log.Fatalf("invalid tenants: %s", err)
+	}
+	if err := loadTimezone(); err != nil {
+		log.Fatalf("cannot load timezone: %s", err)
+	}
+	if err := loadHolidays(); err != nil {
+		log.Fatalf("cannot load holidays: %s", err)
+	}
+	if err := loadState(); err != nil {
+		log.Fatalf("cannot load state: %s", err)
+	}
+	p, err := newProvider(*providerFl)
+	if err != nil {
+		log.Fatalf("cannot create provider: %s", err)
+	}
+	forge = p
+
+	for _, u := range strings.Split(*eventsFl, ",") {
+		if u == "" {
+			continue
+		}
+		p, err := newPublisher(u)
+		if err != nil {
+			log.Fatalf("cannot create event publisher: %s", err)
+		}
+		publishers = append(publishers, p)
+	}
+	for _, u := range strings.Split(*webhookURLFl, ",") {
+		if u != "" {
+			publishers = append(publishers, &webhookPublisher{url: u, secret: *webhookSecretFl})
+		}
+	}
+	if *execFl != "" {
+		publishers = append(publishers, &execPublisher{command: *execFl, timeout: *execTimeoutFl})
+	}
+
+	pagers = newPagers()
+	notifiers = newNotifiers()
+	setupTracing()
+
+	if *exportFl != "" {
+		if exporter, err = newExporter(*exportFl); err != nil {
+			log.Fatalf("cannot create exporter: %s", err)
+		}
+	}
+	if *publishFl != "" {
+		if reportDestination, err = newReportDestination(*publishFl); err != nil {
+			log.Fatalf("cannot create report destination: %s", err)
+		}
+	}
+
+	if *lockFl != "" {
+		if locker, err = newLocker(*lockFl); err != nil {
+			log.Fatalf("cannot create lock: %s", err)
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "":
+	case "validate":
+		ok := validate()
+		preflight()
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	case "state":
+		if err := runState(os.Stdout, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "ignore":
+		if err := runIgnore(os.Stdout, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "history":
+		if err := runHistory(os.Stdout, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "report":
+		if err := runReport(os.Stdout, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "fairness":
+		if err := runFairness(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "simulate":
+		if err := runSimulation(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	default:
+		log.Fatalf("unknown command %q", flag.Arg(0))
+	}
+	if *startupCheckFl && !validate() {
+		log.Fatal("startup check failed, see -startup-check")
+	}
+	preflight()
+
+	if *listenFl == "" {
+		handleSignals(nil)
+		os.Exit(exitCode(runScan()))
+	}
+
+	if *configFl != "" && *configReloadFl > 0 {
+		go watchConfig()
+	}
+	if len(secretRefs) > 0 && *secretRefreshFl > 0 {
+		go watchSecrets()
+	}
+	scheduleTenants()
+	if *intervalFl > 0 {
+		go func() {
+			for {
+				if err := runScan(); err != nil {
+					log.Printf("scan failed: %s", err)
+				}
+				if !sleepUnlessShutdown(*intervalFl) {
+					return
+				}
+			}
+		}()
+	}
+	saveRequestAuth()
+	srv := &http.Server{Addr: *listenFl, Handler: newServer()}
+	handleSignals(func(ctx context.Context) {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("cannot shut down HTTP server: %s", err)
+		}
+		inflight.Wait()
+		// waits for the running scan, scans do not start anymore
+		scanMu.Lock()
+	})
+	log.Printf("listening on %s", *listenFl)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatalf("HTTP server failed: %s", err)
 	}
-	wg.Wait()
+	// handleSignals exits once work in flight is done
+	select {}
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// mattermostNotifier posts messages to Mattermost, using an incoming
+// webhook or, when token is set, the REST API as a bot.
+type mattermostNotifier struct {
+	// url is the incoming webhook URL, or the server URL when token is
+	// set.
+	url   string
+	token string
+	// channel is the channel name for webhooks, channel ID for the REST
+	// API. Empty uses the webhook's default channel.
+	channel string
+}
+
+// mattermostFormat converts Slack formatted text to Mattermost markdown.
+var mattermostFormat = chatFormat{
+	Link: func(url, label string) string {
+		if url == label {
+			return url
+		}
+		return fmt.Sprintf("[%s](%s)", label, url)
+	},
+	Mention: func(login string) string {
+		if name := config.Members[login].Mattermost; name != "" {
+			return "@" + name
+		}
+		return ""
+	},
+	Broadcast: func(name string) string {
+		return "@" + name
+	},
+}
+
+func (n *mattermostNotifier) Notify(msg slackMessage) error {
+	text := mattermostFormat.convert(msg.Text)
+	channel := n.channel
+	if p := messagePolicy(msg); p != nil && p.MattermostChannel != "" {
+		channel = p.MattermostChannel
+	}
+	var attachments []interface{}
+	if issue := msg.Issue; issue != nil {
+		repo, _ := issue.GetRepository()
+		assignee := tr(issue, "nobody")
+		if issue.Assignee != nil {
+			assignee = mattermostFormat.plain("@" + issue.Assignee.Login)
+		}
+		attachments = []interface{}{map[string]interface{}{
+			"fallback":   text,
+			"color":      mattermostColors[msg.Color],
+			"title":      fmt.Sprintf("%s#%d %s", repo, issue.Number, issue.Title),
+			"title_link": issue.HTMLURL,
+			"fields": []interface{}{
+				map[string]interface{}{"title": tr(issue, "field_repository"), "value": repo, "short": true},
+				map[string]interface{}{"title": tr(issue, "field_age"), "value": formatAge(time.Since(issue.CreatedAt)), "short": true},
+				map[string]interface{}{"title": tr(issue, "field_assignee"), "value": assignee, "short": true},
+			},
+		}}
+	}
+
+	var payload map[string]interface{}
+	url := n.url
+	if n.token == "" {
+		payload = map[string]interface{}{
+			"text":     text,
+			"username": "github-pr",
+		}
+		if channel != "" {
+			payload["channel"] = channel
+		}
+		if attachments != nil {
+			payload["attachments"] = attachments
+		}
+	} else {
+		url = strings.TrimSuffix(n.url, "/") + "/api/v4/posts"
+		payload = map[string]interface{}{
+			"channel_id": channel,
+			"message":    text,
+		}
+		if attachments != nil {
+			payload["props"] = map[string]interface{}{"attachments": attachments}
+		}
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode payload: %s", err)
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot POST to Mattermost: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected Mattermost response: %d, %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// mattermostColors maps Slack attachment color names, which Mattermost
+// does not know, to hex colors.
+var mattermostColors = map[string]string{
+	"":        "#2eb886",
+	"warning": "#daa038",
+	"danger":  "#a30200",
+}
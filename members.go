@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Member is the configuration of a single team member.
+type Member struct {
+	// Timezone is the IANA name of member's timezone, for example
+	// "Asia/Singapore".
+	Timezone string `json:"timezone"`
+	// Slack is the member's Slack user ID. When timezone is not set, it
+	// is read from the Slack profile.
+	Slack string `json:"slack"`
+	// Region is the holidays region the member lives in, one of the
+	// keys of the holidays configuration.
+	Region string `json:"region"`
+	// Discord is the member's Discord user ID, used to mention the
+	// member in Discord notifications.
+	Discord string `json:"discord"`
+	// Mattermost is the member's Mattermost username, used to mention the
+	// member in Mattermost notifications.
+	Mattermost string `json:"mattermost"`
+	// Telegram is the member's Telegram username, used to mention the
+	// member in Telegram notifications.
+	Telegram string `json:"telegram"`
+	// GoogleChat is the member's Google Chat user ID, used to mention
+	// the member in Google Chat notifications.
+	GoogleChat string `json:"google_chat"`
+	// Team is the sub-team the member belongs to, used to avoid or
+	// prefer reviewers from the author's team.
+	Team string `json:"team"`
+	// Started is the date the member joined the team, in "2006-01-02"
+	// format, used for ramp-up.
+	Started string `json:"started"`
+	// Capacity is the share of assignments the member gets compared to
+	// other members, for example 0.5 for part-timers. Zero means full
+	// capacity, 1.
+	Capacity float64 `json:"capacity"`
+	// Vacations lists absences of the member.
+	Vacations []Vacation `json:"vacations"`
+}
+
+var (
+	locationsMu    sync.Mutex
+	locationsCache = map[string]*time.Location{}
+	// locationsPending are closed once timezones being looked up are
+	// cached.
+	locationsPending = map[string]chan struct{}{}
+	// locationsGen is increased by every reset of the cache.
+	locationsGen int
+)
+
+// memberLocation returns timezone of member with given login, falling back
+// to the global timezone. Results are cached for the lifetime of the
+// process. Slack profiles are read without holding the lock, while other
+// callers asking about the same member wait.
+func memberLocation(login string) *time.Location {
+	locationsMu.Lock()
+	for {
+		if loc, ok := locationsCache[login]; ok {
+			locationsMu.Unlock()
+			return loc
+		}
+		wait, ok := locationsPending[login]
+		if !ok {
+			break
+		}
+		locationsMu.Unlock()
+		<-wait
+		locationsMu.Lock()
+	}
+	done := make(chan struct{})
+	locationsPending[login] = done
+	gen := locationsGen
+	m := config.Members[login]
+	locationsMu.Unlock()
+
+	loc, ok := lookupLocation(login, m)
+
+	locationsMu.Lock()
+	delete(locationsPending, login)
+	close(done)
+	// results of lookups started before a reset are not kept
+	if ok && gen == locationsGen {
+		locationsCache[login] = loc
+	}
+	locationsMu.Unlock()
+	return loc
+}
+
+// lookupLocation returns timezone of member m with given login, and false
+// if it could not be read from Slack and should not be cached.
+func lookupLocation(login string, m Member) (*time.Location, bool) {
+	loc := timezone
+	name := m.Timezone
+	if name == "" && m.Slack != "" && *slackTokenFl != "" {
+		tz, err := slackTimezone(m.Slack)
+		if err != nil {
+			log.Printf("cannot read Slack timezone of %s: %s", login, err)
+			return loc, false
+		}
+		name = tz
+	}
+	if name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			log.Printf("invalid timezone of %s: %s", login, err)
+		} else {
+			loc = l
+		}
+	}
+	return loc, true
+}
+
+// memberCapacity returns capacity of member with given login, reduced while
+// the member ramps up.
+func memberCapacity(login string) float64 {
+	c := config.Members[login].Capacity
+	if c <= 0 {
+		c = 1
+	}
+	if rampingUp(login, time.Now()) {
+		c *= *rampUpCapacityFl
+	}
+	return c
+}
+
+// resetLocations forgets cached member timezones, so that they are read
+// again from the current configuration.
+func resetLocations() {
+	locationsMu.Lock()
+	locationsCache = map[string]*time.Location{}
+	locationsGen++
+	locationsMu.Unlock()
+}
+
+// slackTimezone returns timezone name from profile of Slack user with given
+// ID.
+func slackTimezone(id string) (string, error) {
+	req, err := http.NewRequest("GET", slackAPIURL+"users.info?user="+url.QueryEscape(id), nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create GET request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+*slackTokenFl)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		User  struct {
+			TZ string `json:"tz"`
+		} `json:"user"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("cannot decode response: %s", err)
+	}
+	if !result.OK {
+		return "", fmt.Errorf("users.info failed: %s", result.Error)
+	}
+	return result.User.TZ, nil
+}
+
+// scheduledDue returns true if reminder can be sent at given time, when
+// reminders are scheduled for -remind-at local time of the recipient. That
+// is the case when the scheduled time of the day passed and no reminder was
+// sent since.
+func scheduledDue(notifiedAt, now time.Time, loc *time.Location) bool {
+	if *remindAtFl == "" {
+		return true
+	}
+	at, err := parseClock(*remindAtFl)
+	if err != nil {
+		return true
+	}
+	local := now.In(loc)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), at/60, at%60, 0, 0, loc)
+	if local.Before(scheduled) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	return notifiedAt.Before(scheduled)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metricLabels are Prometheus labels of a series, written in the order
+// given.
+type metricLabels [][2]string
+
+func (l metricLabels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(l))
+	for _, kv := range l {
+		parts = append(parts, kv[0]+"="+strconv.Quote(kv[1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// metricFamily is a Prometheus gauge with its series keyed by labels.
+type metricFamily struct {
+	name   string
+	help   string
+	series map[string]float64
+	labels map[string]metricLabels
+}
+
+func newMetricFamily(name, help string) *metricFamily {
+	return &metricFamily{
+		name:   name,
+		help:   help,
+		series: map[string]float64{},
+		labels: map[string]metricLabels{},
+	}
+}
+
+// add adds given value to the series with given labels.
+func (m *metricFamily) add(labels metricLabels, v float64) {
+	key := labels.String()
+	m.series[key] += v
+	m.labels[key] = labels
+}
+
+// max keeps the greater of given and current value of the series with
+// given labels.
+func (m *metricFamily) max(labels metricLabels, v float64) {
+	key := labels.String()
+	if cur, ok := m.series[key]; !ok || v > cur {
+		m.series[key] = v
+	}
+	m.labels[key] = labels
+}
+
+// write writes the family in Prometheus text exposition format, series
+// sorted by labels.
+func (m *metricFamily) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", m.name, key, strconv.FormatFloat(m.series[key], 'f', -1, 64))
+	}
+}
+
+// writeMetrics writes metrics of the last scan labelled by repository and
+// team of the assignee, and stale pull requests assigned to each member.
+func writeMetrics(w io.Writer, v scanView) {
+	prefix := "stale_pr_bot_"
+	stale := newMetricFamily(prefix+"stale_prs", "Number of stale pull requests found by the last scan.")
+	old := newMetricFamily(prefix+"old_prs", "Number of stale pull requests past their old threshold.")
+	ageSum := newMetricFamily(prefix+"stale_age_seconds_sum", "Sum of times since stale pull requests became stale.")
+	ageMax := newMetricFamily(prefix+"stale_age_seconds_max", "Longest time since a stale pull request became stale.")
+	assigned := newMetricFamily(prefix+"assigned_stale_prs", "Number of stale pull requests assigned to each member.")
+	for _, pr := range v.PRs {
+		team := v.Teams[pr.Assignee]
+		labels := metricLabels{{"repo", pr.Repo}, {"team", team}}
+		stale.add(labels, 1)
+		if pr.Old {
+			old.add(labels, 1)
+		} else {
+			old.add(labels, 0)
+		}
+		ageSum.add(labels, float64(pr.AgeSeconds))
+		ageMax.max(labels, float64(pr.AgeSeconds))
+	}
+	for login, n := range v.assignmentCounts() {
+		assigned.add(metricLabels{{"assignee", login}, {"team", v.Teams[login]}}, float64(n))
+	}
+	for _, m := range []*metricFamily{stale, old, ageSum, ageMax, assigned} {
+		m.write(w)
+	}
+	if !v.At.IsZero() {
+		fmt.Fprintf(w, "# HELP %slast_scan_timestamp_seconds Time of the last scan.\n# TYPE %slast_scan_timestamp_seconds gauge\n%slast_scan_timestamp_seconds %d\n", prefix, prefix, prefix, v.At.Unix())
+	}
+}
+
+// metricsHandler serves metrics of the last scan for Prometheus.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, currentView())
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Notifier delivers messages to a chat service other than Slack. Messages
+// are written in Slack format and converted by the notifier.
+type Notifier interface {
+	Notify(msg slackMessage) error
+}
+
+// notifiers are the configured chat services other than Slack.
+var notifiers []Notifier
+
+// newNotifiers returns notifiers configured by flags.
+func newNotifiers() []Notifier {
+	var list []Notifier
+	if *discordWebhookFl != "" {
+		list = append(list, &discordNotifier{url: *discordWebhookFl})
+	}
+	if *googleChatWebhookFl != "" {
+		list = append(list, &googleChatNotifier{url: *googleChatWebhookFl})
+	}
+	if *telegramTokenFl != "" {
+		list = append(list, &telegramNotifier{token: *telegramTokenFl, chats: parseChats(*telegramChatsFl)})
+	}
+	switch {
+	case *mattermostTokenFl != "":
+		list = append(list, &mattermostNotifier{url: *mattermostURLFl, token: *mattermostTokenFl, channel: *mattermostChannelFl})
+	case *mattermostURLFl != "":
+		list = append(list, &mattermostNotifier{url: *mattermostURLFl, channel: *mattermostChannelFl})
+	}
+	return list
+}
+
+// messagePolicy returns repository policy of pull request the message is
+// about, nil if there is none.
+func messagePolicy(msg slackMessage) *RepoPolicy {
+	switch {
+	case msg.Issue != nil:
+		return issuePolicy(msg.Issue)
+	case msg.Repo != "":
+		return repoPolicy(msg.Repo)
+	}
+	return keyPolicy(msg.Key)
+}
+
+// notificationsEnabled returns true if Slack or any other chat service is
+// configured.
+func notificationsEnabled() bool {
+	return slackEnabled() || len(notifiers) > 0
+}
+
+// notify sends message to Slack and all other configured chat services. An
+// error is returned only if the message could not be delivered anywhere,
+// other failures are logged.
+func notify(msg slackMessage) error {
+	var firstErr error
+	delivered := false
+	if slackEnabled() {
+		if err := deliverSlack(msg); err != nil {
+			firstErr = err
+		} else {
+			delivered = true
+		}
+	}
+	for _, n := range notifiers {
+		if err := n.Notify(msg); err != nil {
+			log.Printf("cannot notify: %s", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			delivered = true
+		}
+	}
+	if delivered {
+		return nil
+	}
+	return firstErr
+}
+
+var (
+	// slackEntityRegex matches links, user mentions and broadcasts of
+	// Slack formatted text, for example "<https://...|label>".
+	slackEntityRegex = regexp.MustCompile(`<([^<>]+)>`)
+	// loginMentionRegex matches github logins mentioned as "@login".
+	loginMentionRegex = regexp.MustCompile(`\B@[A-Za-z0-9][A-Za-z0-9-]*`)
+)
+
+// chatFormat converts Slack formatted text for another chat service.
+type chatFormat struct {
+	// Link formats link with given label.
+	Link func(url, label string) string
+	// Mention returns mention of member with given github login, or empty
+	// string if the member is not known to the service.
+	Mention func(login string) string
+	// Broadcast returns mention of the whole channel, given "here" or
+	// "channel".
+	Broadcast func(name string) string
+	// Escape escapes plain text, nil if no escaping is needed.
+	Escape func(s string) string
+}
+
+// convert returns given Slack formatted text in the format of the service.
+func (f chatFormat) convert(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range slackEntityRegex.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(f.plain(text[last:m[0]]))
+		b.WriteString(f.entity(text[m[2]:m[3]]))
+		last = m[1]
+	}
+	b.WriteString(f.plain(text[last:]))
+	return b.String()
+}
+
+func (f chatFormat) plain(s string) string {
+	escape := f.Escape
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range loginMentionRegex.FindAllStringIndex(s, -1) {
+		b.WriteString(escape(s[last:m[0]]))
+		if mention := f.Mention(s[m[0]+1 : m[1]]); mention != "" {
+			b.WriteString(mention)
+		} else {
+			b.WriteString(escape(s[m[0]:m[1]]))
+		}
+		last = m[1]
+	}
+	b.WriteString(escape(s[last:]))
+	return b.String()
+}
+
+func (f chatFormat) entity(e string) string {
+	switch {
+	case strings.HasPrefix(e, "@"):
+		login := slackLogin(e[1:])
+		if mention := f.Mention(login); mention != "" {
+			return mention
+		}
+		return f.plain("@" + login)
+	case strings.HasPrefix(e, "!"):
+		return f.Broadcast(e[1:])
+	}
+	url, label := e, e
+	if i := strings.Index(e, "|"); i >= 0 {
+		url, label = e[:i], e[i+1:]
+	}
+	if f.Escape != nil {
+		label = f.Escape(label)
+	}
+	return f.Link(url, label)
+}
+
+// slackLogin returns github login of member with given Slack ID, or the ID
+// if there is no such member.
+func slackLogin(id string) string {
+	for login, m := range config.Members {
+		if m.Slack == id {
+			return login
+		}
+	}
+	return id
+}
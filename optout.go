@@ -0,0 +1,18 @@
+package main
+
+import "strings"
+
+// optedOut returns true if author exempted given pull request from all bot
+// actions with one of the -opt-out markers in its title or description.
+func optedOut(issue *Issue) bool {
+	for _, marker := range strings.Split(*optOutFl, ",") {
+		marker = strings.ToLower(strings.TrimSpace(marker))
+		if marker == "" {
+			continue
+		}
+		if strings.Contains(strings.ToLower(issue.Title), marker) || strings.Contains(strings.ToLower(issue.Body), marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// responseError is returned for github API responses with unexpected
+// status.
+type responseError struct {
+	Status int
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("unexpected response: %d", e.Status)
+}
+
+// parseLinks returns URLs of Link header by relation, for example "next" or
+// "last". Links can be in any order and a link can have several space
+// separated relations.
+func parseLinks(header string) map[string]string {
+	links := map[string]string{}
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		target = target[1 : len(target)-1]
+		for _, param := range parts[1:] {
+			i := strings.Index(param, "=")
+			if i < 0 || !strings.EqualFold(strings.TrimSpace(param[:i]), "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(param[i+1:]), `"`)) {
+				links[strings.ToLower(rel)] = target
+			}
+		}
+	}
+	return links
+}
+
+// githubGetPage returns JSON and headers of github API GET response.
+func githubGetPage(url string) (json.RawMessage, http.Header, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	var page json.RawMessage
+	err = json.NewDecoder(resp.Body).Decode(&page)
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &responseError{Status: resp.StatusCode}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot decode response: %s", err)
+	}
+	return page, resp.Header, nil
+}
+
+// githubGetPages calls fn with JSON of every page of github API GET request,
+// following Link headers. When the first page links the last one, remaining
+// pages are fetched concurrently, if the rate limit allows, and fn is still
+// called in page order.
+func githubGetPages(url string, fn func(page json.RawMessage) error) error {
+	page, header, err := githubGetPage(url)
+	if err != nil {
+		return err
+	}
+	if err := fn(page); err != nil {
+		return err
+	}
+	links := parseLinks(header.Get("Link"))
+	if urls := pageURLs(links["last"]); len(urls) > 1 && *pageConcurrencyFl > 1 && rateLimitAllows(header, len(urls)) {
+		pages, err := fetchPages(urls[1:])
+		if err != nil {
+			return err
+		}
+		for _, page := range pages {
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for url := links["next"]; url != ""; url = parseLinks(header.Get("Link"))["next"] {
+		if page, header, err = githubGetPage(url); err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageURLs returns URLs of all pages up to given URL of the last page, or
+// nil if the URL has no page number.
+func pageURLs(last string) []string {
+	u, err := url.Parse(last)
+	if err != nil {
+		return nil
+	}
+	q := u.Query()
+	n, err := strconv.Atoi(q.Get("page"))
+	if err != nil || n < 1 {
+		return nil
+	}
+	urls := make([]string, n)
+	for i := range urls {
+		q.Set("page", strconv.Itoa(i+1))
+		u.RawQuery = q.Encode()
+		urls[i] = u.String()
+	}
+	return urls
+}
+
+// rateLimitAllows returns true if rate limit reported by given response
+// headers allows fetching given number of pages.
+func rateLimitAllows(header http.Header, pages int) bool {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		// unknown budget, for example GitHub Enterprise with rate limits
+		// disabled
+		return true
+	}
+	return remaining > pages
+}
+
+// fetchPages returns JSON of given pages, in the same order, fetching at
+// most -page-concurrency of them at the same time.
+func fetchPages(urls []string) ([]json.RawMessage, error) {
+	pages := make([]json.RawMessage, len(urls))
+	errs := make([]error, len(urls))
+	sem := make(chan struct{}, *pageConcurrencyFl)
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, url string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			pages[i], _, errs[i] = githubGetPage(url)
+		}(i, url)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// githubGetAll decodes items of all pages of github API GET request, that
+// responds with JSON arrays, into slice v.
+func githubGetAll(url string, v interface{}) error {
+	var items []json.RawMessage
+	err := githubGetPages(url, func(page json.RawMessage) error {
+		var list []json.RawMessage
+		if err := json.Unmarshal(page, &list); err != nil {
+			return fmt.Errorf("cannot decode response: %s", err)
+		}
+		items = append(items, list...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("cannot encode items: %s", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("cannot decode response: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPageURLs(t *testing.T) {
+	tests := []struct {
+		last string
+		want []string
+	}{
+		{
+			last: "https://api.github.com/orgs/acme/issues?page=3&per_page=100",
+			want: []string{
+				"https://api.github.com/orgs/acme/issues?page=1&per_page=100",
+				"https://api.github.com/orgs/acme/issues?page=2&per_page=100",
+				"https://api.github.com/orgs/acme/issues?page=3&per_page=100",
+			},
+		},
+		{
+			last: "https://api.github.com/orgs/acme/issues?page=1",
+			want: []string{"https://api.github.com/orgs/acme/issues?page=1"},
+		},
+		{last: "https://api.github.com/orgs/acme/issues?per_page=100"},
+		{last: "https://api.github.com/orgs/acme/issues?page=0"},
+		{last: "://"},
+	}
+	for _, tt := range tests {
+		if got := pageURLs(tt.last); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pageURLs(%q) = %v, want %v", tt.last, got, tt.want)
+		}
+	}
+}
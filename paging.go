@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Pager alerts the on-call reviewer about critical pull requests. Alerts
+// are deduplicated by pull request key.
+type Pager interface {
+	// Trigger opens alert about given pull request, unless one is open.
+	Trigger(key string, issue *Issue, summary string) error
+	// Resolve closes alert about pull request with given key.
+	Resolve(key string) error
+}
+
+// pagers are the configured paging backends.
+var pagers []Pager
+
+// newPagers returns pagers configured by flags.
+func newPagers() []Pager {
+	var list []Pager
+	if *pagerDutyKeyFl != "" {
+		list = append(list, &pagerDuty{routingKey: *pagerDutyKeyFl})
+	}
+	if *opsgenieKeyFl != "" {
+		list = append(list, &opsgenie{apiKey: *opsgenieKeyFl, schedule: *opsgenieScheduleFl})
+	}
+	return list
+}
+
+// postAlert posts JSON body to alerting API and checks the response status.
+func postAlert(url string, header http.Header, body interface{}) error {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(body); err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	req, err := http.NewRequest("POST", url, &b)
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// pagerDuty triggers incidents using PagerDuty Events API v2.
+type pagerDuty struct {
+	routingKey string
+}
+
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+func (p *pagerDuty) Trigger(key string, issue *Issue, summary string) error {
+	return postAlert(pagerDutyURL, nil, map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    key,
+		"payload": map[string]interface{}{
+			"summary":  summary,
+			"source":   "github-stale-pr-bot",
+			"severity": "critical",
+		},
+		"links": []interface{}{
+			map[string]string{"href": issue.HTMLURL, "text": key},
+		},
+	})
+}
+
+func (p *pagerDuty) Resolve(key string) error {
+	return postAlert(pagerDutyURL, nil, map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "resolve",
+		"dedup_key":    key,
+	})
+}
+
+// opsgenie creates alerts using Opsgenie Alert API, optionally routed to a
+// schedule.
+type opsgenie struct {
+	apiKey   string
+	schedule string
+}
+
+const opsgenieURL = "https://api.opsgenie.com/v2/alerts"
+
+func (o *opsgenie) header() http.Header {
+	return http.Header{"Authorization": []string{"GenieKey " + o.apiKey}}
+}
+
+func (o *opsgenie) Trigger(key string, issue *Issue, summary string) error {
+	alert := map[string]interface{}{
+		"message":     summary,
+		"alias":       key,
+		"description": issue.HTMLURL,
+		"priority":    "P1",
+		"source":      "github-stale-pr-bot",
+	}
+	if o.schedule != "" {
+		alert["responders"] = []interface{}{
+			map[string]string{"type": "schedule", "name": o.schedule},
+		}
+	}
+	return postAlert(opsgenieURL, o.header(), alert)
+}
+
+func (o *opsgenie) Resolve(key string) error {
+	u := fmt.Sprintf("%s/%s/close?identifierType=alias", opsgenieURL, url.PathEscape(key))
+	return postAlert(u, o.header(), map[string]interface{}{
+		"source": "github-stale-pr-bot",
+	})
+}
+
+// critical returns true if given pull request has one of -page-labels.
+func critical(issue *Issue) bool {
+	for _, name := range strings.Split(*pageLabelsFl, ",") {
+		if name = strings.TrimSpace(name); name != "" && hasLabel(issue, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// pageOnCall alerts on-call reviewers about given critical pull request
+// once it is stale for -page-after, and returns true if it is paged. Alerts
+// are triggered once per pull request.
+func pageOnCall(issue *Issue, now time.Time) bool {
+	if len(pagers) == 0 || !critical(issue) || staleSince(issue).Add(*pageAfterFl).After(now) {
+		return false
+	}
+	key := issueKey(issue)
+	if !getState(key).PagedAt.IsZero() {
+		return true
+	}
+	summary := tr(issue, "paging_summary", key, issue.Title, formatAge(now.Sub(staleSince(issue))))
+	paged := false
+	for _, p := range pagers {
+		if err := p.Trigger(key, issue, summary); err != nil {
+			log.Printf("cannot page about %s: %s", key, err)
+			continue
+		}
+		paged = true
+	}
+	if !paged {
+		return false
+	}
+	log.Printf("Paged on-call reviewer about PR #%d (%s)", issue.Number, issue.Title)
+	err := updateState(key, func(s *PRState) {
+		s.PagedAt = now
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+	return true
+}
+
+// resolvePage closes alerts about pull request with given key, if it was
+// paged.
+func resolvePage(key string) {
+	if len(pagers) == 0 || getState(key).PagedAt.IsZero() {
+		return
+	}
+	for _, p := range pagers {
+		if err := p.Resolve(key); err != nil {
+			log.Printf("cannot resolve page about %s: %s", key, err)
+		}
+	}
+	err := updateState(key, func(s *PRState) {
+		s.PagedAt = time.Time{}
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// RepoPolicy overrides settings for pull requests of repositories matching
+// any of the globs.
+type RepoPolicy struct {
+	Repos []string `json:"repos"`
+	// SLA overrides default thresholds. Branch, size, priority and label
+	// SLA still override it.
+	SLA SLA `json:"sla"`
+	// Pool are logins pull requests are assigned to instead of the team
+	// members.
+	Pool []string `json:"pool"`
+	// Shadows are logins of members shadowing reviews instead of
+	// -shadow-pool.
+	Shadows []string `json:"shadows"`
+	// Teammates is avoid or prefer to pick reviewers from other or the
+	// same team as the author instead of -teammates.
+	Teammates string `json:"teammates"`
+	// Rotation names separate round robin of team members for pull
+	// requests of the repositories. Policies with the same rotation
+	// share it.
+	Rotation string `json:"rotation"`
+	// SlackChannel is the channel reminders are posted to with Slack Web
+	// API.
+	SlackChannel string `json:"slack_channel"`
+	// MattermostChannel is the channel reminders are posted to in
+	// Mattermost, its name for webhooks and its ID for the bot API.
+	MattermostChannel string `json:"mattermost_channel"`
+	// TelegramChat is the ID of the Telegram chat reminders are posted
+	// to instead of -telegram-chats.
+	TelegramChat string `json:"telegram_chat"`
+	// Locale is the locale of messages about pull requests of the
+	// repositories instead of -locale.
+	Locale string `json:"locale"`
+	// Features enables or disables features regardless of flags. Known
+	// features are assign, remind, escalate, size_label, check_run,
+	// auto_merge and require_link, and <action>_comment and
+	// <action>_notify switching communication channels of assign, remind
+	// and escalate.
+	Features map[string]bool `json:"features"`
+}
+
+// repoPolicy returns the first policy matching given repository, nil if
+// there is none.
+func repoPolicy(repo string) *RepoPolicy {
+	for i := range config.Policies {
+		for _, glob := range config.Policies[i].Repos {
+			if globMatch(glob, repo) {
+				return &config.Policies[i]
+			}
+		}
+	}
+	return nil
+}
+
+// issuePolicy returns repository policy of given issue, nil if there is
+// none.
+func issuePolicy(issue *Issue) *RepoPolicy {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return nil
+	}
+	return repoPolicy(repo)
+}
+
+// keyPolicy returns repository policy of pull request with given key, nil
+// if there is none.
+func keyPolicy(key string) *RepoPolicy {
+	i := strings.LastIndex(key, "#")
+	if i < 0 {
+		return nil
+	}
+	return repoPolicy(key[:i])
+}
+
+// featureEnabled returns true if given feature is enabled for given issue,
+// falling back to given flag value when its policy does not say.
+func featureEnabled(issue *Issue, feature string, flag bool) bool {
+	if p := issuePolicy(issue); p != nil {
+		if on, ok := p.Features[feature]; ok {
+			return on
+		}
+	}
+	return flag
+}
+
+// teammatesMode returns how teammates of the author of given pull request
+// are picked: any, avoid or prefer.
+func teammatesMode(issue *Issue) string {
+	if p := issuePolicy(issue); p != nil && p.Teammates != "" {
+		return p.Teammates
+	}
+	return *teammatesFl
+}
+
+// nextFromPool returns member of given pool from round robin with given
+// name, that can review given issue.
+func nextFromPool(name string, logins []string, issue *Issue) (User, error) {
+	var pool []User
+	for _, login := range logins {
+		if login != "" {
+			pool = append(pool, User{Login: login})
+		}
+	}
+	if len(pool) == 0 {
+		return User{}, errors.New("empty pool")
+	}
+	return rotate(name, pool, func(login string) bool {
+		return canReview(issue, login)
+	})
+}
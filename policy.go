@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Duration is time.Duration that is JSON encoded as a string, for example
+// "4h" or "30m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %s", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// SLA defines thresholds overriding the default stale and old times. Zero
+// value means the default is used.
+type SLA struct {
+	Stale    Duration `json:"stale"`
+	Old      Duration `json:"old"`
+	Escalate Duration `json:"escalate"`
+	Critical Duration `json:"critical"`
+}
+
+// Policy describes how given pull request should be handled.
+type Policy struct {
+	// Stale is the time after which person is assigned.
+	Stale time.Duration
+	// Old is the time after which the assigned person is reminded.
+	Old time.Duration
+	// Escalate is the time after which the team lead is notified, zero if
+	// escalation is disabled.
+	Escalate time.Duration
+	// Critical is the time after which reminders are sent with critical
+	// severity, zero if disabled.
+	Critical time.Duration
+	// SLA is the name of the strictest SLA that was applied, empty if
+	// defaults are used.
+	SLA string
+}
+
+// policyFor returns policy that applies to given pull request. Repository
+// policy overrides defaults, branch SLA overrides repository policy,
+// external SLA overrides branch SLA, size SLA overrides both, JIRA priority
+// SLA overrides size SLA and label SLA overrides all of them. If more than
+// one label SLA matches, the strictest thresholds are used. Thresholds
+// returned by the policy script override everything.
+func policyFor(issue *Issue) Policy {
+	p := Policy{
+		Stale:    *staleTimeFl,
+		Old:      *oldTimeFl,
+		Escalate: *escalateTimeFl,
+		Critical: *criticalTimeFl,
+	}
+
+	if rp := issuePolicy(issue); rp != nil && rp.SLA != (SLA{}) {
+		p.apply(rp.SLA, "repository")
+	}
+	if sla, pattern, ok := branchSLA(issue); ok {
+		p.apply(sla, "branch "+pattern)
+	}
+
+	if ext := externalPolicy(issue); ext != nil && ext.SLA != (SLA{}) {
+		p.apply(ext.SLA, "external")
+	}
+
+	if len(config.SizeSLA) > 0 {
+		size, err := sizeOf(issue)
+		if err != nil {
+			log.Printf("cannot get size of #%d: %s", issue.Number, err)
+		} else if sla, ok := config.SizeSLA[size]; ok {
+			p.apply(sla, "size "+size)
+		}
+	}
+
+	if len(config.PrioritySLA) > 0 {
+		t, err := ticketFor(issue)
+		if err != nil {
+			log.Printf("cannot get ticket of #%d: %s", issue.Number, err)
+		} else if t != nil {
+			if sla, ok := config.PrioritySLA[t.Priority]; ok {
+				p.apply(sla, "priority "+t.Priority)
+			}
+		}
+	}
+
+	var strictest SLA
+	name := ""
+	for _, label := range issue.Labels {
+		sla, ok := config.LabelSLA[label.Name]
+		if !ok {
+			continue
+		}
+		if sla.Stale > 0 && (strictest.Stale == 0 || sla.Stale < strictest.Stale) {
+			strictest.Stale = sla.Stale
+			name = label.Name
+		}
+		if sla.Old > 0 && (strictest.Old == 0 || sla.Old < strictest.Old) {
+			strictest.Old = sla.Old
+			if strictest.Stale == 0 {
+				name = label.Name
+			}
+		}
+		if sla.Escalate > 0 && (strictest.Escalate == 0 || sla.Escalate < strictest.Escalate) {
+			strictest.Escalate = sla.Escalate
+			if name == "" {
+				name = label.Name
+			}
+		}
+		if sla.Critical > 0 && (strictest.Critical == 0 || sla.Critical < strictest.Critical) {
+			strictest.Critical = sla.Critical
+			if name == "" {
+				name = label.Name
+			}
+		}
+	}
+	if name != "" {
+		p.apply(strictest, name)
+	}
+	if sla := runScript(issue).SLA; sla != (SLA{}) {
+		p.apply(sla, "script")
+	}
+	return p
+}
+
+// apply overrides policy thresholds with non zero thresholds of given SLA.
+func (p *Policy) apply(sla SLA, name string) {
+	if sla.Stale > 0 {
+		p.Stale = time.Duration(sla.Stale)
+	}
+	if sla.Old > 0 {
+		p.Old = time.Duration(sla.Old)
+	}
+	if sla.Escalate > 0 {
+		p.Escalate = time.Duration(sla.Escalate)
+	}
+	if sla.Critical > 0 {
+		p.Critical = time.Duration(sla.Critical)
+	}
+	p.SLA = name
+}
+
+// describe returns human readable description of the SLA, used in messages
+// about given issue.
+func (p Policy) describe(issue *Issue) string {
+	if p.SLA == "" {
+		return ""
+	}
+	return tr(issue, "sla", p.SLA, p.Stale, p.Old)
+}
+
+// Severity tells how urgently a stale pull request needs attention.
+type Severity string
+
+const (
+	// SeverityStale is a pull request past the stale threshold only.
+	SeverityStale Severity = "stale"
+	// SeverityOld is a pull request past the old threshold.
+	SeverityOld Severity = "old"
+	// SeverityCritical is a pull request past the critical threshold, or
+	// labeled with one of -page-labels.
+	SeverityCritical Severity = "critical"
+)
+
+// severity returns severity of given pull request that is stale since given
+// time.
+func (p Policy) severity(issue *Issue, since, now time.Time) Severity {
+	switch {
+	case p.Critical > 0 && since.Add(p.Critical).Before(now), critical(issue):
+		return SeverityCritical
+	case since.Add(p.Old).Before(now):
+		return SeverityOld
+	}
+	return SeverityStale
+}
@@ -0,0 +1,7 @@
+//go:build postgres
+
+package main
+
+// links the Postgres driver used by the state store and the warehouse
+// export with postgres:// URLs
+import _ "github.com/lib/pq"
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// featureRequirement describes what a feature needs from the github token.
+type featureRequirement struct {
+	Name    string
+	Enabled func() bool
+	// Scopes are OAuth scopes, any of which is sufficient, nil if the
+	// feature is not available to OAuth tokens.
+	Scopes []string
+	// Permission is the github App permission, for example
+	// "checks:write", empty if the feature is not available to Apps.
+	Permission string
+	// Disable turns the feature off, nil for features the bot cannot work
+	// without.
+	Disable func()
+}
+
+// featureRequirements lists token requirements of features using github
+// API.
+var featureRequirements = []featureRequirement{
+	{
+		Name:       "assigning pull requests",
+		Enabled:    func() bool { return true },
+		Scopes:     []string{"repo", "public_repo"},
+		Permission: "pull_requests:write",
+	},
+	{
+		Name:       "listing team members",
+		Enabled:    func() bool { return !userAccount() && *ghTeamFl != "" },
+		Scopes:     []string{"read:org", "write:org", "admin:org"},
+		Permission: "members:read",
+	},
+	{
+		Name:       "-stale-label",
+		Enabled:    func() bool { return *staleLabelFl != "" },
+		Scopes:     []string{"repo", "public_repo"},
+		Permission: "issues:write",
+		Disable:    func() { *staleLabelFl = "" },
+	},
+	{
+		Name:       "-size-label",
+		Enabled:    func() bool { return *sizeLabelFl },
+		Scopes:     []string{"repo", "public_repo"},
+		Permission: "issues:write",
+		Disable:    func() { *sizeLabelFl = false },
+	},
+	{
+		Name:       "-check-run",
+		Enabled:    func() bool { return *checkRunFl },
+		Permission: "checks:write",
+		Disable:    func() { *checkRunFl = false },
+	},
+	{
+		Name:       "auto-merge",
+		Enabled:    func() bool { return config.AutoMerge != nil },
+		Scopes:     []string{"repo", "public_repo"},
+		Permission: "contents:write",
+		Disable:    func() { config.AutoMerge = nil },
+	},
+	{
+		Name:       "-project-number",
+		Enabled:    func() bool { return *projectNumberFl > 0 },
+		Scopes:     []string{"project"},
+		Permission: "organization_projects:write",
+		Disable:    func() { *projectNumberFl = 0 },
+	},
+	{
+		Name:       "-report-repo",
+		Enabled:    func() bool { return *reportRepoFl != "" },
+		Scopes:     []string{"repo", "public_repo"},
+		Permission: "issues:write",
+		Disable:    func() { *reportRepoFl = "" },
+	},
+	{
+		Name:       "-delete-branches-after",
+		Enabled:    func() bool { return *deleteBranchesAfterFl > 0 },
+		Scopes:     []string{"repo", "public_repo"},
+		Permission: "contents:write",
+		Disable:    func() { *deleteBranchesAfterFl = 0 },
+	},
+	{
+		Name:    "-publish to a gist",
+		Enabled: func() bool { return strings.HasPrefix(*publishFl, "gist://") },
+		Scopes:  []string{"gist"},
+		Disable: func() { reportDestination = nil },
+	},
+}
+
+// permissionLevels orders github App permission levels.
+var permissionLevels = map[string]int{"read": 1, "write": 2, "admin": 3}
+
+// missingGrant returns scope or permission given feature needs but the
+// token lacks, empty if it has everything needed.
+func (r featureRequirement) missingGrant(scopes map[string]bool, permissions map[string]string) string {
+	if permissions != nil {
+		if r.Permission == "" {
+			return "user token"
+		}
+		parts := strings.SplitN(r.Permission, ":", 2)
+		if permissionLevels[permissions[parts[0]]] < permissionLevels[parts[1]] {
+			return r.Permission + " permission"
+		}
+		return ""
+	}
+	if r.Scopes == nil {
+		return "github App"
+	}
+	for _, scope := range r.Scopes {
+		if scopes[scope] {
+			return ""
+		}
+	}
+	return r.Scopes[0] + " scope"
+}
+
+// unsupportedFeatures returns enabled features the github token cannot
+// perform, mapped to what it lacks. Tokens that report neither scopes nor
+// permissions, like fine-grained tokens, are not checked.
+func unsupportedFeatures() (map[string]string, error) {
+	var scopes map[string]bool
+	var permissions map[string]string
+	var err error
+	if appEnabled() {
+		permissions, err = installationPermissions()
+	} else {
+		scopes, err = oauthScopes()
+	}
+	if err != nil || (scopes == nil && permissions == nil) {
+		return nil, err
+	}
+	missing := map[string]string{}
+	for _, r := range featureRequirements {
+		if !r.Enabled() {
+			continue
+		}
+		if grant := r.missingGrant(scopes, permissions); grant != "" {
+			missing[r.Name] = grant
+		}
+	}
+	return missing, nil
+}
+
+// preflight disables enabled features the github token cannot perform and
+// warns about missing grants the bot cannot work without, so that they do
+// not fail with opaque errors during scans.
+func preflight() {
+	if githubOnly() != nil {
+		return
+	}
+	missing, err := unsupportedFeatures()
+	if err != nil {
+		log.Printf("cannot check token permissions: %s", err)
+		return
+	}
+	for _, r := range featureRequirements {
+		grant, ok := missing[r.Name]
+		if !ok {
+			continue
+		}
+		if r.Disable == nil {
+			log.Printf("WARNING: %s requires %s the token does not have", r.Name, grant)
+			continue
+		}
+		log.Printf("disabling %s, it requires %s the token does not have", r.Name, grant)
+		r.Disable()
+	}
+}
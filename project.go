@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// project is the github project (v2) board stale pull requests are moved on.
+type project struct {
+	ID      string
+	FieldID string
+	// Options maps names of the status field options (columns) to their
+	// IDs.
+	Options map[string]string
+}
+
+// loadProject returns project configured by flags.
+func loadProject() (*project, error) {
+	const query = `query($org: String!, $number: Int!, $field: String!) {
+  organization(login: $org) {
+    projectV2(number: $number) {
+      id
+      field(name: $field) {
+        ... on ProjectV2SingleSelectField {
+          id
+          options { id name }
+        }
+      }
+    }
+  }
+}`
+	var data struct {
+		Organization struct {
+			ProjectV2 struct {
+				ID    string `json:"id"`
+				Field struct {
+					ID      string `json:"id"`
+					Options []struct {
+						ID   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"options"`
+				} `json:"field"`
+			} `json:"projectV2"`
+		} `json:"organization"`
+	}
+	vars := map[string]interface{}{
+		"org":    *ghOrgFl,
+		"number": *projectNumberFl,
+		"field":  *projectFieldFl,
+	}
+	if err := githubGraphQL(query, vars, &data); err != nil {
+		return nil, err
+	}
+	p := data.Organization.ProjectV2
+	if p.Field.ID == "" {
+		return nil, fmt.Errorf("single select field %q not found", *projectFieldFl)
+	}
+	proj := &project{
+		ID:      p.ID,
+		FieldID: p.Field.ID,
+		Options: map[string]string{},
+	}
+	for _, o := range p.Field.Options {
+		proj.Options[o.Name] = o.ID
+	}
+	return proj, nil
+}
+
+// projectItem is a pull request item on the project board.
+type projectItem struct {
+	ID     string
+	Status string
+	Repo   string
+	Number int64
+	State  string
+}
+
+// items return all pull request items of the project.
+func (p *project) items() ([]projectItem, error) {
+	const query = `query($id: ID!, $field: String!, $after: String) {
+  node(id: $id) {
+    ... on ProjectV2 {
+      items(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          id
+          fieldValueByName(name: $field) {
+            ... on ProjectV2ItemFieldSingleSelectValue { name }
+          }
+          content {
+            ... on PullRequest {
+              number
+              state
+              repository { name }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+	var items []projectItem
+	var after interface{}
+	for {
+		var data struct {
+			Node struct {
+				Items struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []struct {
+						ID               string `json:"id"`
+						FieldValueByName *struct {
+							Name string `json:"name"`
+						} `json:"fieldValueByName"`
+						Content *struct {
+							Number     int64  `json:"number"`
+							State      string `json:"state"`
+							Repository *struct {
+								Name string `json:"name"`
+							} `json:"repository"`
+						} `json:"content"`
+					} `json:"nodes"`
+				} `json:"items"`
+			} `json:"node"`
+		}
+		vars := map[string]interface{}{
+			"id":    p.ID,
+			"field": *projectFieldFl,
+			"after": after,
+		}
+		if err := githubGraphQL(query, vars, &data); err != nil {
+			return nil, err
+		}
+		for _, n := range data.Node.Items.Nodes {
+			// issues and drafts have no repository in the queried
+			// fragment
+			if n.Content == nil || n.Content.Repository == nil {
+				continue
+			}
+			item := projectItem{
+				ID:     n.ID,
+				Repo:   n.Content.Repository.Name,
+				Number: n.Content.Number,
+				State:  n.Content.State,
+			}
+			if n.FieldValueByName != nil {
+				item.Status = n.FieldValueByName.Name
+			}
+			items = append(items, item)
+		}
+		if !data.Node.Items.PageInfo.HasNextPage {
+			return items, nil
+		}
+		after = data.Node.Items.PageInfo.EndCursor
+	}
+}
+
+// move sets status of given item to given column.
+func (p *project) move(item projectItem, column string) error {
+	optionID, ok := p.Options[column]
+	if !ok {
+		return fmt.Errorf("column %q not found", column)
+	}
+	const mutation = `mutation($project: ID!, $item: ID!, $field: ID!, $option: String!) {
+  updateProjectV2ItemFieldValue(input: {
+    projectId: $project, itemId: $item, fieldId: $field,
+    value: {singleSelectOptionId: $option}
+  }) { projectV2Item { id } }
+}`
+	vars := map[string]interface{}{
+		"project": p.ID,
+		"item":    item.ID,
+		"field":   p.FieldID,
+		"option":  optionID,
+	}
+	if err := githubGraphQL(mutation, vars, nil); err != nil {
+		return err
+	}
+	log.Printf("#%d pull request of %q moved to %q column", item.Number, item.Repo, column)
+	return nil
+}
+
+// syncProjectBoard moves board items of given stale pull requests to the
+// stale column, and items of open pull requests that are no longer stale
+// back to the active column. Pull requests that are not on the board are
+// ignored.
+func syncProjectBoard(stale []Issue) error {
+	p, err := loadProject()
+	if err != nil {
+		return fmt.Errorf("cannot load project: %s", err)
+	}
+	items, err := p.items()
+	if err != nil {
+		return fmt.Errorf("cannot list project items: %s", err)
+	}
+	isStale := map[string]bool{}
+	for _, issue := range stale {
+		isStale[issueKey(&issue)] = true
+	}
+	for _, item := range items {
+		key := fmt.Sprintf("%s#%d", item.Repo, item.Number)
+		switch {
+		case isStale[key] && item.Status != *projectStaleColumnFl:
+			if err := p.move(item, *projectStaleColumnFl); err != nil {
+				log.Printf("cannot move %s: %s", key, err)
+			}
+		case !isStale[key] && item.State == "OPEN" && item.Status == *projectStaleColumnFl && *projectActiveColumnFl != "":
+			if err := p.move(item, *projectActiveColumnFl); err != nil {
+				log.Printf("cannot move %s: %s", key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Provider is the code hosting service pull requests are fetched from. All
+// staleness and assignment policies are shared across providers, only the
+// communication with the service differs.
+type Provider interface {
+	// OpenIssues return all open issues and pull requests.
+	OpenIssues() ([]Issue, error)
+	// Issue returns issue with given number from given repository.
+	Issue(repo string, number int64) (*Issue, error)
+	// Members return all members pull requests can be assigned to.
+	Members() ([]User, error)
+	// Assign replaces assignee of given issue.
+	Assign(issue *Issue, user *User) error
+	// Comment writes comment on given issue.
+	Comment(issue *Issue, comment string) error
+	// RequestReview requests review of given pull request from given
+	// user.
+	RequestReview(issue *Issue, user *User) error
+}
+
+// forge is the provider selected by flag.
+var forge Provider = githubProvider{}
+
+// errNotSupported is returned by features that are not supported by the
+// selected provider.
+var errNotSupported = errors.New("not supported by provider")
+
+// newProvider returns provider with given name.
+func newProvider(name string) (Provider, error) {
+	switch name {
+	case "github":
+		return githubProvider{}, nil
+	case "gitlab":
+		return gitlabProvider{}, nil
+	case "gitea":
+		if *giteaURLFl == "" {
+			return nil, errors.New("gitea url is required")
+		}
+		return giteaProvider{}, nil
+	case "bitbucket":
+		return bitbucketProvider{}, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q", name)
+}
+
+// githubOnly returns error if selected provider is not github. Used by
+// features that talk to github API directly.
+func githubOnly() error {
+	if *providerFl != "github" {
+		return errNotSupported
+	}
+	return nil
+}
+
+// approvalsProvider is implemented by providers other than github that
+// know how many approvals a pull request needs.
+type approvalsProvider interface {
+	// MissingApprovals returns number of approvals given pull request
+	// needs before it can be merged, errNoRequiredApprovals if none are
+	// required.
+	MissingApprovals(issue *Issue) (int, error)
+}
+
+// defaultReviewersProvider is implemented by providers that let
+// repositories configure their default reviewers.
+type defaultReviewersProvider interface {
+	// DefaultReviewers returns logins of default reviewers of given
+	// repository.
+	DefaultReviewers(repo string) ([]string, error)
+}
+
+// defaultReviewers returns logins of default reviewers of the repository
+// of given pull request, nil if the provider has none.
+func defaultReviewers(issue *Issue) map[string]bool {
+	p, ok := forge.(defaultReviewersProvider)
+	if !ok {
+		return nil
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return nil
+	}
+	logins, err := p.DefaultReviewers(repo)
+	if err != nil {
+		log.Printf("cannot get default reviewers of %s: %s", repo, err)
+		return nil
+	}
+	if len(logins) == 0 {
+		return nil
+	}
+	defaults := map[string]bool{}
+	for _, login := range logins {
+		defaults[login] = true
+	}
+	return defaults
+}
+
+// githubProvider is the Provider using github API.
+type githubProvider struct{}
+
+func (githubProvider) OpenIssues() ([]Issue, error) {
+	if *fetcherFl == "graphql" {
+		return openIssuesGraphQL()
+	}
+	return openIssues()
+}
+
+func (githubProvider) Issue(repo string, number int64) (*Issue, error) {
+	return fetchIssue(repo, number)
+}
+
+func (githubProvider) Members() ([]User, error) {
+	if userAccount() {
+		return collaborators(), nil
+	}
+	return githubTeamMembers()
+}
+
+func (githubProvider) Assign(issue *Issue, user *User) error {
+	return setAssignee(issue, user)
+}
+
+func (githubProvider) Comment(issue *Issue, comment string) error {
+	return writeGithubComment(issue, comment)
+}
+
+func (githubProvider) RequestReview(issue *Issue, user *User) error {
+	return requestGithubReview(issue, user)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// reportTimeLayout is the format of the time in names of published
+// reports. It sorts chronologically and contains no colons.
+const reportTimeLayout = "2006-01-02T15-04-05Z"
+
+// ReportDestination stores reports of scans.
+type ReportDestination interface {
+	// Publish stores given files of report of the scan at given time.
+	Publish(at time.Time, files map[string][]byte) error
+	// Prune deletes reports of scans before given time.
+	Prune(before time.Time) error
+}
+
+// reportDestination is the configured report destination, nil if
+// publishing is disabled.
+var reportDestination ReportDestination
+
+// newReportDestination returns report destination for given "gist://<id>",
+// "s3://<bucket>/<prefix>" or "gs://<bucket>/<prefix>" URL.
+func newReportDestination(rawurl string) (ReportDestination, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publish url: %s", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing gist ID or bucket in %q", rawurl)
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	switch u.Scheme {
+	case "gist":
+		return &gistDestination{id: u.Host}, nil
+	case "s3":
+		return &s3Destination{bucket: u.Host, prefix: prefix}, nil
+	case "gs":
+		return &gcsDestination{bucket: u.Host, prefix: prefix}, nil
+	}
+	return nil, fmt.Errorf("unsupported publish url scheme %q", u.Scheme)
+}
+
+// publishReport publishes JSON and Markdown report of given stale pull
+// requests and deletes reports older than -publish-retention.
+func publishReport(stale []Issue, now time.Time) error {
+	prs := stalePRList(stale, now, true)
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"at":  now,
+		"prs": prs,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode report: %s", err)
+	}
+	files := map[string][]byte{
+		"stale-prs.json": data,
+		"stale-prs.md":   []byte(reportText(prs, now)),
+	}
+	if err := reportDestination.Publish(now, files); err != nil {
+		return err
+	}
+	if *publishRetentionFl > 0 {
+		if err := reportDestination.Prune(now.Add(-*publishRetentionFl)); err != nil {
+			log.Printf("cannot delete old reports: %s", err)
+		}
+	}
+	return nil
+}
+
+// reportObjects returns object names of given report files in a bucket:
+// one copy under the time of the scan and one under "latest".
+func reportObjects(prefix string, at time.Time, files map[string][]byte) map[string][]byte {
+	objects := map[string][]byte{}
+	for name, data := range files {
+		objects[prefix+at.UTC().Format(reportTimeLayout)+"/"+name] = data
+		objects[prefix+"latest/"+name] = data
+	}
+	return objects
+}
+
+// reportTime returns time of the scan the object with given name belongs
+// to, false for objects that are not timestamped reports.
+func reportTime(prefix, name string) (time.Time, bool) {
+	name = strings.TrimPrefix(name, prefix)
+	i := strings.Index(name, "/")
+	if i < 0 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(reportTimeLayout, name[:i])
+	return t, err == nil
+}
+
+// contentType returns content type of report file with given name.
+func contentType(name string) string {
+	if strings.HasSuffix(name, ".md") {
+		return "text/markdown; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// gistDestination keeps the latest report in a github gist. Older reports
+// are available as revisions of the gist.
+type gistDestination struct {
+	id string
+}
+
+func (d *gistDestination) Publish(at time.Time, files map[string][]byte) error {
+	payload := map[string]interface{}{}
+	for name, data := range files {
+		payload[name] = map[string]string{"content": string(data)}
+	}
+	url := fmt.Sprintf("%s/gists/%s", *ghAPIFl, d.id)
+	if err := githubRequest("PATCH", url, map[string]interface{}{"files": payload}, nil); err != nil {
+		return fmt.Errorf("cannot update gist: %s", err)
+	}
+	return nil
+}
+
+// Prune does nothing, gist revisions cannot be deleted.
+func (d *gistDestination) Prune(before time.Time) error {
+	return nil
+}
+
+// s3Destination stores reports in an Amazon S3 bucket. Credentials and
+// region are read from the standard AWS_* environment variables.
+type s3Destination struct {
+	bucket string
+	prefix string
+}
+
+// call sends signed request to the bucket and returns the response body.
+func (d *s3Destination) call(method, path string, query url.Values, body []byte, contentType string) ([]byte, error) {
+	region, accessKey, secretKey, err := awsCredentials()
+	if err != nil {
+		return nil, err
+	}
+	host := fmt.Sprintf("%s.s3.%s.amazonaws.com", d.bucket, region)
+	u := &url.URL{Scheme: "https", Host: host, Path: "/" + path, RawQuery: query.Encode()}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s request: %s", method, err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signAWS(req, body, host, region, "s3", accessKey, secretKey, time.Now().UTC())
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response: %s", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected response: %d, %s", resp.StatusCode, b)
+	}
+	return b, nil
+}
+
+func (d *s3Destination) Publish(at time.Time, files map[string][]byte) error {
+	for name, data := range reportObjects(d.prefix, at, files) {
+		if _, err := d.call("PUT", name, nil, data, contentType(name)); err != nil {
+			return fmt.Errorf("cannot upload %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func (d *s3Destination) Prune(before time.Time) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {d.prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		b, err := d.call("GET", "", query, nil, "")
+		if err != nil {
+			return fmt.Errorf("cannot list objects: %s", err)
+		}
+		var list struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(b, &list); err != nil {
+			return fmt.Errorf("cannot decode response: %s", err)
+		}
+		for _, o := range list.Contents {
+			if t, ok := reportTime(d.prefix, o.Key); ok && t.Before(before) {
+				if _, err := d.call("DELETE", o.Key, nil, nil, ""); err != nil {
+					return fmt.Errorf("cannot delete %s: %s", o.Key, err)
+				}
+			}
+		}
+		if list.NextContinuationToken == "" {
+			return nil
+		}
+		token = list.NextContinuationToken
+	}
+}
+
+// gcsDestination stores reports in a Google Cloud Storage bucket. Access
+// token is obtained the same way as for Google Secret Manager.
+type gcsDestination struct {
+	bucket string
+	prefix string
+}
+
+// call sends request to Cloud Storage JSON API and returns the response
+// body.
+func (d *gcsDestination) call(method, url string, body []byte, contentType string) ([]byte, error) {
+	token, err := gcpAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s request: %s", method, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response: %s", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected response: %d, %s", resp.StatusCode, b)
+	}
+	return b, nil
+}
+
+func (d *gcsDestination) Publish(at time.Time, files map[string][]byte) error {
+	for name, data := range reportObjects(d.prefix, at, files) {
+		u := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s", d.bucket, url.QueryEscape(name))
+		if _, err := d.call("POST", u, data, contentType(name)); err != nil {
+			return fmt.Errorf("cannot upload %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func (d *gcsDestination) Prune(before time.Time) error {
+	base := fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o", d.bucket)
+	token := ""
+	for {
+		query := url.Values{"prefix": {d.prefix}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		b, err := d.call("GET", base+"?"+query.Encode(), nil, "")
+		if err != nil {
+			return fmt.Errorf("cannot list objects: %s", err)
+		}
+		var list struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(b, &list); err != nil {
+			return fmt.Errorf("cannot decode response: %s", err)
+		}
+		for _, o := range list.Items {
+			if t, ok := reportTime(d.prefix, o.Name); ok && t.Before(before) {
+				if _, err := d.call("DELETE", base+"/"+url.PathEscape(o.Name), nil, ""); err != nil {
+					return fmt.Errorf("cannot delete %s: %s", o.Name, err)
+				}
+			}
+		}
+		if list.NextPageToken == "" {
+			return nil
+		}
+		token = list.NextPageToken
+	}
+}
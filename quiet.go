@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timezone is the location quiet hours are evaluated in.
+var timezone = time.Local
+
+// loadTimezone sets timezone from -timezone flag.
+func loadTimezone() error {
+	if *timezoneFl == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(*timezoneFl)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %s", err)
+	}
+	timezone = loc
+	return nil
+}
+
+// parseClock returns minutes since midnight of given "HH:MM" time.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseQuietHours returns start and end, in minutes since midnight, of
+// given "HH:MM-HH:MM" range. The range can span midnight.
+func parseQuietHours(s string) (int, int, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", s)
+	}
+	from, err := parseClock(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := parseClock(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return from, to, nil
+}
+
+// parseWeekdays returns set of week days given as comma separated three
+// letter names, for example "Sat,Sun".
+func parseWeekdays(s string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(d.String()[:3], name) {
+				days[d] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid week day %q", name)
+		}
+	}
+	return days, nil
+}
+
+// checkQuietHours verifies quiet hours flags.
+func checkQuietHours() error {
+	if *quietHoursFl != "" {
+		if _, _, err := parseQuietHours(*quietHoursFl); err != nil {
+			return err
+		}
+	}
+	_, err := parseWeekdays(*quietDaysFl)
+	return err
+}
+
+// quietAt returns true if no notifications should be sent at given time,
+// evaluated in given location.
+func quietAt(now time.Time, loc *time.Location) bool {
+	now = now.In(loc)
+	if days, err := parseWeekdays(*quietDaysFl); err == nil && days[now.Weekday()] {
+		return true
+	}
+	if *quietHoursFl == "" {
+		return false
+	}
+	from, to, err := parseQuietHours(*quietHoursFl)
+	if err != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	// range spans midnight
+	return minute >= from || minute < to
+}
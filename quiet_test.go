@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		in       string
+		from, to int
+		err      bool
+	}{
+		{in: "18:00-09:00", from: 18 * 60, to: 9 * 60},
+		{in: "12:30-13:15", from: 12*60 + 30, to: 13*60 + 15},
+		{in: " 22:00 - 06:00 ", from: 22 * 60, to: 6 * 60},
+		{in: "18:00", err: true},
+		{in: "18:00-09:00-10:00", err: true},
+		{in: "25:00-09:00", err: true},
+		{in: "6pm-9am", err: true},
+	}
+	for _, tt := range tests {
+		from, to, err := parseQuietHours(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("parseQuietHours(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if from != tt.from || to != tt.to {
+			t.Errorf("parseQuietHours(%q) = %d, %d, want %d, %d", tt.in, from, to, tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// rampingUp returns true if member with given login joined the team less
+// than -ramp-up ago, according to the member's start date.
+func rampingUp(login string, now time.Time) bool {
+	started := config.Members[login].Started
+	if *rampUpFl <= 0 || started == "" {
+		return false
+	}
+	t, err := time.ParseInLocation("2006-01-02", started, memberLocation(login))
+	if err != nil {
+		log.Printf("invalid start date of %s: %s", login, err)
+		return false
+	}
+	return now.Before(t.Add(*rampUpFl))
+}
+
+// pairWithExperienced requests review of given pull request, assigned to a
+// member ramping up, from the next member of the rotation that is not
+// ramping up, unless such member already reviews it.
+func pairWithExperienced(issue *Issue, now time.Time) {
+	for _, r := range issue.Reviewers {
+		if !rampingUp(r.Login, now) {
+			return
+		}
+	}
+	members, err := listMembers()
+	if err != nil {
+		log.Printf("cannot list members: %s", err)
+		return
+	}
+	user, err := rotate(rotationFor(issue), members, func(login string) bool {
+		return !rampingUp(login, now) && canReview(issue, login)
+	})
+	if err != nil {
+		log.Printf("cannot pick experienced reviewer for %d: %s", issue.ID, err)
+		return
+	}
+	addReviewer(issue, user, now)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ackReaction returns time the assignee of given pull request reacted with
+// one of -ack-reactions on the assignment comment with given ID, zero time
+// if there is no such reaction.
+func ackReaction(issue *Issue, commentID int64) (time.Time, error) {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var reactions []struct {
+		User      *User     `json:"user"`
+		Content   string    `json:"content"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d/reactions?per_page=100", *ghAPIFl, *ghOrgFl, repo, commentID)
+	if err := githubGetAll(url, &reactions); err != nil {
+		return time.Time{}, err
+	}
+	accepted := map[string]bool{}
+	for _, content := range strings.Split(*ackReactionsFl, ",") {
+		accepted[strings.TrimSpace(content)] = true
+	}
+	var latest time.Time
+	for _, r := range reactions {
+		if r.User == nil || r.User.Login != issue.Assignee.Login || !accepted[r.Content] {
+			continue
+		}
+		if r.CreatedAt.After(latest) {
+			latest = r.CreatedAt
+		}
+	}
+	return latest, nil
+}
+
+// checkAckReaction marks given pull request as acknowledged if its assignee
+// reacted on the assignment comment since the last acknowledgement.
+func checkAckReaction(issue *Issue, key string) {
+	if *ackReactionsFl == "" || githubOnly() != nil {
+		return
+	}
+	st := getState(key)
+	if st.AssignmentComment == 0 {
+		return
+	}
+	at, err := ackReaction(issue, st.AssignmentComment)
+	if err != nil {
+		log.Printf("cannot read reactions of #%d: %s", issue.Number, err)
+		return
+	}
+	if at.IsZero() || !at.After(st.AcknowledgedAt) {
+		return
+	}
+	err = updateState(key, func(s *PRState) {
+		s.AcknowledgedAt = at
+		s.AcknowledgedBy = issue.Assignee.Login
+	})
+	if err != nil {
+		log.Printf("cannot save state: %s", err)
+		return
+	}
+	log.Printf("%s acknowledged #%d with a reaction", issue.Assignee.Login, issue.Number)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secrets in recorded exchanges.
+const redacted = "REDACTED"
+
+// Exchange is a recorded HTTP request and its response.
+type Exchange struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        string      `json:"body"`
+}
+
+// secretHeaders are headers that are never recorded.
+var secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Vault-Token", "Private-Token", "X-Api-Key"}
+
+// secretFieldRegex matches tokens in JSON bodies, for example of github App
+// installation token responses.
+var secretFieldRegex = regexp.MustCompile(`"(token|access_token|refresh_token|password|secret)"\s*:\s*"[^"]*"`)
+
+// redact returns given text with values of all secret flags replaced.
+func redact(s string) string {
+	for _, name := range secretFlags {
+		if f := flag.Lookup(name); f != nil && len(f.Value.String()) > 3 {
+			s = strings.Replace(s, f.Value.String(), redacted, -1)
+		}
+	}
+	return secretFieldRegex.ReplaceAllString(s, `"$1":"`+redacted+`"`)
+}
+
+// secretBackend returns true if given URL belongs to a secret backend or
+// to a credentials endpoint, whose responses are never recorded.
+func secretBackend(u *url.URL) bool {
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" && strings.HasPrefix(u.String(), strings.TrimSuffix(addr, "/")+"/v1/") {
+		return true
+	}
+	return strings.HasPrefix(u.Host, "secretsmanager.") ||
+		u.Host == "secretmanager.googleapis.com" ||
+		u.Host == "metadata.google.internal"
+}
+
+// redactURL returns given URL with secret flags and query parameters
+// looking like credentials replaced.
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	q := c.Query()
+	for name := range q {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "token") || strings.Contains(lower, "key") || strings.Contains(lower, "secret") {
+			q.Set(name, redacted)
+		}
+	}
+	c.RawQuery = q.Encode()
+	return redact(c.String())
+}
+
+// recordingTransport saves every exchange to a numbered JSON file in a
+// directory.
+type recordingTransport struct {
+	base http.RoundTripper
+	dir  string
+
+	mu   sync.Mutex
+	next int
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read request body: %s", err)
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %s", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	e := Exchange{
+		Method:      req.Method,
+		URL:         redactURL(req.URL),
+		RequestBody: redact(string(reqBody)),
+		Status:      resp.StatusCode,
+		Header:      resp.Header.Clone(),
+		Body:        redact(string(body)),
+	}
+	for _, h := range secretHeaders {
+		e.Header.Del(h)
+	}
+	if secretBackend(req.URL) {
+		e.RequestBody = redacted
+		e.Body = redacted
+	}
+	if err := t.save(&e); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (t *recordingTransport) save(e *Exchange) error {
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode exchange: %s", err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next++
+	path := filepath.Join(t.dir, fmt.Sprintf("%06d.json", t.next))
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		return fmt.Errorf("cannot record exchange: %s", err)
+	}
+	return nil
+}
+
+// replayTransport responds with exchanges recorded by recordingTransport,
+// without any network access. Requests are matched by method, URL and
+// body, falling back to method and URL. Repeated requests get the recorded
+// responses in order, the last one is repeated when they run out.
+type replayTransport struct {
+	mu        sync.Mutex
+	exchanges map[string][]*Exchange
+}
+
+func newReplayTransport(dir string) (*replayTransport, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot list recordings: %s", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no recordings in %s", dir)
+	}
+	sort.Strings(paths)
+	t := &replayTransport{exchanges: map[string][]*Exchange{}}
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read recording: %s", err)
+		}
+		var e Exchange
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("cannot decode %s: %s", path, err)
+		}
+		for _, key := range []string{e.Method + " " + e.URL + " " + e.RequestBody, e.Method + " " + e.URL} {
+			t.exchanges[key] = append(t.exchanges[key], &e)
+		}
+	}
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read request body: %s", err)
+		}
+		reqBody = b
+	}
+	u := redactURL(req.URL)
+	e := t.take(req.Method+" "+u+" "+redact(string(reqBody)), req.Method+" "+u)
+	if e == nil {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, u)
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode: e.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     e.Header.Clone(),
+		Body:       ioutil.NopCloser(strings.NewReader(e.Body)),
+		Request:    req,
+	}, nil
+}
+
+// take returns next exchange recorded under the first of given keys that
+// has any.
+func (t *replayTransport) take(keys ...string) *Exchange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range keys {
+		list := t.exchanges[key]
+		if len(list) == 0 {
+			continue
+		}
+		e := list[0]
+		if len(list) > 1 {
+			t.exchanges[key] = list[1:]
+		}
+		return e
+	}
+	return nil
+}
+
+// wrapRecording returns given transport wrapped for -record, or replaying
+// transport for -replay.
+func wrapRecording(base http.RoundTripper) (http.RoundTripper, error) {
+	switch {
+	case *recordFl != "" && *replayFl != "":
+		return nil, errors.New("-record and -replay cannot be used together")
+	case *recordFl != "":
+		if err := os.MkdirAll(*recordFl, 0700); err != nil {
+			return nil, fmt.Errorf("cannot create recordings directory: %s", err)
+		}
+		return &recordingTransport{base: base, dir: *recordFl}, nil
+	case *replayFl != "":
+		return newReplayTransport(*replayFl)
+	}
+	return base, nil
+}
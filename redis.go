@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisClient is a minimal Redis client, speaking RESP protocol over a
+// single connection. Commands are serialized.
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// newRedisClient returns client for given "redis://[:password@]host:port[/db]"
+// URL. Connection is established lazily.
+func newRedisClient(rawurl string) (*redisClient, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %s", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("unsupported redis url scheme %q", u.Scheme)
+	}
+	c := &redisClient{addr: u.Host}
+	if !strings.Contains(c.addr, ":") {
+		c.addr += ":6379"
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database: %s", err)
+		}
+	}
+	return c, nil
+}
+
+// Do sends command and returns its reply. Replies are decoded as string,
+// int64, []interface{} or nil. Redis errors are returned as errors.
+func (c *redisClient) Do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.do(args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// connection is in unknown state
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+func (c *redisClient) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("cannot connect to redis: %s", err)
+	}
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err := c.do("AUTH", c.password); err != nil {
+			c.conn.Close()
+			c.conn = nil
+			return fmt.Errorf("cannot authenticate: %s", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(c.db)); err != nil {
+			c.conn.Close()
+			c.conn = nil
+			return fmt.Errorf("cannot select database: %s", err)
+		}
+	}
+	return nil
+}
+
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(30 * time.Second))
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		return nil, fmt.Errorf("cannot write command: %s", err)
+	}
+	return c.read()
+}
+
+func (c *redisClient) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("cannot read reply: %s", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length: %s", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := readFull(c.r, buf); err != nil {
+			return nil, fmt.Errorf("cannot read reply: %s", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length: %s", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("unknown reply type %q", line[0])
+}
+
+func readFull(r *bufio.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := r.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// configModTime is the modification time of the configuration file when it
+// was last loaded.
+var configModTime time.Time
+
+// watchConfig reloads configuration file whenever it changes, checking every
+// -config-reload. It runs forever, so it should be started in a goroutine.
+func watchConfig() {
+	if fi, err := os.Stat(*configFl); err == nil {
+		configModTime = fi.ModTime()
+	}
+	for range time.Tick(*configReloadFl) {
+		fi, err := os.Stat(*configFl)
+		if err != nil {
+			log.Printf("cannot stat configuration: %s", err)
+			continue
+		}
+		if fi.ModTime().Equal(configModTime) {
+			continue
+		}
+		configModTime = fi.ModTime()
+		reloadConfig()
+	}
+}
+
+// reloadConfig reads configuration file again and applies it between scans
+// and requests. Invalid configuration is logged and the previous one is
+// kept.
+func reloadConfig() {
+	c, err := readConfig(*configFl)
+	if err != nil {
+		log.Printf("cannot reload configuration, keeping previous one: %s", err)
+		return
+	}
+
+	globalsMu.Lock()
+	defer globalsMu.Unlock()
+	scanMu.Lock()
+	defer scanMu.Unlock()
+
+	changed := configDiff(config, c)
+	if len(changed) == 0 {
+		return
+	}
+	previous := config
+	config = c
+	if err := loadHolidays(); err != nil {
+		log.Printf("cannot reload configuration, keeping previous one: %s", err)
+		config = previous
+		return
+	}
+	// member timezones may have changed
+	resetLocations()
+	log.Printf("configuration reloaded, changed: %s", strings.Join(changed, ", "))
+	// features enabled by the new configuration may lack grants
+	preflight()
+}
+
+// configDiff returns names of configuration sections that differ between
+// given configurations. Sections that are objects, like members, are
+// compared by keys, for example "members.alice".
+func configDiff(a, b Config) []string {
+	am, bm := configSections(a), configSections(b)
+	var changed []string
+	for name := range mergedKeys(am, bm) {
+		if bytes.Equal(am[name], bm[name]) {
+			continue
+		}
+		var ao, bo map[string]json.RawMessage
+		if json.Unmarshal(am[name], &ao) != nil || json.Unmarshal(bm[name], &bo) != nil {
+			changed = append(changed, name)
+			continue
+		}
+		for key := range mergedKeys(ao, bo) {
+			if !bytes.Equal(ao[key], bo[key]) {
+				changed = append(changed, name+"."+key)
+			}
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// configSections returns JSON encoded top level sections of given
+// configuration.
+func configSections(c Config) map[string]json.RawMessage {
+	var sections map[string]json.RawMessage
+	b, _ := json.Marshal(c)
+	json.Unmarshal(b, &sections)
+	return sections
+}
+
+// mergedKeys returns union of keys of given maps.
+func mergedKeys(a, b map[string]json.RawMessage) map[string]bool {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
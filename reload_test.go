@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigDiff(t *testing.T) {
+	base := Config{
+		Members: map[string]Member{"alice": {Team: "api"}, "bob": {Team: "web"}},
+		Ignore:  []string{"api#1"},
+	}
+	tests := []struct {
+		name string
+		b    Config
+		want []string
+	}{
+		{name: "same", b: base},
+		{
+			name: "member changed",
+			b: Config{
+				Members: map[string]Member{"alice": {Team: "web"}, "bob": {Team: "web"}},
+				Ignore:  []string{"api#1"},
+			},
+			want: []string{"members.alice"},
+		},
+		{
+			name: "member added and list changed",
+			b: Config{
+				Members: map[string]Member{"alice": {Team: "api"}, "bob": {Team: "web"}, "carol": {}},
+				Ignore:  []string{"api#1", "api#2"},
+			},
+			want: []string{"ignore", "members.carol"},
+		},
+		{
+			name: "members removed",
+			b:    Config{Ignore: []string{"api#1"}},
+			want: []string{"members.alice", "members.bob"},
+		},
+	}
+	for _, tt := range tests {
+		if got := configDiff(base, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: configDiff() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
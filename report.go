@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// reportIssueKey is the state store key of the weekly report issue.
+const reportIssueKey = "report-issue"
+
+// reportIssue is the tracking issue of the current week.
+type reportIssue struct {
+	// Week is the ISO week the issue reports on, for example "2026-W42".
+	Week   string `json:"week"`
+	Number int64  `json:"number"`
+}
+
+// isoWeek returns ISO week of given time, for example "2026-W42".
+func isoWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// updateReportIssue opens tracking issue in -report-repo listing given stale
+// pull requests, or updates the issue of the current week. Issue of the
+// previous week is closed, so that exactly one report is open.
+func updateReportIssue(stale []Issue, now time.Time) error {
+	var current reportIssue
+	if _, err := loadDocument(reportIssueKey, &current); err != nil {
+		return err
+	}
+	base := fmt.Sprintf("%s/repos/%s/%s/issues", *ghAPIFl, *ghOrgFl, *reportRepoFl)
+	body := reportText(stalePRList(stale, now, true), now)
+	week := isoWeek(now.In(timezone))
+	if current.Week == week && current.Number != 0 {
+		url := fmt.Sprintf("%s/%d", base, current.Number)
+		return githubRequest("PATCH", url, map[string]interface{}{"body": body}, nil)
+	}
+
+	var created struct {
+		Number int64 `json:"number"`
+	}
+	issue := map[string]interface{}{
+		"title": tr(nil, "report_title", week),
+		"body":  body,
+	}
+	if err := githubRequest("POST", base, issue, &created); err != nil {
+		return fmt.Errorf("cannot open report issue: %s", err)
+	}
+	log.Printf("opened report issue #%d in %s", created.Number, *reportRepoFl)
+	if current.Number != 0 {
+		url := fmt.Sprintf("%s/%d", base, current.Number)
+		if err := githubRequest("PATCH", url, map[string]interface{}{"state": "closed"}, nil); err != nil {
+			log.Printf("cannot close report issue #%d: %s", current.Number, err)
+		}
+	}
+	return saveDocument(reportIssueKey, reportIssue{Week: week, Number: created.Number})
+}
+
+// reportText returns Markdown body of the report issue, with trends of the
+// last two weeks if the history is kept.
+func reportText(prs []StalePR, now time.Time) string {
+	var b strings.Builder
+	b.WriteString(tr(nil, "report_header", len(prs), now.In(timezone).Format("2006-01-02 15:04 MST")) + "\n\n")
+	if *historyRetentionFl > 0 {
+		snapshots, err := loadHistory()
+		if err != nil {
+			log.Printf("cannot load history: %s", err)
+		} else if trend := trendText(snapshots, now); trend != "" {
+			b.WriteString(trend + "\n")
+		}
+	}
+	if len(prs) == 0 {
+		return b.String()
+	}
+	b.WriteString(tr(nil, "report_columns") + "\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+	for _, pr := range prs {
+		assignee := tr(nil, "unassigned")
+		if pr.Assignee != "" {
+			// not mentioned, editing the issue would notify all assignees again
+			assignee = "`" + pr.Assignee + "`"
+		}
+		state := string(pr.Phase)
+		if pr.CI != "" {
+			state += ", " + tr(nil, "report_ci", pr.CI)
+		}
+		fmt.Fprintf(&b, "| [#%d %s](%s) | %s | %s | %s | %s |\n",
+			pr.Number, cell.Replace(pr.Title), pr.URL, pr.Repo, formatAge(pr.Age), assignee, state)
+	}
+	return b.String()
+}
+
+// githubRequest sends JSON encoded payload to github API with given method
+// and decodes the response into v, unless it is nil.
+func githubRequest(method, url string, payload, v interface{}) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return fmt.Errorf("cannot create %s request: %s", method, err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &responseError{Status: resp.StatusCode}
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("cannot decode response: %s", err)
+	}
+	return nil
+}
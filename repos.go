@@ -0,0 +1,81 @@
+package main
+
+import "strings"
+
+// Repository is the repository metadata github includes with every issue of
+// the organization issues feed.
+type Repository struct {
+	Name     string `json:"name"`
+	Archived bool   `json:"archived"`
+	Fork     bool   `json:"fork"`
+	// DefaultBranch is set in repository listings only.
+	DefaultBranch string `json:"default_branch"`
+	// Visibility is one of public, private or internal.
+	Visibility string   `json:"visibility"`
+	Private    bool     `json:"private"`
+	Topics     []string `json:"topics"`
+	// Owner is set in repository listings only.
+	Owner *User `json:"owner"`
+}
+
+// skipRepository returns true if issues of given repository should not be
+// processed. Issues without repository metadata are never skipped.
+func skipRepository(repo *Repository) bool {
+	if repo == nil {
+		return false
+	}
+	if *skipArchivedFl && repo.Archived {
+		return true
+	}
+	if *skipForksFl && repo.Fork {
+		return true
+	}
+	if !visibilityMatches(repo) {
+		return true
+	}
+	return !topicsMatch(repo)
+}
+
+// visibilityMatches returns true if visibility of given repository matches
+// -visibility. Internal repositories count as private.
+func visibilityMatches(repo *Repository) bool {
+	switch *visibilityFl {
+	case "public":
+		return !repo.Private
+	case "private":
+		return repo.Private
+	}
+	return true
+}
+
+// topicsMatch returns true if given repository has at least one of
+// -topics, or no topics are required.
+func topicsMatch(repo *Repository) bool {
+	required := false
+	for _, topic := range strings.Split(*topicsFl, ",") {
+		topic = strings.TrimSpace(topic)
+		if topic == "" {
+			continue
+		}
+		required = true
+		for _, t := range repo.Topics {
+			if strings.EqualFold(t, topic) {
+				return true
+			}
+		}
+	}
+	return !required
+}
+
+// filterRepositories returns given issues without those of skipped
+// repositories.
+func filterRepositories(issues []Issue) []Issue {
+	filtered := issues[:0]
+	for _, issue := range issues {
+		if skipRepository(issue.Repository) {
+			continue
+		}
+		filtered = append(filtered, issue)
+	}
+	return filtered
+}
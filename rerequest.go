@@ -0,0 +1,111 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// rerequestReviews requests review again from members the bot made
+// responsible for given pull requests, when a pull request got new commits
+// after their change request. Authors often push fixes without asking for
+// another review, and nobody notices.
+func rerequestReviews(issues []Issue, now time.Time) {
+	if !*rerequestReviewFl || githubOnly() != nil {
+		return
+	}
+	entries, err := loadAudit()
+	if err != nil {
+		log.Printf("cannot load audit log: %s", err)
+		return
+	}
+	responsible := map[string]map[string]bool{}
+	for _, e := range entries {
+		if !e.assignment() {
+			continue
+		}
+		if responsible[e.Key] == nil {
+			responsible[e.Key] = map[string]bool{}
+		}
+		responsible[e.Key][e.Login] = true
+	}
+	for i := range issues {
+		issue := &issues[i]
+		if !issue.isPullRequest() || optedOut(issue) || ignored(issue) {
+			continue
+		}
+		if logins := responsible[issueKey(issue)]; len(logins) > 0 {
+			rerequestReview(issue, logins)
+		}
+	}
+}
+
+// rerequestReview requests review of given pull request again from those
+// of given reviewers whose latest review requested changes of an older
+// commit, once per commit.
+func rerequestReview(issue *Issue, logins map[string]bool) {
+	pull, err := getPull(issue)
+	if err != nil {
+		log.Printf("cannot get pull request #%d: %s", issue.Number, err)
+		return
+	}
+	reviews, err := pullReviews(issue)
+	if err != nil {
+		log.Printf("cannot list reviews of #%d: %s", issue.Number, err)
+		return
+	}
+	latest := map[string]pullReview{}
+	for _, r := range reviews {
+		// comments do not change requested changes
+		if r.User == nil || r.State == "COMMENTED" || r.State == "PENDING" {
+			continue
+		}
+		latest[r.User.Login] = r
+	}
+	requested := map[string]bool{}
+	for _, u := range pull.RequestedReviewers {
+		requested[u.Login] = true
+	}
+
+	key := issueKey(issue)
+	st := getState(key)
+	var reviewers []string
+	for login := range logins {
+		reviewers = append(reviewers, login)
+	}
+	sort.Strings(reviewers)
+	for _, login := range reviewers {
+		r, ok := latest[login]
+		if !ok || r.State != "CHANGES_REQUESTED" || r.CommitID == pull.Head.SHA || requested[login] {
+			continue
+		}
+		if st.Rerequested[login] == pull.Head.SHA {
+			continue
+		}
+		user := User{Login: login}
+		if err := forge.RequestReview(issue, &user); err != nil {
+			log.Printf("cannot request review of #%d from %q again: %s", issue.Number, login, err)
+			continue
+		}
+		log.Printf("Requested review of PR #%d from %s again", issue.Number, login)
+		err := updateState(key, func(s *PRState) {
+			if s.Rerequested == nil {
+				s.Rerequested = map[string]string{}
+			}
+			s.Rerequested[login] = pull.Head.SHA
+		})
+		if err != nil {
+			log.Printf("cannot update state of %s: %s", key, err)
+		}
+		if !*rerequestNotifyFl || !notificationsEnabled() {
+			continue
+		}
+		msg := slackMessage{
+			Text:  tr(issue, "rerequested", userMention(login), issue.HTMLURL, issue.Number, issue.Title),
+			Issue: issue,
+		}
+		if err := notify(msg); err != nil {
+			log.Printf("cannot write slack notification: %s", err)
+		}
+	}
+}
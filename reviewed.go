@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	reviewedMu    sync.Mutex
+	reviewedCache = map[int64][]string{}
+)
+
+// pullReview is a review submitted to a pull request.
+type pullReview struct {
+	User  *User  `json:"user"`
+	State string `json:"state"`
+	// CommitID is the SHA of the commit the review was submitted for.
+	CommitID    string    `json:"commit_id"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// pullReviews returns reviews of given pull request, oldest first.
+func pullReviews(issue *Issue) ([]pullReview, error) {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var reviews []pullReview
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100", *ghAPIFl, *ghOrgFl, repo, issue.Number)
+	if err := githubGetAll(url, &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// reviewedSinceLastCommit returns sorted logins of reviewers who requested
+// changes to or commented on the latest commit of given pull request and
+// were not requested to review it again since. Such pull requests wait for
+// their author. Approvals and reviews of bots do not count.
+func reviewedSinceLastCommit(issue *Issue) ([]string, error) {
+	reviewedMu.Lock()
+	logins, ok := reviewedCache[issue.ID]
+	reviewedMu.Unlock()
+	if ok {
+		return logins, nil
+	}
+	pull, err := getPull(issue)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := pullReviews(issue)
+	if err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{issue.User.Login: true}
+	for _, u := range pull.RequestedReviewers {
+		skip[u.Login] = true
+	}
+	// Only the latest review of every reviewer counts, so that approving
+	// after commenting hands the pull request back to other reviewers.
+	latest := map[string]string{}
+	for _, r := range reviews {
+		if r.User == nil || skip[r.User.Login] || r.CommitID != pull.Head.SHA {
+			continue
+		}
+		if isBot(r.User.Login) || r.User.Type == "Bot" {
+			continue
+		}
+		if r.State == "PENDING" || r.State == "DISMISSED" {
+			continue
+		}
+		latest[r.User.Login] = r.State
+	}
+	logins = []string{}
+	for login, state := range latest {
+		if state == "CHANGES_REQUESTED" || state == "COMMENTED" {
+			logins = append(logins, login)
+		}
+	}
+	sort.Strings(logins)
+
+	reviewedMu.Lock()
+	reviewedCache[issue.ID] = logins
+	reviewedMu.Unlock()
+	return logins, nil
+}
+
+// awaitingAuthor returns reviewers of given pull request if it was reviewed
+// since the last commit and -skip-reviewed is set, nil otherwise.
+func awaitingAuthor(issue *Issue) []string {
+	if !*skipReviewedFl || githubOnly() != nil {
+		return nil
+	}
+	logins, err := reviewedSinceLastCommit(issue)
+	if err != nil {
+		log.Printf("cannot check reviews of #%d: %s", issue.Number, err)
+		return nil
+	}
+	return logins
+}
+
+// remindAuthorOfReview reminds author of given pull request, reviewed by
+// given reviewers since the last commit, instead of its assignee. The
+// reminder follows the same schedule as reminders of assignees.
+func remindAuthorOfReview(issue *Issue, reviewers []string, now time.Time) {
+	if !notificationsEnabled() || !featureEnabled(issue, "remind", true) {
+		return
+	}
+	if staleSince(issue).Add(policyFor(issue).Old).After(now) {
+		return
+	}
+	key := issueKey(issue)
+	st := getState(key)
+	author := issue.User.Login
+	loc := memberLocation(author)
+	if st.quiet(now) || !st.notificationDue(now) || quietAt(now, loc) || !scheduledDue(st.NotifiedAt, now, loc) {
+		return
+	}
+	mentions := make([]string, len(reviewers))
+	for i, login := range reviewers {
+		mentions[i] = "@" + login
+	}
+	log.Printf("Reminding author %s of reviewed PR #%d", author, issue.Number)
+	msg := slackMessage{
+		Text:  tr(issue, "reviewed", userMention(author), issue.HTMLURL, issue.Number, issue.Title, strings.Join(mentions, ", ")),
+		Issue: issue,
+	}
+	if *slackTokenFl != "" {
+		msg.Key = key
+	}
+	if err := notify(msg); err != nil {
+		log.Printf("cannot write slack notification: %s", err)
+		countStat(&stats.Errors)
+		return
+	}
+	countStat(&stats.Reminded)
+	err := updateState(key, func(s *PRState) {
+		s.NotifiedAt = now
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+}
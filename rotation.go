@@ -0,0 +1,296 @@
+package main
+
+import (
+	"container/ring"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// rotationState is the persisted state of a round robin rotation.
+type rotationState struct {
+	// Order are logins of the members in rotation order.
+	Order []string `json:"order"`
+	// Last is the login of the member picked last.
+	Last string `json:"last"`
+	// Credits accumulate relative capacities of members with reduced
+	// capacity, who are picked once their credit reaches one.
+	Credits map[string]float64 `json:"credits,omitempty"`
+}
+
+var (
+	rotationsMu sync.Mutex
+	// rotations are the rings of all rotations, keyed by scoped name.
+	rotations = map[string]*ring.Ring{}
+)
+
+// rotationKey returns state store key of rotation with given name.
+func rotationKey(name string) string {
+	return "rotation/" + name
+}
+
+// scopedRotation returns name of given rotation unique among tenants, so
+// that rotations of different teams do not share rings.
+func scopedRotation(name string) string {
+	return tenantScope() + name
+}
+
+// rotationFor returns name of the rotation team members are assigned to
+// given pull request from. Repository policies with rotation set have their
+// own rotation, so that busy repositories do not perturb the others.
+func rotationFor(issue *Issue) string {
+	if p := issuePolicy(issue); p != nil && p.Rotation != "" {
+		return "members/" + p.Rotation
+	}
+	return "members"
+}
+
+// loadRotation returns persisted state of rotation with given name.
+func loadRotation(name string) (rotationState, error) {
+	var s rotationState
+	b, err := store.Get(rotationKey(name))
+	if err != nil {
+		return s, fmt.Errorf("cannot read %s: %s", rotationKey(name), err)
+	}
+	if b == nil {
+		return s, nil
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		// rotations used to keep the last picked login only
+		if json.Unmarshal(b, &s.Last) != nil {
+			return s, fmt.Errorf("cannot decode %s: %s", rotationKey(name), err)
+		}
+	}
+	return s, nil
+}
+
+// saveRotation saves given state, with order of given ring, as state of
+// rotation with given name.
+func saveRotation(name string, r *ring.Ring, s rotationState) {
+	s.Order = nil
+	r.Do(func(v interface{}) {
+		s.Order = append(s.Order, v.(*User).Login)
+	})
+	if err := saveDocument(rotationKey(name), s); err != nil {
+		log.Printf("cannot save rotation: %s", err)
+	}
+}
+
+// newRing returns ring of given members in the persisted order of given
+// rotation state, members not in it joining at the end. Without persisted
+// order, the ring starts at a random member, to not always start from the
+// same place.
+func newRing(members []User, s rotationState) *ring.Ring {
+	position := map[string]int{}
+	for i, login := range s.Order {
+		position[login] = i
+	}
+	ordered := make([]*User, len(members))
+	for i := range members {
+		ordered[i] = &members[i]
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		pi, iok := position[ordered[i].Login]
+		pj, jok := position[ordered[j].Login]
+		if iok && jok {
+			return pi < pj
+		}
+		return iok && !jok
+	})
+	r := ring.New(len(ordered))
+	for _, m := range ordered {
+		r.Value = m
+		r = r.Next()
+	}
+	if len(s.Order) == 0 {
+		skip, _ := rand.Int(rand.Reader, big.NewInt(int64(len(ordered))))
+		for i := int64(0); i < skip.Int64(); i++ {
+			r = r.Next()
+		}
+	}
+	return r
+}
+
+// seekRotation returns given ring positioned after the member picked last,
+// according to given persisted state. This way rotation continues after
+// restarts and on other instances.
+func seekRotation(r *ring.Ring, s rotationState) *ring.Ring {
+	if s.Last == "" {
+		return r
+	}
+	for i := 0; i < r.Len(); i++ {
+		if r.Prev().Value.(*User).Login == s.Last {
+			return r
+		}
+		r = r.Next()
+	}
+	return r
+}
+
+// rotate returns the next member of rotation with given name, made of given
+// members, that is accepted by given function. Because assigning randomly
+// may not always produce best result, members are picked in round robin of
+// random order. Given function is called without holding rotationsMu, as
+// it may query the API.
+func rotate(name string, members []User, accept func(login string) bool) (User, error) {
+	if len(members) == 0 {
+		return User{}, errors.New("no members")
+	}
+	rejected := map[string]bool{}
+	for {
+		member, ok := nextCandidate(name, members, rejected)
+		if !ok {
+			return User{}, errors.New("no member available")
+		}
+		if !accept(member.Login) {
+			rejected[member.Login] = true
+			continue
+		}
+		chargeCredit(name, members, member.Login)
+		return member, nil
+	}
+}
+
+// nextCandidate advances rotation with given name, made of given members,
+// to the next member not rejected yet and returns it. Members with reduced
+// capacity are passed several times before their credit allows picking
+// them.
+func nextCandidate(name string, members []User, rejected map[string]bool) (User, bool) {
+	rotationsMu.Lock()
+	defer rotationsMu.Unlock()
+
+	s, err := loadRotation(name)
+	if err != nil {
+		log.Printf("cannot load rotation: %s", err)
+	}
+	scoped := scopedRotation(name)
+	r := rotations[scoped]
+	if r != nil && !ringMatches(r, members) {
+		r = updateRing(r, members)
+	}
+	if r == nil {
+		r = newRing(members, s)
+	}
+	defer func() { rotations[scoped] = r }()
+
+	r = seekRotation(r, s)
+	defer func() { saveRotation(name, r, s) }()
+	if s.Credits == nil {
+		s.Credits = map[string]float64{}
+	}
+	weights := relativeCapacities(members)
+	for len(rejected) < r.Len() {
+		member := r.Value.(*User)
+		r = r.Next()
+		s.Last = member.Login
+		if rejected[member.Login] {
+			continue
+		}
+		if w := weights[member.Login]; w < 1 && s.Credits[member.Login]+w < 1 {
+			s.Credits[member.Login] += w
+			continue
+		}
+		return *member, true
+	}
+	return User{}, false
+}
+
+// chargeCredit deducts picking of given member from the credit of rotation
+// with given name, made of given members, if the member has reduced
+// capacity.
+func chargeCredit(name string, members []User, login string) {
+	w := relativeCapacities(members)[login]
+	if w >= 1 {
+		return
+	}
+	rotationsMu.Lock()
+	defer rotationsMu.Unlock()
+
+	s, err := loadRotation(name)
+	if err != nil {
+		log.Printf("cannot load rotation: %s", err)
+		return
+	}
+	if s.Credits == nil {
+		s.Credits = map[string]float64{}
+	}
+	s.Credits[login] += w - 1
+	if err := saveDocument(rotationKey(name), s); err != nil {
+		log.Printf("cannot save rotation: %s", err)
+	}
+}
+
+// relativeCapacities returns capacities of given members relative to the
+// highest capacity among them, or to full capacity if nobody has more.
+func relativeCapacities(members []User) map[string]float64 {
+	highest := 1.0
+	for _, m := range members {
+		if c := memberCapacity(m.Login); c > highest {
+			highest = c
+		}
+	}
+	weights := map[string]float64{}
+	for _, m := range members {
+		// bounds the number of passes before picking anybody
+		weights[m.Login] = math.Max(memberCapacity(m.Login)/highest, 0.01)
+	}
+	return weights
+}
+
+// rotationNames returns names of all rotations used with the current
+// configuration.
+func rotationNames() []string {
+	names := []string{"members"}
+	seen := map[string]bool{}
+	for _, p := range config.Policies {
+		if p.Rotation != "" && !seen[p.Rotation] {
+			seen[p.Rotation] = true
+			names = append(names, "members/"+p.Rotation)
+		}
+	}
+	if *includeIssuesFl {
+		names = append(names, "issues")
+	}
+	if config.External != nil && len(config.External.Triagers) > 0 {
+		names = append(names, "triagers")
+	}
+	return names
+}
+
+// runState runs the state subcommand, printing persisted rotations, the
+// next member to be picked first.
+func runState(w io.Writer, args []string) error {
+	if len(args) > 0 && args[0] != "rotations" {
+		return fmt.Errorf("unknown state command %q, expected rotations", args[0])
+	}
+	for _, name := range rotationNames() {
+		s, err := loadRotation(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s:", name)
+		if len(s.Order) == 0 {
+			fmt.Fprintf(w, " no state, last picked %q\n", s.Last)
+			continue
+		}
+		// print the ring starting after the member picked last
+		start := 0
+		for i, login := range s.Order {
+			if login == s.Last {
+				start = i + 1
+			}
+		}
+		for i := range s.Order {
+			fmt.Fprintf(w, " %s", s.Order[(start+i)%len(s.Order)])
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRotate(t *testing.T) {
+	defer func(s Store) { store = s }(store)
+	store = newMemoryStore()
+	members := []User{{Login: "alice"}, {Login: "bob"}, {Login: "carol"}}
+	all := func(string) bool { return true }
+
+	// every member is picked once before anybody is picked again
+	picked := map[string]bool{}
+	var first string
+	for i := 0; i < len(members); i++ {
+		u, err := rotate("test/round-robin", members, all)
+		if err != nil {
+			t.Fatalf("rotate() error = %v", err)
+		}
+		if picked[u.Login] {
+			t.Fatalf("rotate() picked %s twice in one round", u.Login)
+		}
+		picked[u.Login] = true
+		if i == 0 {
+			first = u.Login
+		}
+	}
+	if u, _ := rotate("test/round-robin", members, all); u.Login != first {
+		t.Errorf("rotate() started next round with %s, want %s", u.Login, first)
+	}
+
+	tests := []struct {
+		name    string
+		members []User
+		accept  func(string) bool
+		want    string
+		err     bool
+	}{
+		{name: "test/no-members", accept: all, err: true},
+		{name: "test/nobody-accepted", members: members, accept: func(string) bool { return false }, err: true},
+		{name: "test/one-accepted", members: members, accept: func(login string) bool { return login == "bob" }, want: "bob"},
+	}
+	for _, tt := range tests {
+		u, err := rotate(tt.name, tt.members, tt.accept)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: rotate() error = %v, want error %v", tt.name, err, tt.err)
+			continue
+		}
+		if u.Login != tt.want {
+			t.Errorf("%s: rotate() = %s, want %s", tt.name, u.Login, tt.want)
+		}
+	}
+}
+
+func TestRotateAcceptUnlocked(t *testing.T) {
+	defer func(s Store) { store = s }(store)
+	store = newMemoryStore()
+	members := []User{{Login: "alice"}, {Login: "bob"}}
+
+	// accepting may query the API, so other rotations must not wait for it
+	accept := func(login string) bool {
+		if !rotationsMu.TryLock() {
+			t.Errorf("accept(%s) called holding rotationsMu", login)
+			return true
+		}
+		rotationsMu.Unlock()
+		return login == "bob"
+	}
+	if u, err := rotate("test/unlocked", members, accept); err != nil || u.Login != "bob" {
+		t.Errorf("rotate() = %s, %v, want bob", u.Login, err)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// policyScript is the parsed -policy-script template, nil if not set. It
+// is parsed once at startup.
+var policyScript *template.Template
+
+var (
+	scriptMu sync.Mutex
+	// scriptCache keeps decisions of the policy script by pull request ID
+	// during a scan, so that it is not executed for every candidate.
+	scriptCache = map[int64]scriptDecision{}
+)
+
+// scriptPR is the pull request passed to the policy script.
+type scriptPR struct {
+	Number   int64
+	Repo     string
+	Title    string
+	Body     string
+	Author   string
+	Assignee string
+	Labels   []string
+	// Age is the time since the pull request was created.
+	Age time.Duration
+}
+
+// HasLabel returns true if the pull request has label with given name.
+func (pr scriptPR) HasLabel(name string) bool {
+	for _, l := range pr.Labels {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptDecision is the outcome of the policy script for a pull request.
+type scriptDecision struct {
+	// Skip is true if the bot should ignore the pull request.
+	Skip bool
+	// SLA overrides thresholds of all other SLA.
+	SLA SLA
+	// Exclude are logins that must not be assigned.
+	Exclude map[string]bool
+	// Only are the logins that can be assigned, any member if empty.
+	Only map[string]bool
+}
+
+// loadScript parses the -policy-script template.
+func loadScript() error {
+	if *policyScriptFl == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(*policyScriptFl)
+	if err != nil {
+		return fmt.Errorf("cannot read file: %s", err)
+	}
+	t, err := template.New("policy").Funcs(template.FuncMap{
+		"hasPrefix": strings.HasPrefix,
+		"hasSuffix": strings.HasSuffix,
+		"contains":  strings.Contains,
+		"lower":     strings.ToLower,
+	}).Parse(string(b))
+	if err != nil {
+		return fmt.Errorf("cannot parse policy script: %s", err)
+	}
+	policyScript = t
+	return nil
+}
+
+// runScript evaluates the policy script for given pull request, once per
+// scan. Without the script, or when it fails, empty decision is returned.
+func runScript(issue *Issue) scriptDecision {
+	d := scriptDecision{Exclude: map[string]bool{}, Only: map[string]bool{}}
+	if policyScript == nil {
+		return d
+	}
+	scriptMu.Lock()
+	cached, ok := scriptCache[issue.ID]
+	scriptMu.Unlock()
+	if ok {
+		return cached
+	}
+	defer func() {
+		scriptMu.Lock()
+		scriptCache[issue.ID] = d
+		scriptMu.Unlock()
+	}()
+	pr := scriptPR{
+		Number: issue.Number,
+		Title:  issue.Title,
+		Body:   issue.Body,
+		Age:    time.Since(issue.CreatedAt),
+	}
+	pr.Repo, _ = issue.GetRepository()
+	if issue.User != nil {
+		pr.Author = issue.User.Login
+	}
+	if issue.Assignee != nil {
+		pr.Assignee = issue.Assignee.Login
+	}
+	for _, l := range issue.Labels {
+		pr.Labels = append(pr.Labels, l.Name)
+	}
+
+	var out strings.Builder
+	if err := policyScript.Execute(&out, pr); err != nil {
+		log.Printf("policy script failed for #%d: %s", issue.Number, err)
+		return d
+	}
+	for _, word := range strings.Fields(out.String()) {
+		if err := d.parse(word); err != nil {
+			log.Printf("policy script returned invalid decision for #%d: %s", issue.Number, err)
+		}
+	}
+	return d
+}
+
+// parse applies single decision word, for example "skip", "stale=4h" or
+// "exclude=alice", to the decision.
+func (d *scriptDecision) parse(word string) error {
+	if word == "skip" {
+		d.Skip = true
+		return nil
+	}
+	i := strings.Index(word, "=")
+	if i < 0 {
+		return fmt.Errorf("unknown decision %q", word)
+	}
+	name, value := word[:i], word[i+1:]
+	switch name {
+	case "exclude":
+		d.Exclude[value] = true
+		return nil
+	case "only":
+		d.Only[value] = true
+		return nil
+	case "stale", "old", "escalate":
+	default:
+		return fmt.Errorf("unknown decision %q", word)
+	}
+	v, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", name, err)
+	}
+	switch name {
+	case "stale":
+		d.SLA.Stale = Duration(v)
+	case "old":
+		d.SLA.Old = Duration(v)
+	case "escalate":
+		d.SLA.Escalate = Duration(v)
+	}
+	return nil
+}
+
+// scriptAllows returns true if the policy script allows assigning member
+// with given login to given pull request.
+func scriptAllows(issue *Issue, login string) bool {
+	d := runScript(issue)
+	if d.Exclude[login] {
+		return false
+	}
+	return len(d.Only) == 0 || d.Only[login]
+}
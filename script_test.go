@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"text/template"
+)
+
+func TestScriptAllows(t *testing.T) {
+	defer func(s *template.Template) { policyScript = s }(policyScript)
+	defer func() { scriptCache = map[int64]scriptDecision{} }()
+	runs := 0
+	policyScript = template.Must(template.New("policy").Funcs(template.FuncMap{
+		"count": func() string { runs++; return "" },
+	}).Parse(`{{count}}{{if eq .Repo "payments"}}exclude=intern{{end}}`))
+	scriptCache = map[int64]scriptDecision{}
+
+	issue := &Issue{ID: 1, Number: 1, Repo: "payments"}
+	tests := []struct {
+		login string
+		want  bool
+	}{
+		{login: "alice", want: true},
+		{login: "intern", want: false},
+		{login: "bob", want: true},
+	}
+	for _, tt := range tests {
+		if got := scriptAllows(issue, tt.login); got != tt.want {
+			t.Errorf("scriptAllows(%s) = %v, want %v", tt.login, got, tt.want)
+		}
+	}
+	// candidates of the same pull request reuse the decision
+	if runs != 1 {
+		t.Errorf("policy script executed %d times, want 1", runs)
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// secretFlags lists flags holding secrets that can be given as references
+// to a secret backend.
+var secretFlags = []string{
+	"auth-key",
+	"pass",
+	"slack-url",
+	"slack-token",
+	"slack-signing-secret",
+	"discord-webhook-url",
+	"mattermost-url",
+	"mattermost-token",
+	"telegram-token",
+	"google-chat-webhook-url",
+	"github-webhook-secret",
+	"gitlab-token",
+	"gitea-token",
+	"bitbucket-token",
+	"jira-token",
+	"linear-api-key",
+	"pagerduty-routing-key",
+	"opsgenie-api-key",
+	"webhook-secret",
+	"api-token",
+	"otlp-headers",
+}
+
+var (
+	// secretRefs maps flag names to secret references they were given.
+	secretRefs = map[string]string{}
+	// secretsResolvedAt is the time secrets were resolved last time.
+	secretsResolvedAt time.Time
+)
+
+// resolveSecret returns value of the secret reference. References have
+// "<backend>:<name>[#<key>]" format, where key selects a field of secrets
+// holding JSON objects. Values that are not references are returned as is.
+func resolveSecret(ref string) (string, bool, error) {
+	i := strings.Index(ref, ":")
+	if i < 0 {
+		return ref, false, nil
+	}
+	backend, name := ref[:i], ref[i+1:]
+	key := ""
+	if j := strings.LastIndex(name, "#"); j >= 0 {
+		name, key = name[:j], name[j+1:]
+	}
+
+	var fields map[string]interface{}
+	var raw string
+	var err error
+	switch backend {
+	case "vault":
+		fields, err = vaultSecret(name)
+	case "aws-sm":
+		raw, err = awsSecret(name)
+	case "gcp-sm":
+		raw, err = gcpSecret(name)
+	default:
+		// not a reference, for example slack URL
+		return ref, false, nil
+	}
+	if err != nil {
+		return "", true, fmt.Errorf("cannot read %s secret %q: %s", backend, name, err)
+	}
+
+	if fields == nil {
+		if key == "" {
+			return raw, true, nil
+		}
+		if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+			return "", true, fmt.Errorf("%s secret %q is not a JSON object: %s", backend, name, err)
+		}
+	}
+	if key == "" {
+		return "", true, fmt.Errorf("%s secret %q requires a #key", backend, name)
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", true, fmt.Errorf("%s secret %q has no %q string field", backend, name, key)
+	}
+	return value, true, nil
+}
+
+// resolveSecrets replaces secret references given to secret flags with
+// secret values. References are remembered, so that secrets can be
+// refreshed.
+func resolveSecrets() error {
+	values, err := secretValues()
+	if err != nil {
+		return err
+	}
+	applySecrets(values)
+	return nil
+}
+
+// secretValues returns values of secrets given as references to secret
+// flags, by flag name. Flags that are not references are not included.
+func secretValues() (map[string]string, error) {
+	values := map[string]string{}
+	for _, name := range secretFlags {
+		ref, ok := secretRefs[name]
+		if !ok && !secretsResolvedAt.IsZero() {
+			// only references found on startup are refreshed
+			continue
+		}
+		if !ok {
+			ref = flag.Lookup(name).Value.String()
+		}
+		value, isRef, err := resolveSecret(ref)
+		if err != nil {
+			return nil, fmt.Errorf("-%s: %s", name, err)
+		}
+		if !isRef {
+			continue
+		}
+		secretRefs[name] = ref
+		values[name] = value
+	}
+	return values, nil
+}
+
+// applySecrets sets secret flags to given values.
+func applySecrets(values map[string]string) {
+	for name, value := range values {
+		f := flag.Lookup(name)
+		if value == f.Value.String() {
+			continue
+		}
+		if !secretsResolvedAt.IsZero() {
+			log.Printf("-%s secret rotated", name)
+		}
+		f.Value.Set(value)
+	}
+	secretsResolvedAt = time.Now()
+}
+
+// watchSecrets resolves secret references again every -secret-refresh, in
+// daemon mode. Secrets are read from their backends first and then set
+// between scans and background work of requests, which read the flags
+// without locking. Errors
+// are logged and the previous values are kept. It runs forever, so it
+// should be started in a goroutine.
+func watchSecrets() {
+	for range time.Tick(*secretRefreshFl) {
+		values, err := secretValues()
+		if err != nil {
+			log.Printf("cannot refresh secrets: %s", err)
+			continue
+		}
+		globalsMu.Lock()
+		scanMu.Lock()
+		applySecrets(values)
+		saveRequestAuth()
+		scanMu.Unlock()
+		globalsMu.Unlock()
+	}
+}
+
+// vaultSecret reads secret at given path using Vault HTTP API, configured by
+// VAULT_ADDR and VAULT_TOKEN environment variables. Both KV version 1 and 2
+// responses are supported, for version 2 path must contain "data/", for
+// example "secret/data/github".
+func vaultSecret(path string) (map[string]interface{}, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, errors.New("VAULT_ADDR not set")
+	}
+	req, err := http.NewRequest("GET", strings.TrimSuffix(addr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GET request: %s", err)
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %s", err)
+	}
+	// KV version 2 nests the secret and adds metadata
+	if nested, ok := result.Data["data"].(map[string]interface{}); ok {
+		if _, ok := result.Data["metadata"]; ok {
+			return nested, nil
+		}
+	}
+	return result.Data, nil
+}
+
+// awsSecret reads secret string with given name or ARN from AWS Secrets
+// Manager. Credentials and region are read from the standard AWS_*
+// environment variables.
+func awsSecret(name string) (string, error) {
+	region, accessKey, secretKey, err := awsCredentials()
+	if err != nil {
+		return "", err
+	}
+	body, _ := json.Marshal(map[string]string{"SecretId": name})
+	host := fmt.Sprintf("secretsmanager.%s.amazonaws.com", region)
+	req, err := http.NewRequest("POST", "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("cannot create POST request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signAWS(req, body, host, region, "secretsmanager", accessKey, secretKey, time.Now().UTC())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("unexpected response: %d, %s", resp.StatusCode, b)
+	}
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("cannot decode response: %s", err)
+	}
+	return result.SecretString, nil
+}
+
+// awsCredentials returns region and credentials from the standard AWS_*
+// environment variables.
+func awsCredentials() (region, accessKey, secretKey string, err error) {
+	region = os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if region == "" || accessKey == "" || secretKey == "" {
+		return "", "", "", errors.New("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return region, accessKey, secretKey, nil
+}
+
+// signAWS signs request using AWS Signature Version 4.
+func signAWS(req *http.Request, body []byte, host, region, service, accessKey, secretKey string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("Host", host)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	hash := func(b []byte) string {
+		h := sha256.Sum256(b)
+		return hex.EncodeToString(h[:])
+	}
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+
+	// content type, host and all X-Amz-* headers are signed, sorted
+	signed := []string{"host"}
+	for name := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			signed = append(signed, name)
+		}
+	}
+	sort.Strings(signed)
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = host
+		}
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", h, strings.TrimSpace(value))
+	}
+	signedHeaders := strings.Join(signed, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+	canonical := strings.Join([]string{
+		req.Method, path, query, canonicalHeaders.String(), signedHeaders, hash(body),
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hash([]byte(canonical))}, "\n")
+
+	key := mac([]byte("AWS4"+secretKey), date)
+	key = mac(key, region)
+	key = mac(key, service)
+	key = mac(key, "aws4_request")
+	signature := hex.EncodeToString(mac(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// gcpSecret reads secret version with given resource name, for example
+// "projects/p/secrets/s/versions/latest", from Google Secret Manager. The
+// access token is taken from GOOGLE_OAUTH_ACCESS_TOKEN environment variable
+// or from the metadata server when running on Google Cloud.
+func gcpSecret(name string) (string, error) {
+	token, err := gcpAccessToken()
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("GET", "https://secretmanager.googleapis.com/v1/"+name+":access", nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create GET request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var result struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("cannot decode response: %s", err)
+	}
+	b, err := base64.StdEncoding.DecodeString(result.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("cannot decode payload: %s", err)
+	}
+	return string(b), nil
+}
+
+// gcpAccessToken returns Google Cloud access token from
+// GOOGLE_OAUTH_ACCESS_TOKEN environment variable or from the metadata
+// server.
+func gcpAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	token, err := gcpMetadataToken()
+	if err != nil {
+		return "", fmt.Errorf("cannot get access token: %s", err)
+	}
+	return token, nil
+}
+
+// gcpMetadataToken returns access token of the default service account from
+// the Google Cloud metadata server.
+func gcpMetadataToken() (string, error) {
+	req, err := http.NewRequest("GET", "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create GET request: %s", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("cannot decode response: %s", err)
+	}
+	return result.AccessToken, nil
+}
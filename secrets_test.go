@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/github":
+			w.Write([]byte(`{"data": {"data": {"token": "kv2"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/slack":
+			w.Write([]byte(`{"data": {"url": "https://hooks.slack.com/services/T/B/X"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_ADDR", srv.URL+"/")
+	t.Setenv("VAULT_TOKEN", "root")
+
+	tests := []struct {
+		ref   string
+		want  string
+		isRef bool
+		err   bool
+	}{
+		{ref: "plain-token", want: "plain-token"},
+		// URLs are not references, although they contain a colon
+		{ref: "https://hooks.slack.com/services/T/B/X", want: "https://hooks.slack.com/services/T/B/X"},
+		{ref: "vault:secret/data/github#token", want: "kv2", isRef: true},
+		{ref: "vault:kv/slack#url", want: "https://hooks.slack.com/services/T/B/X", isRef: true},
+		{ref: "vault:kv/slack", isRef: true, err: true},
+		{ref: "vault:kv/slack#missing", isRef: true, err: true},
+		{ref: "vault:kv/unknown#url", isRef: true, err: true},
+	}
+	for _, tt := range tests {
+		got, isRef, err := resolveSecret(tt.ref)
+		if (err != nil) != tt.err || isRef != tt.isRef {
+			t.Errorf("resolveSecret(%q) = %v, %v, want reference %v, error %v", tt.ref, isRef, err, tt.isRef, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveSecret(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// newServer returns HTTP handler serving all daemon mode endpoints.
+func newServer() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slack/command", handleSlashCommand)
+	mux.HandleFunc("/slack/interactions", handleSlackInteraction)
+	mux.HandleFunc("/github/webhook", handleGithubWebhook)
+	mux.HandleFunc("/lifecycle", readHandler(lifecycleHandler))
+	mux.HandleFunc("/metrics", readHandler(metricsHandler))
+	mux.HandleFunc("/api/stale-prs", apiHandler("GET", handleAPIStalePRs))
+	mux.HandleFunc("/api/assignments", apiHandler("GET", handleAPIAssignments))
+	mux.HandleFunc("/api/scan", apiHandler("POST", handleAPIScan))
+	mux.HandleFunc("/", readHandler(dashboardHandler))
+
+	root := http.NewServeMux()
+	root.HandleFunc("/healthz", healthHandler("healthz", livenessChecks))
+	root.HandleFunc("/readyz", healthHandler("readyz", readinessChecks))
+	root.Handle("/", mux)
+	return root
+}
+
+// requestAuth holds credentials requests are verified with.
+type requestAuth struct {
+	slackSigningSecret  string
+	githubWebhookSecret string
+	apiToken            string
+	publicDashboard     bool
+}
+
+// daemonAuth holds requestAuth of the daemon's own team. Handlers read it
+// instead of the flags, which tenant scans swap, so that they can answer
+// without waiting for a tenant scan to finish.
+var daemonAuth atomic.Value
+
+// saveRequestAuth remembers credentials of the daemon's own team from the
+// flags. It must be called with the daemon's own flags in place, whenever
+// they may have changed.
+func saveRequestAuth() {
+	daemonAuth.Store(requestAuth{
+		slackSigningSecret:  *slackSigningSecretFl,
+		githubWebhookSecret: *githubWebhookSecretFl,
+		apiToken:            *apiTokenFl,
+		publicDashboard:     *publicDashboardFl,
+	})
+}
+
+// currentAuth returns credentials requests are verified with.
+func currentAuth() requestAuth {
+	a, _ := daemonAuth.Load().(requestAuth)
+	return a
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestsDoNotWaitForTenantScans(t *testing.T) {
+	defer func(token string) { *apiTokenFl = token; saveRequestAuth() }(*apiTokenFl)
+	*apiTokenFl = "secret"
+	saveRequestAuth()
+	// a tenant scan swaps flags while holding the lock
+	*apiTokenFl = "tenant"
+	globalsMu.Lock()
+	defer globalsMu.Unlock()
+
+	srv := newServer()
+	for _, path := range []string{"/api/stale-prs", "/api/assignments", "/lifecycle", "/metrics", "/"} {
+		done := make(chan int)
+		go func() {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			done <- w.Code
+		}()
+		select {
+		case code := <-done:
+			if code != http.StatusOK {
+				t.Errorf("GET %s = %d, want %d", path, code, http.StatusOK)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("GET %s waits for the tenant scan", path)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+)
+
+// shadowPool returns logins of members shadowing reviews of given pull
+// request to learn, from its policy or -shadow-pool.
+func shadowPool(issue *Issue) []string {
+	if p := issuePolicy(issue); p != nil && len(p.Shadows) > 0 {
+		return p.Shadows
+	}
+	var logins []string
+	for _, login := range strings.Split(*shadowPoolFl, ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			logins = append(logins, login)
+		}
+	}
+	return logins
+}
+
+// pickShadow returns member of the shadow pool from its own round robin
+// that shadows review of given pull request by given primary reviewer, nil
+// if there is no shadow pool or nobody in it can review the pull request.
+func pickShadow(issue *Issue, primary string) *User {
+	var pool []User
+	for _, login := range shadowPool(issue) {
+		pool = append(pool, User{Login: login})
+	}
+	if len(pool) == 0 {
+		return nil
+	}
+	user, err := rotate("shadows", pool, func(login string) bool {
+		return login != primary && canReview(issue, login)
+	})
+	if err != nil {
+		return nil
+	}
+	return &user
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// shutdownCh is closed when the bot starts shutting down.
+	shutdownCh = make(chan struct{})
+	// inflight are background goroutines started by HTTP handlers, that
+	// shutdown waits for.
+	inflight sync.WaitGroup
+)
+
+// errShuttingDown is returned by scans requested during shutdown.
+var errShuttingDown = errors.New("shutting down")
+
+// shuttingDown returns true once the bot received SIGINT or SIGTERM. No
+// new pull requests are handled from then on.
+func shuttingDown() bool {
+	select {
+	case <-shutdownCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// goTracked runs fn in a goroutine shutdown waits for. Fn runs with globals
+// of the daemon's own team, waiting for a running tenant scan to restore
+// them.
+func goTracked(fn func()) {
+	inflight.Add(1)
+	go func() {
+		defer inflight.Done()
+		globalsMu.RLock()
+		defer globalsMu.RUnlock()
+		fn()
+	}()
+}
+
+// sleepUnlessShutdown waits for given duration and returns true, or returns
+// false as soon as shutdown starts.
+func sleepUnlessShutdown(d time.Duration) bool {
+	select {
+	case <-shutdownCh:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// handleSignals starts shutdown on the first SIGINT or SIGTERM: calls drain
+// to finish work in flight and exits once it returns. With nil drain, the
+// caller exits on its own once its work is done. The bot exits with status
+// 1 if the work does not finish within -shutdown-timeout or on a second
+// signal.
+func handleSignals(drain func(ctx context.Context)) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("received %s, finishing work in flight", sig)
+		close(shutdownCh)
+
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeoutFl)
+		defer cancel()
+		done := make(chan struct{})
+		if drain != nil {
+			go func() {
+				drain(ctx)
+				close(done)
+			}()
+		}
+		select {
+		case <-done:
+			flushSpans()
+			log.Print("shut down")
+			os.Exit(0)
+		case <-ctx.Done():
+			log.Printf("work in flight did not finish within %s, exiting", *shutdownTimeoutFl)
+		case sig := <-signals:
+			log.Printf("received %s again, exiting", sig)
+		}
+		os.Exit(1)
+	}()
+}
+
+// runWorkers calls all jobs, at most -workers of them at the same time, and
+// returns once they are done. Once shutdown starts or the API call budget
+// is exhausted, workers finish the job they are running but do not take
+// further ones.
+func runWorkers(jobs []func()) {
+	queue := make(chan func())
+	var wg sync.WaitGroup
+	for i := 0; i < *workersFl && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				job()
+			}
+		}()
+	}
+	for i, job := range jobs {
+		if shuttingDown() {
+			break
+		}
+		if budget.exhausted() {
+			log.Printf("%s, skipping %d pull requests", errBudgetExhausted, len(jobs)-i)
+			break
+		}
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+}
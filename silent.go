@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// botActions are actions whose communication channels can be switched with
+// -comment and -notify.
+var botActions = []string{"assign", "remind", "escalate", "link"}
+
+var (
+	// commentDefaults are actions announced with a pull request comment
+	// by default.
+	commentDefaults = map[string]bool{"assign": true, "link": true}
+	// notifyDefaults are actions announced with a chat notification by
+	// default.
+	notifyDefaults = map[string]bool{"remind": true, "escalate": true}
+)
+
+// parseSwitches returns actions switched on by given -comment or -notify
+// value, "on" or "off" for all actions, or comma separated action=on|off
+// pairs overriding given defaults.
+func parseSwitches(value string, defaults map[string]bool) (map[string]bool, error) {
+	on := map[string]bool{}
+	for _, action := range botActions {
+		on[action] = defaults[action]
+	}
+	switch value {
+	case "on", "off":
+		for _, action := range botActions {
+			on[action] = value == "on"
+		}
+		return on, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if _, ok := on[parts[0]]; !ok {
+			return nil, fmt.Errorf("unknown action %q, expected one of: %s", parts[0], strings.Join(botActions, ", "))
+		}
+		if len(parts) != 2 || (parts[1] != "on" && parts[1] != "off") {
+			return nil, fmt.Errorf("invalid switch %q, expected action=on or action=off", pair)
+		}
+		on[parts[0]] = parts[1] == "on"
+	}
+	return on, nil
+}
+
+// commentOn returns true if given action on given pull request is announced
+// with a pull request comment.
+func commentOn(issue *Issue, action string) bool {
+	on, _ := parseSwitches(*commentFl, commentDefaults)
+	return featureEnabled(issue, action+"_comment", on[action])
+}
+
+// notifyOn returns true if given action on given pull request is announced
+// with a chat notification.
+func notifyOn(issue *Issue, action string) bool {
+	on, _ := parseSwitches(*notifyFl, notifyDefaults)
+	return notificationsEnabled() && featureEnabled(issue, action+"_notify", on[action])
+}
+
+// announced returns true if given action on given pull request is
+// announced on any channel.
+func announced(issue *Issue, action string) bool {
+	return commentOn(issue, action) || notifyOn(issue, action)
+}
+
+// remind reminds assignee of given pull request on the channels switched on
+// for reminders.
+func remind(issue *Issue) error {
+	if commentOn(issue, "remind") {
+		comment := tr(issue, "remind_comment", issue.Assignee.Login)
+		if nudge := descriptionNudge(issue); nudge != "" {
+			comment += "\n\n" + nudge
+		}
+		if err := forge.Comment(issue, comment); err != nil {
+			return fmt.Errorf("cannot comment on #%d pull request: %s", issue.Number, err)
+		}
+	}
+	if notifyOn(issue, "remind") {
+		return remindOnSlack(issue)
+	}
+	return nil
+}
+
+// notifyAssignment tells given member on chat about assignment of given
+// pull request.
+func notifyAssignment(issue *Issue, login string) {
+	msg := slackMessage{
+		Text:  tr(issue, "assigned", userMention(login), issue.HTMLURL, issue.Number, issue.Title),
+		Issue: issue,
+	}
+	if err := notify(msg); err != nil {
+		log.Printf("cannot write slack notification: %s", err)
+	}
+}
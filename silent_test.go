@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSwitches(t *testing.T) {
+	defaults := map[string]bool{"assign": true, "link": true}
+	tests := []struct {
+		in   string
+		want map[string]bool
+		err  bool
+	}{
+		{in: "", want: map[string]bool{"assign": true, "remind": false, "escalate": false, "link": true}},
+		{in: "on", want: map[string]bool{"assign": true, "remind": true, "escalate": true, "link": true}},
+		{in: "off", want: map[string]bool{"assign": false, "remind": false, "escalate": false, "link": false}},
+		{in: "assign=off, remind=on", want: map[string]bool{"assign": false, "remind": true, "escalate": false, "link": true}},
+		{in: "merge=on", err: true},
+		{in: "remind", err: true},
+		{in: "remind=yes", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSwitches(tt.in, defaults)
+		if (err != nil) != tt.err {
+			t.Errorf("parseSwitches(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSwitches(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
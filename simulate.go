@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historicalPR is a pull request replayed by the simulation.
+type historicalPR struct {
+	Issue Issue
+	// DoneAt is the time of the first review, or closing if it was closed
+	// without review, zero if neither happened.
+	DoneAt time.Time
+}
+
+// historicalPRs returns pull requests of the organization created since
+// given time, with the time of their first review.
+func historicalPRs(since time.Time) ([]historicalPR, error) {
+	if err := githubOnly(); err != nil {
+		return nil, err
+	}
+	if userAccount() {
+		return nil, errOrganizationRequired
+	}
+	var issues []Issue
+	url := fmt.Sprintf("%s/orgs/%s/issues?filter=all&state=all&per_page=100&since=%s",
+		*ghAPIFl, *ghOrgFl, since.UTC().Format(time.RFC3339))
+	err := githubGetPages(url, func(raw json.RawMessage) error {
+		var page []Issue
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		for _, issue := range page {
+			if issue.isPullRequest() && issue.CreatedAt.After(since) {
+				issues = append(issues, issue)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	issues = filterRepositories(issues)
+
+	prs := make([]historicalPR, 0, len(issues))
+	for _, issue := range issues {
+		pr := historicalPR{Issue: issue}
+		reviewed, err := firstReviewAt(&issue)
+		if err != nil {
+			log.Printf("cannot list reviews of #%d: %s", issue.Number, err)
+		}
+		pr.DoneAt = reviewed
+		if issue.ClosedAt != nil && (pr.DoneAt.IsZero() || issue.ClosedAt.Before(pr.DoneAt)) {
+			pr.DoneAt = *issue.ClosedAt
+		}
+		prs = append(prs, pr)
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].Issue.CreatedAt.Before(prs[j].Issue.CreatedAt)
+	})
+	return prs, nil
+}
+
+// firstReviewAt returns time of the first review of given pull request by
+// someone else than its author, zero if there is none.
+func firstReviewAt(issue *Issue) (time.Time, error) {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	var reviews []struct {
+		User        *User     `json:"user"`
+		SubmittedAt time.Time `json:"submitted_at"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100", *ghAPIFl, *ghOrgFl, repo, issue.Number)
+	if err := githubGetAll(url, &reviews); err != nil {
+		return time.Time{}, err
+	}
+	var first time.Time
+	for _, r := range reviews {
+		if r.User == nil || r.User.Login == issue.User.Login {
+			continue
+		}
+		if first.IsZero() || r.SubmittedAt.Before(first) {
+			first = r.SubmittedAt
+		}
+	}
+	return first, nil
+}
+
+// simulationResult summarizes a single simulation run.
+type simulationResult struct {
+	Stale    time.Duration
+	Strategy string
+	// StaleCount is the number of pull requests that would be assigned.
+	StaleCount int
+	// MeanWait is the mean time until a pull request was reviewed, or
+	// would be assigned, whichever happens first.
+	MeanWait time.Duration
+	// Counts maps logins to number of assignments.
+	Counts map[string]int
+	StdDev float64
+}
+
+// simulate replays given pull requests with given stale threshold and
+// strategy. Pull requests not reviewed within the threshold are assigned
+// as the bot would assign them, except that no state is changed.
+func simulate(prs []historicalPR, members []User, stale time.Duration, strategy string, now time.Time) simulationResult {
+	res := simulationResult{Stale: stale, Strategy: strategy, Counts: map[string]int{}}
+	for _, m := range members {
+		res.Counts[m.Login] = 0
+	}
+	next := 0
+	roundRobin := func(issue *Issue) string {
+		for i := 0; i < len(members); i++ {
+			m := members[(next+i)%len(members)]
+			if m.Login != issue.User.Login {
+				next = (next + i + 1) % len(members)
+				return m.Login
+			}
+		}
+		return ""
+	}
+
+	var wait time.Duration
+	for i := range prs {
+		pr := &prs[i]
+		staleAt := pr.Issue.CreatedAt.Add(stale)
+		done := pr.DoneAt
+		if done.IsZero() {
+			done = now
+		}
+		if done.Before(staleAt) {
+			wait += done.Sub(pr.Issue.CreatedAt)
+			continue
+		}
+		wait += stale
+		res.StaleCount++
+
+		var user User
+		var ok bool
+		var err error
+		switch strategy {
+		case "expertise":
+			user, ok, err = expertReviewer(&pr.Issue)
+		case "blame":
+			user, ok, err = blameReviewer(&pr.Issue)
+		}
+		if err != nil {
+			log.Printf("cannot simulate %s for #%d: %s", strategy, pr.Issue.Number, err)
+		}
+		login := user.Login
+		if !ok {
+			login = roundRobin(&pr.Issue)
+		}
+		if login != "" {
+			res.Counts[login]++
+		}
+	}
+	if len(prs) > 0 {
+		res.MeanWait = wait / time.Duration(len(prs))
+	}
+	if len(res.Counts) > 0 {
+		mean := float64(res.StaleCount) / float64(len(res.Counts))
+		variance := 0.0
+		for _, n := range res.Counts {
+			variance += (float64(n) - mean) * (float64(n) - mean)
+		}
+		res.StdDev = math.Sqrt(variance / float64(len(res.Counts)))
+	}
+	return res
+}
+
+// runSimulation replays pull requests of the last -simulate-window with
+// every combination of -simulate-stale thresholds and -simulate-strategies
+// and writes the results.
+func runSimulation(w io.Writer) error {
+	var thresholds []time.Duration
+	for _, s := range strings.Split(*simulateStaleFl, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid stale threshold %q: %s", s, err)
+		}
+		thresholds = append(thresholds, d)
+	}
+	var strategies []string
+	for _, s := range strings.Split(*simulateStrategiesFl, ",") {
+		s = strings.TrimSpace(s)
+		if s != "round-robin" && s != "expertise" && s != "blame" {
+			return fmt.Errorf("invalid strategy %q", s)
+		}
+		strategies = append(strategies, s)
+	}
+
+	now := time.Now()
+	prs, err := historicalPRs(now.Add(-*simulateWindowFl))
+	if err != nil {
+		return fmt.Errorf("cannot fetch pull requests: %s", err)
+	}
+	members, err := listMembers()
+	if err != nil {
+		return fmt.Errorf("cannot list members: %s", err)
+	}
+
+	fmt.Fprintf(w, "%d pull requests created in the last %s, %d members\n\n", len(prs), formatAge(*simulateWindowFl), len(members))
+	fmt.Fprintf(w, "%-10s %-12s %8s %12s %8s %12s\n", "stale", "strategy", "assigned", "mean wait", "stddev", "max")
+	for _, stale := range thresholds {
+		for _, strategy := range strategies {
+			res := simulate(prs, members, stale, strategy, now)
+			maxLogin, maxCount := "", 0
+			for login, n := range res.Counts {
+				if n > maxCount || (n == maxCount && login < maxLogin) {
+					maxLogin, maxCount = login, n
+				}
+			}
+			fmt.Fprintf(w, "%-10s %-12s %8d %12s %8.1f %12s\n", stale, strategy, res.StaleCount,
+				formatAge(res.MeanWait), res.StdDev, fmt.Sprintf("%s (%d)", maxLogin, maxCount))
+		}
+	}
+	return nil
+}
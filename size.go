@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Pull contains pull request details that are not part of the issue
+// representation.
+type Pull struct {
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+	ChangedFiles int `json:"changed_files"`
+	Head         struct {
+		SHA string `json:"sha"`
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	// Mergeable is nil while github computes it.
+	Mergeable *bool `json:"mergeable"`
+	// RequestedReviewers are users whose review is pending.
+	RequestedReviewers []User `json:"requested_reviewers"`
+}
+
+var (
+	pullsMu    sync.Mutex
+	pullsCache = map[int64]*Pull{}
+)
+
+// getPull return pull request details of given issue. Globally cached.
+func getPull(issue *Issue) (*Pull, error) {
+	if err := githubOnly(); err != nil {
+		return nil, err
+	}
+	pullsMu.Lock()
+	pull, ok := pullsCache[issue.ID]
+	pullsMu.Unlock()
+	if ok {
+		return pull, nil
+	}
+
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", *ghAPIFl, *ghOrgFl, repo, issue.Number)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch response: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	pull = &Pull{}
+	if err := json.NewDecoder(resp.Body).Decode(pull); err != nil {
+		return nil, fmt.Errorf("cannot decode response: %s", err)
+	}
+
+	pullsMu.Lock()
+	pullsCache[issue.ID] = pull
+	pullsMu.Unlock()
+	return pull, nil
+}
+
+// sizeClasses is the list of size classes and the maximum number of changed
+// lines for each of them. The last class has no limit.
+var sizeClasses = []struct {
+	Name     string
+	MaxLines int
+}{
+	{"XS", 9},
+	{"S", 49},
+	{"M", 249},
+	{"L", 999},
+	{"XL", -1},
+}
+
+// size returns size class of the pull request.
+func (p *Pull) size() string {
+	lines := p.Additions + p.Deletions
+	for _, c := range sizeClasses {
+		if c.MaxLines < 0 || lines <= c.MaxLines {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// sizeOf returns size class of given pull request.
+func sizeOf(issue *Issue) (string, error) {
+	pull, err := getPull(issue)
+	if err != nil {
+		return "", err
+	}
+	return pull.size(), nil
+}
+
+const sizeLabelPrefix = "size/"
+
+// applySizeLabel sets size label of given pull request, removing size labels
+// that are no longer valid.
+func applySizeLabel(issue *Issue) error {
+	if err := githubOnly(); err != nil {
+		return err
+	}
+	size, err := sizeOf(issue)
+	if err != nil {
+		return fmt.Errorf("cannot get size: %s", err)
+	}
+	repo, err := issue.GetRepository()
+	if err != nil {
+		return fmt.Errorf("cannot extract repo name from URL: %s", err)
+	}
+	want := sizeLabelPrefix + size
+	labelsURL := fmt.Sprintf("%s/repos/%s/%s/issues/%d/labels", *ghAPIFl, *ghOrgFl, repo, issue.Number)
+
+	hasLabel := false
+	for _, label := range issue.Labels {
+		if !strings.HasPrefix(label.Name, sizeLabelPrefix) {
+			continue
+		}
+		if label.Name == want {
+			hasLabel = true
+			continue
+		}
+		req, err := http.NewRequest("DELETE", labelsURL+"/"+url.PathEscape(label.Name), nil)
+		if err != nil {
+			return fmt.Errorf("cannot create DELETE request: %s", err)
+		}
+		addAuthentication(req)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("cannot do request: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+		}
+	}
+	if hasLabel {
+		return nil
+	}
+
+	var body bytes.Buffer
+	err = json.NewEncoder(&body).Encode(map[string]interface{}{
+		"labels": []string{want},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode body: %s", err)
+	}
+	req, err := http.NewRequest("POST", labelsURL, &body)
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	log.Printf("%s label added to #%d issue of %q", want, issue.Number, repo)
+	return nil
+}
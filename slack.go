@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const slackAPIURL = "https://slack.com/api/"
+
+// slackAPI calls given Slack Web API method with given JSON payload.
+func slackAPI(method string, payload interface{}) error {
+	return slackCall(method, payload, nil)
+}
+
+// slackCall calls Slack Web API method and decodes successful response into
+// v, unless it is nil.
+func slackCall(method string, payload interface{}, v interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot JSON encode data: %s", err)
+	}
+	req, err := http.NewRequest("POST", slackAPIURL+method, bytes.NewBuffer(b))
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+*slackTokenFl)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return &transientError{err: fmt.Errorf("cannot POST data: %s", err)}
+	}
+	defer resp.Body.Close()
+	if err := checkSlackResponse(resp); err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &transientError{err: fmt.Errorf("cannot read response: %s", err)}
+	}
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("cannot decode response: %s", err)
+	}
+	if !result.OK {
+		err := fmt.Errorf("%s failed: %s", method, result.Error)
+		if result.Error == "ratelimited" || result.Error == "internal_error" {
+			return &transientError{err: err}
+		}
+		return err
+	}
+	if v != nil {
+		if err := json.Unmarshal(body, v); err != nil {
+			return fmt.Errorf("cannot decode response: %s", err)
+		}
+	}
+	return nil
+}
+
+// directMessage sends given text to the Slack member with given ID in a
+// direct message, opening the conversation with them first.
+func directMessage(id, text string) error {
+	var conv struct {
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+	}
+	if err := slackCall("conversations.open", map[string]string{"users": id}, &conv); err != nil {
+		return fmt.Errorf("cannot open conversation with %s: %s", id, err)
+	}
+	return slackAPI("chat.postMessage", map[string]interface{}{
+		"channel": conv.Channel.ID,
+		"text":    text,
+	})
+}
+
+// postSlackMessage posts reminder about pull request with given key to the
+// configured channel using Slack Web API. Blocks are optional, text is used
+// for notifications then. Messages are paced and retried by deliverSlack.
+func postSlackMessage(text string, blocks []interface{}, key string) error {
+	return deliverSlack(slackMessage{Text: text, Blocks: blocks, Key: key})
+}
+
+// sendSlackMessage posts message to the configured channel using Slack Web
+// API. Messages about a pull request are remembered in its state, so that
+// they can be updated once it is resolved.
+func sendSlackMessage(m slackMessage) error {
+	text, blocks, key := m.Text, m.Blocks, m.Key
+	channel := *slackChannelFl
+	if p := messagePolicy(m); p != nil && p.SlackChannel != "" {
+		channel = p.SlackChannel
+	}
+	msg := map[string]interface{}{
+		"channel":    channel,
+		"text":       text,
+		"username":   "github-pr",
+		"icon_emoji": ":octocat:",
+	}
+	switch {
+	case m.Color != "":
+		// colored bar is only available for attachments
+		attachment := map[string]interface{}{"color": m.Color, "fallback": text}
+		if len(blocks) > 0 {
+			attachment["blocks"] = blocks
+		} else {
+			attachment["text"] = text
+		}
+		msg["attachments"] = []interface{}{attachment}
+	case len(blocks) > 0:
+		msg["blocks"] = blocks
+	}
+	var result struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if err := slackCall("chat.postMessage", msg, &result); err != nil {
+		return err
+	}
+	if key == "" {
+		return nil
+	}
+	err := updateState(key, func(s *PRState) {
+		s.Reminders = append(s.Reminders, SlackReminder{Channel: result.Channel, TS: result.TS, Text: text})
+	})
+	if err != nil {
+		log.Printf("cannot update state of %s: %s", key, err)
+	}
+	return nil
+}
+
+// reminderBlocks returns Block Kit blocks of the reminder message about
+// given pull request, with buttons handled by handleSlackInteraction.
+func reminderBlocks(issue *Issue, text string) []interface{} {
+	key := issueKey(issue)
+	button := func(actionID, label string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":      "button",
+			"action_id": actionID,
+			"value":     key,
+			"text": map[string]interface{}{
+				"type": "plain_text",
+				"text": label,
+			},
+		}
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{
+				"type": "mrkdwn",
+				"text": text,
+			},
+		},
+		map[string]interface{}{
+			"type": "actions",
+			"elements": []interface{}{
+				button("snooze", tr(issue, "button_snooze", formatAge(*snoozeFl))),
+				button("reassign", tr(issue, "button_reassign")),
+				button("ack", tr(issue, "button_ack")),
+			},
+		},
+	}
+}
+
+// slackInteraction is the payload sent by Slack when a button is clicked.
+type slackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	ResponseURL string `json:"response_url"`
+	Actions     []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// handleSlackInteraction serves clicks on buttons of reminder messages.
+func handleSlackInteraction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := verifySlackRequest(r)
+	if err != nil {
+		log.Printf("rejecting slack interaction: %s", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	var payload slackInteraction
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	// slack expects acknowledgement within 3 seconds, results are sent to
+	// the response URL
+	w.WriteHeader(http.StatusOK)
+
+	for _, action := range payload.Actions {
+		actionID, key := action.ActionID, action.Value
+		goTracked(func() {
+			var text string
+			var err error
+			if actionID == "delete_branch" {
+				text, err = deleteStaleBranch(key, payload.User.ID)
+			} else {
+				text, err = handleReminderAction(actionID, key, payload.User.Username)
+			}
+			if err != nil {
+				log.Printf("cannot handle %s of %s: %s", actionID, key, err)
+				text = fmt.Sprintf("Cannot handle %s of %s: %s", actionID, key, err)
+			}
+			replaceSlackMessage(payload.ResponseURL, text)
+		})
+	}
+}
+
+// handleReminderAction executes action of a reminder button clicked by
+// given Slack user and returns text replacing the reminder message.
+func handleReminderAction(actionID, key, username string) (string, error) {
+	now := time.Now()
+	switch actionID {
+	case "snooze":
+		until := now.Add(*snoozeFl)
+		err := updateState(key, func(s *PRState) {
+			s.SnoozedUntil = until
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s snoozed by %s until %s.", key, username, until.Format("Mon Jan 2 15:04")), nil
+	case "ack":
+		err := updateState(key, func(s *PRState) {
+			s.AcknowledgedAt = now
+			s.AcknowledgedBy = username
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s is being worked on by %s.", key, username), nil
+	case "reassign":
+		user, err := reassign(key)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s reassigned to @%s on request of %s.", key, user.Login, username), nil
+	}
+	return "", fmt.Errorf("unknown action %q", actionID)
+}
+
+// issueByKey fetches issue with given key.
+func issueByKey(key string) (*Issue, error) {
+	i := strings.LastIndex(key, "#")
+	if i < 0 {
+		return nil, errors.New("invalid key")
+	}
+	number, err := strconv.ParseInt(key[i+1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %s", err)
+	}
+	issue, err := forge.Issue(key[:i], number)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch issue: %s", err)
+	}
+	return issue, nil
+}
+
+// reassign assigns different member to the pull request with given key.
+func reassign(key string) (User, error) {
+	issue, err := issueByKey(key)
+	if err != nil {
+		return User{}, err
+	}
+	user, err := pickReviewer(issue)
+	if err != nil {
+		return User{}, fmt.Errorf("cannot pick user: %s", err)
+	}
+	if err := forge.Assign(issue, &user); err != nil {
+		releaseAssignment(user.Login)
+		return User{}, err
+	}
+	recordAssignment(key, user.Login, "reassigned", time.Now())
+	comment := tr(issue, "reassign_comment", user.Login)
+	if err := forge.Comment(issue, comment); err != nil {
+		log.Printf("cannot comment on %s: %s", key, err)
+	}
+	return user, nil
+}
+
+// replaceSlackMessage replaces message that contained clicked button.
+func replaceSlackMessage(responseURL, text string) {
+	b, err := json.Marshal(map[string]interface{}{
+		"replace_original": true,
+		"text":             text,
+	})
+	if err != nil {
+		log.Printf("cannot JSON encode slack response: %s", err)
+		return
+	}
+	resp, err := httpClient.Post(responseURL, "application/json", bytes.NewBuffer(b))
+	if err != nil {
+		log.Printf("cannot send slack response: %s", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("invalid slack response status: %d", resp.StatusCode)
+	}
+}
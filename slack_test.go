@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleSlackInteraction(t *testing.T) {
+	var replies []string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reply struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&reply)
+		replies = append(replies, reply.Text)
+	}))
+	defer slack.Close()
+	defer func(secret string, s Store) { *slackSigningSecretFl, store = secret, s; saveRequestAuth() }(*slackSigningSecretFl, store)
+	*slackSigningSecretFl, store = "signing", newMemoryStore()
+	saveRequestAuth()
+
+	post := func(actionID, signature string) int {
+		payload := fmt.Sprintf(`{"type": "block_actions", "user": {"id": "U1", "username": "bob"},
+			"response_url": %q, "actions": [{"action_id": %q, "value": "api#1"}]}`, slack.URL, actionID)
+		body := url.Values{"payload": {payload}}.Encode()
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte("signing"))
+			fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+			signature = "v0=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		req := httptest.NewRequest("POST", "/slack/interactions", strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", signature)
+		w := httptest.NewRecorder()
+		handleSlackInteraction(w, req)
+		inflight.Wait()
+		return w.Code
+	}
+
+	if code := post("snooze", "v0=forged"); code != http.StatusUnauthorized {
+		t.Errorf("forged interaction status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if s := getState("api#1"); !s.SnoozedUntil.IsZero() {
+		t.Errorf("forged interaction snoozed api#1 until %s", s.SnoozedUntil)
+	}
+
+	if code := post("snooze", ""); code != http.StatusOK {
+		t.Fatalf("snooze status = %d, want %d", code, http.StatusOK)
+	}
+	if s := getState("api#1"); !s.SnoozedUntil.After(time.Now()) {
+		t.Errorf("snooze left api#1 snoozed until %s", s.SnoozedUntil)
+	}
+	if code := post("ack", ""); code != http.StatusOK {
+		t.Fatalf("ack status = %d, want %d", code, http.StatusOK)
+	}
+	if s := getState("api#1"); s.AcknowledgedBy != "bob" {
+		t.Errorf("ack left api#1 acknowledged by %q, want bob", s.AcknowledgedBy)
+	}
+	// the clicked message is replaced with the outcome
+	if len(replies) != 2 || !strings.Contains(replies[0], "snoozed by bob") || !strings.Contains(replies[1], "worked on by bob") {
+		t.Errorf("replies = %q", replies)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// slackRetries is the number of times a message is retried after a
+// transient failure.
+const slackRetries = 4
+
+// slackMessage is a message waiting to be delivered to Slack.
+type slackMessage struct {
+	Text   string        `json:"text"`
+	Blocks []interface{} `json:"blocks,omitempty"`
+	// Key is the key of the pull request the message reminds about.
+	Key string `json:"key,omitempty"`
+	// Repo is the repository whose policy routes a message about several
+	// pull requests, like batched reminders.
+	Repo string `json:"repo,omitempty"`
+	// Color is the color of the attachment bar the message is shown with,
+	// none if empty.
+	Color string `json:"color,omitempty"`
+	// Issue is the pull request the message is about, used by notifiers
+	// of other chat services. It is not kept for undelivered messages.
+	Issue *Issue `json:"-"`
+}
+
+// transientError is an error after which the request can be retried.
+type transientError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+// checkSlackResponse returns transientError for rate limited and server
+// error responses.
+func checkSlackResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	err := &transientError{err: fmt.Errorf("invalid response: %d, %s", resp.StatusCode, body)}
+	if seconds, e := strconv.Atoi(resp.Header.Get("Retry-After")); e == nil {
+		err.retryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+var (
+	// slackPaceMu serializes messages, so that they are sent no more often
+	// than once per -slack-interval.
+	slackPaceMu sync.Mutex
+	slackSentAt time.Time
+)
+
+// deliverSlack sends message to Slack, waiting for its turn and retrying
+// transient failures with backoff. In daemon mode, messages that could not
+// be delivered are kept in the state store and retried in the next scan.
+func deliverSlack(msg slackMessage) error {
+	var err error
+	for attempt := 0; attempt <= slackRetries; attempt++ {
+		err = sendPaced(msg)
+		terr, ok := err.(*transientError)
+		if !ok {
+			return err
+		}
+		if attempt == slackRetries {
+			break
+		}
+		wait := terr.retryAfter
+		if wait == 0 {
+			wait = time.Second << uint(attempt)
+		}
+		log.Printf("slack delivery failed, retrying in %s: %s", wait, err)
+		time.Sleep(wait)
+	}
+	if *listenFl != "" {
+		if qerr := queuePending(msg); qerr != nil {
+			log.Printf("cannot queue undelivered message: %s", qerr)
+		}
+	}
+	return err
+}
+
+// sendPaced sends message once, waiting until -slack-interval elapsed since
+// the previous message.
+func sendPaced(msg slackMessage) error {
+	slackPaceMu.Lock()
+	defer slackPaceMu.Unlock()
+
+	if wait := slackSentAt.Add(*slackIntervalFl).Sub(time.Now()); wait > 0 {
+		time.Sleep(wait)
+	}
+	defer func() { slackSentAt = time.Now() }()
+	if *slackTokenFl != "" {
+		return sendSlackMessage(msg)
+	}
+	return sendSlackWebhook(msg)
+}
+
+// pendingKey is the key of the document holding undelivered messages.
+const pendingKey = "slack/pending"
+
+var pendingMu sync.Mutex
+
+// queuePending stores message for delivery in the next scan.
+func queuePending(msg slackMessage) error {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	var pending []slackMessage
+	if _, err := loadDocument(pendingKey, &pending); err != nil {
+		return err
+	}
+	return saveDocument(pendingKey, append(pending, msg))
+}
+
+// deliverPending sends messages left undelivered by previous scans.
+func deliverPending() {
+	pendingMu.Lock()
+	var pending []slackMessage
+	_, err := loadDocument(pendingKey, &pending)
+	if err == nil && len(pending) > 0 {
+		err = saveDocument(pendingKey, []slackMessage{})
+	}
+	pendingMu.Unlock()
+	if err != nil {
+		log.Printf("cannot load undelivered messages: %s", err)
+		return
+	}
+	for _, msg := range pending {
+		if err := deliverSlack(msg); err != nil {
+			log.Printf("cannot deliver queued message: %s", err)
+		}
+	}
+}
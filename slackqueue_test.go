@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeliverSlack(t *testing.T) {
+	var texts []string
+	var sentAt []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		texts = append(texts, msg.Text)
+		sentAt = append(sentAt, time.Now())
+		// the first message is rate limited
+		if len(texts) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+	defer func(u, token, listen string, interval time.Duration, s Store) {
+		*slackURLFl, *slackTokenFl, *listenFl, *slackIntervalFl, store = u, token, listen, interval, s
+	}(*slackURLFl, *slackTokenFl, *listenFl, *slackIntervalFl, store)
+	*slackURLFl, *slackTokenFl, *listenFl, *slackIntervalFl, store = srv.URL, "", ":8080", 200*time.Millisecond, newMemoryStore()
+
+	if err := deliverSlack(slackMessage{Text: "first"}); err != nil {
+		t.Fatalf("deliverSlack() error = %v", err)
+	}
+	if len(texts) != 2 || texts[1] != "first" {
+		t.Fatalf("deliverSlack() sent %q, want first retried once", texts)
+	}
+	if d := sentAt[1].Sub(sentAt[0]); d < time.Second {
+		t.Errorf("retried after %s, want Retry-After of 1s", d)
+	}
+
+	// messages left undelivered by a previous scan are sent first
+	if err := queuePending(slackMessage{Text: "queued"}); err != nil {
+		t.Fatalf("queuePending() error = %v", err)
+	}
+	deliverPending()
+	if len(texts) != 3 || texts[2] != "queued" {
+		t.Errorf("deliverPending() sent %q, want queued", texts)
+	}
+	var pending []slackMessage
+	if _, err := loadDocument(pendingKey, &pending); err != nil || len(pending) != 0 {
+		t.Errorf("pending messages after delivery = %v, %v, want none", pending, err)
+	}
+	if d := sentAt[2].Sub(sentAt[1]); d < *slackIntervalFl {
+		t.Errorf("messages sent %s apart, want at least %s", d, *slackIntervalFl)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackRequestMaxAge is the maximum age of a signed Slack request, older
+// requests are rejected to prevent replay attacks.
+const slackRequestMaxAge = 5 * time.Minute
+
+// verifySlackRequest checks signature of the request sent by Slack and
+// returns its body.
+func verifySlackRequest(r *http.Request) ([]byte, error) {
+	secret := currentAuth().slackSigningSecret
+	if secret == "" {
+		return nil, errors.New("signing secret not configured")
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read body: %s", err)
+	}
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %s", err)
+	}
+	if age := time.Since(time.Unix(sec, 0)); age > slackRequestMaxAge || age < -slackRequestMaxAge {
+		return nil, errors.New("request too old")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature"))) {
+		return nil, errors.New("invalid signature")
+	}
+	return body, nil
+}
+
+// handleSlashCommand serves Slack slash command requests. Supported commands
+// are "run", which triggers a scan, and "list [repo=<name>]", which lists
+// stale pull requests. Because both can take longer than Slack allows, and
+// a tenant scan may be running, the request is acknowledged immediately and
+// all replies are sent to the response URL.
+func handleSlashCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := verifySlackRequest(r)
+	if err != nil {
+		log.Printf("rejecting slack command: %s", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	responseURL := form.Get("response_url")
+	user := form.Get("user_name")
+	args := strings.Fields(form.Get("text"))
+	if len(args) == 0 {
+		args = []string{"help"}
+	}
+	w.WriteHeader(http.StatusOK)
+	goTracked(func() { runSlashCommand(args, user, responseURL) })
+}
+
+// runSlashCommand executes slash command with given arguments, sent by given
+// Slack user, and sends replies to given response URL.
+func runSlashCommand(args []string, user, responseURL string) {
+	switch args[0] {
+	case "run":
+		log.Printf("scan triggered by %s", user)
+		respondSlack(responseURL, tr(nil, "slash_scan_started"))
+		text := tr(nil, "slash_scan_finished")
+		if err := runScan(); err != nil {
+			text = tr(nil, "slash_scan_failed", err)
+		}
+		respondSlack(responseURL, text)
+	case "list":
+		filter := map[string]string{}
+		for _, arg := range args[1:] {
+			kv := strings.SplitN(arg, "=", 2)
+			if len(kv) != 2 {
+				respondSlack(responseURL, tr(nil, "slash_invalid_arg", arg))
+				return
+			}
+			filter[kv[0]] = kv[1]
+		}
+		respondSlack(responseURL, tr(nil, "slash_looking"))
+		text, err := listStale(filter["repo"])
+		if err != nil {
+			text = tr(nil, "slash_list_failed", err)
+		}
+		respondSlack(responseURL, text)
+	default:
+		respondSlack(responseURL, tr(nil, "slash_usage"))
+	}
+}
+
+// listStale returns text listing all stale pull requests, optionally only
+// of given repository.
+func listStale(repo string) (string, error) {
+	issues, err := forge.OpenIssues()
+	if err != nil {
+		return "", err
+	}
+	stale := stalePullRequests(issues)
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].CreatedAt.Before(stale[j].CreatedAt)
+	})
+
+	var b bytes.Buffer
+	for _, issue := range stale {
+		name, err := issue.GetRepository()
+		if err != nil {
+			continue
+		}
+		if repo != "" && name != repo {
+			continue
+		}
+		assignee := tr(&issue, "unassigned")
+		if issue.Assignee != nil {
+			assignee = "@" + issue.Assignee.Login
+		}
+		fmt.Fprintf(&b, "• <%s|%s#%d> %s (%s, %s)\n", issue.HTMLURL, name, issue.Number,
+			issue.Title, assignee, formatAge(time.Since(staleSince(&issue))))
+	}
+	if b.Len() == 0 {
+		return tr(nil, "slash_no_stale"), nil
+	}
+	return b.String(), nil
+}
+
+// formatAge returns human readable, rounded duration.
+func formatAge(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", days, hours)
+}
+
+// respondSlack sends delayed response to a slash command.
+func respondSlack(responseURL, text string) {
+	if responseURL == "" {
+		return
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"response_type": "ephemeral",
+		"text":          text,
+	})
+	if err != nil {
+		log.Printf("cannot JSON encode slack response: %s", err)
+		return
+	}
+	resp, err := httpClient.Post(responseURL, "application/json", bytes.NewBuffer(b))
+	if err != nil {
+		log.Printf("cannot send slack response: %s", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("invalid slack response status: %d", resp.StatusCode)
+	}
+}
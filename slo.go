@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sloKey is the state store key of the first review tracking used by SLOs.
+const sloKey = "slo/first-review"
+
+// SLO is a service level objective for time to first review, for example
+// 90% of pull requests get their first review within 24 business hours.
+type SLO struct {
+	Name string `json:"name"`
+	// Repos are globs of repositories the SLO applies to, all if empty.
+	Repos []string `json:"repos"`
+	// FirstReview is the time pull requests should get their first
+	// review within.
+	FirstReview Duration `json:"first_review"`
+	// BusinessHours counts only time outside -quiet-hours, -quiet-days
+	// and holidays of -holiday-region towards FirstReview.
+	BusinessHours bool `json:"business_hours"`
+	// Target is the share of pull requests, for example 0.9, that must
+	// meet FirstReview.
+	Target float64 `json:"target"`
+	// Window is the rolling window of pull requests, by opening time,
+	// compliance is computed over. Four weeks if not set.
+	Window Duration `json:"window"`
+	// MinSamples is the number of pull requests with known outcome
+	// needed before alerting.
+	MinSamples int `json:"min_samples"`
+	// Channel is the Slack channel alerted when compliance drops below
+	// the target, the default notification channels if empty.
+	Channel string `json:"channel"`
+}
+
+// window returns the rolling window of the SLO.
+func (s *SLO) window() time.Duration {
+	if s.Window > 0 {
+		return time.Duration(s.Window)
+	}
+	return 28 * 24 * time.Hour
+}
+
+// appliesTo returns true if the SLO applies to pull request with given key.
+func (s *SLO) appliesTo(key string) bool {
+	if len(s.Repos) == 0 {
+		return true
+	}
+	repo := key
+	if i := strings.LastIndex(key, "#"); i >= 0 {
+		repo = key[:i]
+	}
+	for _, glob := range s.Repos {
+		if globMatch(glob, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// elapsed returns time counted towards the SLO between given times, up to
+// given limit.
+func (s *SLO) elapsed(from, to time.Time, limit time.Duration) time.Duration {
+	if !s.BusinessHours {
+		return to.Sub(from)
+	}
+	var d time.Duration
+	for t := from; t.Before(to) && d <= limit; {
+		next := quietBoundary(t)
+		if next.After(to) {
+			next = to
+		}
+		quiet := quietAt(t, timezone) || (*holidayRegionFl != "" && isHoliday(*holidayRegionFl, t.In(timezone)))
+		if !quiet {
+			d += next.Sub(t)
+		}
+		t = next
+	}
+	return d
+}
+
+// quietBoundary returns the first time after given one business hours may
+// start or end at: the next midnight, or start or end of -quiet-hours if
+// earlier, all in -timezone.
+func quietBoundary(t time.Time) time.Time {
+	t = t.In(timezone)
+	next := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, timezone)
+	if *quietHoursFl == "" {
+		return next
+	}
+	from, to, err := parseQuietHours(*quietHoursFl)
+	if err != nil {
+		return next
+	}
+	for _, minute := range []int{from, to} {
+		b := time.Date(t.Year(), t.Month(), t.Day(), minute/60, minute%60, 0, 0, timezone)
+		if b.After(t) && b.Before(next) {
+			next = b
+		}
+	}
+	return next
+}
+
+// reviewRecord tracks the first review of a pull request.
+type reviewRecord struct {
+	Opened time.Time `json:"opened"`
+	// Author is the login of the author, whose reviews do not count.
+	Author   string    `json:"author,omitempty"`
+	Reviewed time.Time `json:"reviewed,omitempty"`
+	// Closed is the time the pull request was first found closed
+	// without review.
+	Closed time.Time `json:"closed,omitempty"`
+	// Outcomes maps names of SLOs to true if the first review met them,
+	// false if it did not. Pending outcomes are missing.
+	Outcomes map[string]bool `json:"outcomes,omitempty"`
+}
+
+// outcome returns whether the record met given SLO at given time, and false
+// as the second value if that is not known yet.
+func (r *reviewRecord) outcome(s *SLO, now time.Time) (bool, bool) {
+	if met, ok := r.Outcomes[s.Name]; ok {
+		return met, true
+	}
+	limit := time.Duration(s.FirstReview)
+	end := now
+	switch {
+	case !r.Reviewed.IsZero():
+		end = r.Reviewed
+	case !r.Closed.IsZero():
+		end = r.Closed
+	}
+	missed := s.elapsed(r.Opened, end, limit) > limit
+	if !missed && r.Reviewed.IsZero() {
+		// still waiting, or closed without review in time
+		return false, false
+	}
+	if r.Outcomes == nil {
+		r.Outcomes = map[string]bool{}
+	}
+	r.Outcomes[s.Name] = !missed
+	return !missed, true
+}
+
+// sloState is the state of SLO tracking.
+type sloState struct {
+	// PRs maps keys of pull requests to their first review records.
+	PRs map[string]*reviewRecord `json:"prs"`
+	// Alerting are names of SLOs below their target.
+	Alerting map[string]bool `json:"alerting"`
+}
+
+// firstReviewTime returns time of the first review of given pull request
+// by somebody else than its author, zero if there is none yet.
+func firstReviewTime(issue *Issue) (time.Time, error) {
+	reviews, err := pullReviews(issue)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, r := range reviews {
+		if r.User == nil || r.User.Login == issue.User.Login || r.State == "PENDING" || r.SubmittedAt.IsZero() {
+			continue
+		}
+		return r.SubmittedAt, nil
+	}
+	return time.Time{}, nil
+}
+
+// closedFirstReview returns time of the first review of the pull request
+// with given key and author, which is no longer open.
+func closedFirstReview(key, author string) (time.Time, error) {
+	i := strings.LastIndex(key, "#")
+	if i < 0 {
+		return time.Time{}, fmt.Errorf("invalid key %q", key)
+	}
+	number, err := strconv.ParseInt(key[i+1:], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid key %q", key)
+	}
+	return firstReviewTime(&Issue{Repo: key[:i], Number: number, User: &User{Login: author}})
+}
+
+// trackSLOs records first reviews of given open pull requests, computes
+// rolling compliance of every SLO and alerts when it drops below the target
+// or recovers.
+func trackSLOs(issues []Issue, now time.Time) {
+	if len(config.SLOs) == 0 || githubOnly() != nil {
+		return
+	}
+	var st sloState
+	if _, err := loadDocument(sloKey, &st); err != nil {
+		log.Printf("cannot load SLO state: %s", err)
+		return
+	}
+	if st.PRs == nil {
+		st.PRs = map[string]*reviewRecord{}
+	}
+	if st.Alerting == nil {
+		st.Alerting = map[string]bool{}
+	}
+
+	open := map[string]bool{}
+	for i := range issues {
+		issue := &issues[i]
+		if !issue.isPullRequest() {
+			continue
+		}
+		key := issueKey(issue)
+		open[key] = true
+		r := st.PRs[key]
+		if r == nil {
+			r = &reviewRecord{Opened: issue.CreatedAt}
+			st.PRs[key] = r
+		}
+		if issue.User != nil {
+			r.Author = issue.User.Login
+		}
+		if !r.Reviewed.IsZero() {
+			continue
+		}
+		reviewed, err := firstReviewTime(issue)
+		if err != nil {
+			log.Printf("cannot get first review of #%d: %s", issue.Number, err)
+			continue
+		}
+		r.Reviewed = reviewed
+	}
+
+	var longest time.Duration
+	for i := range config.SLOs {
+		if w := config.SLOs[i].window(); w > longest {
+			longest = w
+		}
+	}
+	for key, r := range st.PRs {
+		if r.Opened.Before(now.Add(-longest)) {
+			delete(st.PRs, key)
+			continue
+		}
+		if !open[key] && r.Reviewed.IsZero() && r.Closed.IsZero() {
+			// the pull request may have been reviewed since the last scan
+			reviewed, err := closedFirstReview(key, r.Author)
+			if err != nil {
+				log.Printf("cannot get first review of %s: %s", key, err)
+				continue
+			}
+			if reviewed.IsZero() {
+				r.Closed = now
+			}
+			r.Reviewed = reviewed
+		}
+	}
+
+	for i := range config.SLOs {
+		s := &config.SLOs[i]
+		met, total := 0, 0
+		for key, r := range st.PRs {
+			if !s.appliesTo(key) || r.Opened.Before(now.Add(-s.window())) {
+				continue
+			}
+			ok, known := r.outcome(s, now)
+			if !known {
+				continue
+			}
+			total++
+			if ok {
+				met++
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		compliance := float64(met) / float64(total)
+		log.Printf("SLO %s: %.1f%% of %d pull requests reviewed within %s, target %.1f%%", s.Name, compliance*100, total, time.Duration(s.FirstReview), s.Target*100)
+		switch {
+		case compliance < s.Target && total >= s.MinSamples && !st.Alerting[s.Name]:
+			st.Alerting[s.Name] = true
+			alertSLO(s, tr(nil, "slo_breach", s.Name, compliance*100, s.Target*100, total, formatAge(time.Duration(s.FirstReview))))
+		case compliance >= s.Target && st.Alerting[s.Name]:
+			delete(st.Alerting, s.Name)
+			alertSLO(s, tr(nil, "slo_recovered", s.Name, compliance*100, s.Target*100))
+		}
+	}
+
+	if err := saveDocument(sloKey, st); err != nil {
+		log.Printf("cannot save SLO state: %s", err)
+	}
+}
+
+// alertSLO posts given text to the channel of given SLO.
+func alertSLO(s *SLO, text string) {
+	var err error
+	if s.Channel == "" {
+		err = postSlack(text)
+	} else {
+		err = slackCall("chat.postMessage", map[string]interface{}{
+			"channel":    s.Channel,
+			"text":       text,
+			"username":   "github-pr",
+			"icon_emoji": ":octocat:",
+		}, nil)
+	}
+	if err != nil {
+		log.Printf("cannot alert about SLO %s: %s", s.Name, err)
+	}
+}
+
+// checkSLOs verifies SLOs of given configuration.
+func checkSLOs(slos []SLO) error {
+	names := map[string]bool{}
+	for _, s := range slos {
+		if s.Name == "" || names[s.Name] {
+			return fmt.Errorf("SLOs need unique names, got %q", s.Name)
+		}
+		names[s.Name] = true
+		if s.FirstReview <= 0 {
+			return fmt.Errorf("SLO %s needs first_review", s.Name)
+		}
+		if s.Target <= 0 || s.Target > 1 {
+			return fmt.Errorf("target of SLO %s must be greater than 0 and at most 1", s.Name)
+		}
+	}
+	return nil
+}
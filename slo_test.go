@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLOOutcome(t *testing.T) {
+	defer func(tz *time.Location, hours, days, region string) {
+		timezone, *quietHoursFl, *quietDaysFl, *holidayRegionFl = tz, hours, days, region
+	}(timezone, *quietHoursFl, *quietDaysFl, *holidayRegionFl)
+	timezone = time.UTC
+	*quietHoursFl, *quietDaysFl, *holidayRegionFl = "", "Sat,Sun", ""
+
+	// Friday noon
+	opened := time.Date(2026, 10, 9, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return opened.Add(d) }
+	slo := &SLO{Name: "first-review", FirstReview: Duration(24 * time.Hour)}
+	business := &SLO{Name: "business", FirstReview: Duration(24 * time.Hour), BusinessHours: true}
+	tests := []struct {
+		name      string
+		slo       *SLO
+		record    reviewRecord
+		now       time.Time
+		met, done bool
+	}{
+		{name: "reviewed in time", slo: slo, record: reviewRecord{Opened: opened, Reviewed: at(2 * time.Hour)}, now: at(48 * time.Hour), met: true, done: true},
+		{name: "reviewed late", slo: slo, record: reviewRecord{Opened: opened, Reviewed: at(30 * time.Hour)}, now: at(48 * time.Hour), done: true},
+		{name: "waiting", slo: slo, record: reviewRecord{Opened: opened}, now: at(time.Hour)},
+		{name: "waiting too long", slo: slo, record: reviewRecord{Opened: opened}, now: at(25 * time.Hour), done: true},
+		{name: "closed in time without review", slo: slo, record: reviewRecord{Opened: opened, Closed: at(time.Hour)}, now: at(48 * time.Hour)},
+		{name: "closed late without review", slo: slo, record: reviewRecord{Opened: opened, Closed: at(30 * time.Hour)}, now: at(48 * time.Hour), done: true},
+		{name: "known outcome", slo: slo, record: reviewRecord{Opened: opened, Outcomes: map[string]bool{"first-review": true}}, now: at(48 * time.Hour), met: true, done: true},
+		// Friday noon to Monday 10:00 is 22 business hours
+		{name: "weekend does not count", slo: business, record: reviewRecord{Opened: opened, Reviewed: at(70 * time.Hour)}, now: at(96 * time.Hour), met: true, done: true},
+		{name: "business hours late", slo: business, record: reviewRecord{Opened: opened, Reviewed: at(74 * time.Hour)}, now: at(96 * time.Hour), done: true},
+	}
+	for _, tt := range tests {
+		met, done := tt.record.outcome(tt.slo, tt.now)
+		if met != tt.met || done != tt.done {
+			t.Errorf("%s: outcome() = %v, %v, want %v, %v", tt.name, met, done, tt.met, tt.done)
+			continue
+		}
+		if got, ok := tt.record.Outcomes[tt.slo.Name]; ok != done || got != met {
+			t.Errorf("%s: recorded outcome %v, %v, want %v, %v", tt.name, got, ok, met, done)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package main
+
+// links the SQLite driver used by the state store with sqlite:// URLs
+import _ "modernc.org/sqlite"
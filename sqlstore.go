@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// sqlDrivers maps URL schemes of SQL databases to names of their
+// database/sql drivers. Drivers are not dependencies of the default build,
+// they are linked in with the build tag of the same name, for example
+// "go build -tags postgres".
+var sqlDrivers = map[string]string{
+	"postgres": "postgres",
+	"sqlite":   "sqlite",
+}
+
+// openSQL opens database of given URL with the driver of its scheme.
+func openSQL(rawurl string) (*sql.DB, string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid database url: %s", err)
+	}
+	driver, ok := sqlDrivers[u.Scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported database url scheme %q", u.Scheme)
+	}
+	registered := false
+	for _, name := range sql.Drivers() {
+		registered = registered || name == driver
+	}
+	if !registered {
+		return nil, "", fmt.Errorf("%s support is not built in, build with -tags %s", u.Scheme, u.Scheme)
+	}
+	dsn := rawurl
+	if u.Scheme != "postgres" {
+		// drivers of embedded databases take the file path only
+		dsn = u.Path
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot open %s database: %s", u.Scheme, err)
+	}
+	if u.Scheme != "postgres" {
+		// concurrent writers of a file fail with "database is locked"
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, "", fmt.Errorf("cannot connect to %s database: %s", u.Scheme, err)
+	}
+	return db, driver, nil
+}
+
+// sqlPlaceholders returns query with "?" placeholders replaced by those of
+// given driver.
+func sqlPlaceholders(driver, query string) string {
+	if driver != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sqlStore keeps every document as a row of the bot_state table.
+type sqlStore struct {
+	db     *sql.DB
+	driver string
+}
+
+func newSQLStore(rawurl string) (Store, error) {
+	db, driver, err := openSQL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS bot_state (key TEXT PRIMARY KEY, value TEXT NOT NULL)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot create state table: %s", err)
+	}
+	return &sqlStore{db: db, driver: driver}, nil
+}
+
+func (s *sqlStore) Get(key string) ([]byte, error) {
+	var value string
+	err := s.db.QueryRow(sqlPlaceholders(s.driver, `SELECT value FROM bot_state WHERE key = ?`), key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+// sqlUpsert replaces document of given key, in both Postgres and SQLite.
+const sqlUpsert = `INSERT INTO bot_state (key, value) VALUES (?, ?)
+	ON CONFLICT (key) DO UPDATE SET value = excluded.value`
+
+func (s *sqlStore) Set(key string, value []byte) error {
+	_, err := s.db.Exec(sqlPlaceholders(s.driver, sqlUpsert), key, string(value))
+	return err
+}
+
+func (s *sqlStore) SetMany(docs map[string][]byte) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for key, value := range docs {
+		if _, err := tx.Exec(sqlPlaceholders(s.driver, sqlUpsert), key, string(value)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
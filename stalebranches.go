@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// branchesReportedKey is the key of the document holding the time stale
+// branches were last reported.
+const branchesReportedKey = "branches/reported"
+
+// maxDeleteButtons limits delete buttons in a report, Slack allows up to 25
+// elements in an actions block.
+const maxDeleteButtons = 25
+
+// StaleBranch is a branch without open pull request nobody pushed to for a
+// long time.
+type StaleBranch struct {
+	Repo   string
+	Name   string
+	SHA    string
+	Owner  string
+	PushAt time.Time
+}
+
+// staleBranches returns branches of given repository that have commits not
+// merged to the default branch, no open pull request and no push since
+// given time.
+func staleBranches(repo Repository, since time.Time) ([]StaleBranch, error) {
+	base := fmt.Sprintf("%s/repos/%s/%s", *ghAPIFl, *ghOrgFl, repo.Name)
+
+	withPR := map[string]bool{}
+	err := githubGetPages(base+"/pulls?state=open&per_page=100", func(raw json.RawMessage) error {
+		var page []struct {
+			Head struct {
+				Ref string `json:"ref"`
+			} `json:"head"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		for _, p := range page {
+			withPR[p.Head.Ref] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list pull requests: %s", err)
+	}
+
+	type branch struct {
+		Name   string `json:"name"`
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+		Protected bool `json:"protected"`
+	}
+	var branches []branch
+	err = githubGetPages(base+"/branches?per_page=100", func(raw json.RawMessage) error {
+		var page []branch
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		branches = append(branches, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list branches: %s", err)
+	}
+
+	var stale []StaleBranch
+	for _, b := range branches {
+		if b.Name == repo.DefaultBranch || b.Protected || withPR[b.Name] {
+			continue
+		}
+		var commit struct {
+			Author *User `json:"author"`
+			Commit struct {
+				Committer struct {
+					Date time.Time `json:"date"`
+				} `json:"committer"`
+			} `json:"commit"`
+		}
+		if err := githubGet(base+"/commits/"+b.Commit.SHA, &commit); err != nil {
+			return nil, fmt.Errorf("cannot get commit of %s: %s", b.Name, err)
+		}
+		if commit.Commit.Committer.Date.After(since) {
+			continue
+		}
+		var compare struct {
+			AheadBy int `json:"ahead_by"`
+		}
+		if err := githubGet(fmt.Sprintf("%s/compare/%s...%s", base, repo.DefaultBranch, b.Commit.SHA), &compare); err != nil {
+			return nil, fmt.Errorf("cannot compare %s: %s", b.Name, err)
+		}
+		if compare.AheadBy == 0 {
+			continue
+		}
+		sb := StaleBranch{Repo: repo.Name, Name: b.Name, SHA: b.Commit.SHA, PushAt: commit.Commit.Committer.Date}
+		if commit.Author != nil {
+			sb.Owner = commit.Author.Login
+		}
+		stale = append(stale, sb)
+	}
+	return stale, nil
+}
+
+// reportStaleBranches posts list of stale branches of the organization to
+// Slack, at most once per -branch-report-every. Branches older than
+// -delete-branches-after get a button their owner can delete them with.
+func reportStaleBranches(now time.Time) error {
+	var reported time.Time
+	if _, err := loadDocument(branchesReportedKey, &reported); err != nil {
+		return err
+	}
+	if now.Before(reported.Add(*branchReportEveryFl)) {
+		return nil
+	}
+	repos, err := ownerRepositories()
+	if err != nil {
+		return fmt.Errorf("cannot list repositories: %s", err)
+	}
+	var all []StaleBranch
+	for _, repo := range repos {
+		stale, err := staleBranches(repo, now.Add(-*staleBranchesFl))
+		if err != nil {
+			log.Printf("cannot list stale branches of %s: %s", repo.Name, err)
+			continue
+		}
+		all = append(all, stale...)
+	}
+	if len(all) > 0 {
+		sort.Slice(all, func(i, j int) bool {
+			return all[i].PushAt.Before(all[j].PushAt)
+		})
+		text := staleBranchesText(all, now)
+		var err error
+		if buttons := deleteButtons(all, now); len(buttons) > 0 && *slackTokenFl != "" {
+			err = postSlackMessage(text, []interface{}{
+				map[string]interface{}{
+					"type": "section",
+					"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+				},
+				map[string]interface{}{"type": "actions", "elements": buttons},
+			}, "")
+		} else {
+			err = postSlack(text)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot write slack notification: %s", err)
+		}
+	}
+	return saveDocument(branchesReportedKey, now)
+}
+
+// staleBranchesText returns text of the stale branches report.
+func staleBranchesText(branches []StaleBranch, now time.Time) string {
+	var b strings.Builder
+	b.WriteString(tr(nil, "branches_header", len(branches), formatAge(*staleBranchesFl)) + "\n")
+	for _, sb := range branches {
+		owner := tr(nil, "branches_unknown_owner")
+		if sb.Owner != "" {
+			owner = userMention(sb.Owner)
+		}
+		b.WriteString(tr(nil, "branches_line", sb.Repo, sb.Name, owner, formatAge(now.Sub(sb.PushAt))) + "\n")
+	}
+	return b.String()
+}
+
+// deleteButtons returns Slack buttons deleting branches older than
+// -delete-branches-after.
+func deleteButtons(branches []StaleBranch, now time.Time) []interface{} {
+	if *deleteBranchesAfterFl == 0 {
+		return nil
+	}
+	var buttons []interface{}
+	for _, sb := range branches {
+		if sb.Owner == "" || now.Sub(sb.PushAt) < *deleteBranchesAfterFl {
+			continue
+		}
+		if len(buttons) == maxDeleteButtons {
+			break
+		}
+		label := tr(nil, "branches_delete_button", sb.Repo, sb.Name)
+		if len(label) > 75 {
+			// Slack limit of button text
+			label = label[:72] + "..."
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"type":      "button",
+			"action_id": "delete_branch",
+			"value":     strings.Join([]string{sb.Repo, sb.SHA, sb.Owner, sb.Name}, ":"),
+			"style":     "danger",
+			"text": map[string]interface{}{
+				"type": "plain_text",
+				"text": label,
+			},
+		})
+	}
+	return buttons
+}
+
+// deleteStaleBranch deletes branch described by value of a delete button,
+// if it was clicked by the branch owner and was not pushed to since.
+func deleteStaleBranch(value, slackID string) (string, error) {
+	parts := strings.SplitN(value, ":", 4)
+	if len(parts) != 4 {
+		return "", errors.New("invalid branch")
+	}
+	repo, sha, owner, name := parts[0], parts[1], parts[2], parts[3]
+	if id := config.Members[owner].Slack; id == "" || id != slackID {
+		return "", fmt.Errorf("only %s can delete %s:%s", userMention(owner), repo, name)
+	}
+
+	base := fmt.Sprintf("%s/repos/%s/%s/git/refs/heads/%s", *ghAPIFl, *ghOrgFl, repo, name)
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := githubGet(base, &ref); err != nil {
+		return "", fmt.Errorf("cannot get branch: %s", err)
+	}
+	if ref.Object.SHA != sha {
+		return "", fmt.Errorf("%s:%s was pushed to since the report", repo, name)
+	}
+	req, err := http.NewRequest("DELETE", base, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create DELETE request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot do request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return "", fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	log.Printf("Deleted branch %s:%s on request of %s", repo, name, owner)
+	return tr(nil, "branches_deleted", repo, name, userMention(owner)), nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// PRState is the state the bot keeps about a single pull request.
+type PRState struct {
+	// SnoozedUntil is the time until which no reminders are sent.
+	SnoozedUntil time.Time `json:"snoozed_until,omitempty"`
+	// AcknowledgedAt is the time when the assignee acknowledged working on
+	// the pull request.
+	AcknowledgedAt time.Time `json:"acknowledged_at,omitempty"`
+	// AcknowledgedBy is the login of the person that acknowledged.
+	AcknowledgedBy string `json:"acknowledged_by,omitempty"`
+	// AssignmentComment is the ID of the github comment announcing the
+	// assignment.
+	AssignmentComment int64 `json:"assignment_comment,omitempty"`
+	// Phase is the current phase of the pull request.
+	Phase Phase `json:"phase,omitempty"`
+	// Transitions lists all phase changes of the pull request.
+	Transitions []Transition `json:"transitions,omitempty"`
+	// NotifiedAt is the time the assignee or lead was last notified.
+	NotifiedAt time.Time `json:"notified_at,omitempty"`
+	// AuthorNotices lists notices sent to the author, for example
+	// "ready:<sha>".
+	AuthorNotices []string `json:"author_notices,omitempty"`
+	// AuthorRemindedAt is the time the author was last reminded to act on
+	// the pull request.
+	AuthorRemindedAt time.Time `json:"author_reminded_at,omitempty"`
+	// Reminders lists Slack reminders posted about the pull request.
+	Reminders []SlackReminder `json:"reminders,omitempty"`
+	// TicketCommented tells if the JIRA issue of the pull request was
+	// commented on.
+	TicketCommented bool `json:"ticket_commented,omitempty"`
+	// LinearCommented tells if the Linear issue of the pull request was
+	// commented on.
+	LinearCommented bool `json:"linear_commented,omitempty"`
+	// Rerequested maps logins of reviewers whose review was requested
+	// again after their change request to the SHA of the commit it was
+	// requested for.
+	Rerequested map[string]string `json:"rerequested,omitempty"`
+	// PagedAt is the time on-call reviewers were paged about the pull
+	// request.
+	PagedAt time.Time `json:"paged_at,omitempty"`
+}
+
+// quiet returns true if reminders about the pull request should not be sent
+// at given time.
+func (s PRState) quiet(now time.Time) bool {
+	if now.Before(s.SnoozedUntil) {
+		return true
+	}
+	return !s.AcknowledgedAt.IsZero() && now.Before(s.AcknowledgedAt.Add(*ackGraceFl))
+}
+
+// stateMu serializes state updates of this instance.
+var stateMu sync.Mutex
+
+// issueKey returns key identifying given issue in the state.
+func issueKey(issue *Issue) string {
+	repo, err := issue.GetRepository()
+	if err != nil {
+		repo = issue.HTMLURL
+	}
+	return fmt.Sprintf("%s#%d", repo, issue.Number)
+}
+
+// loadState opens the configured state store.
+func loadState() error {
+	s, err := newStore(*stateStoreFl, *stateFileFl)
+	if err != nil {
+		return err
+	}
+	store = s
+	return nil
+}
+
+// getState returns state of the pull request with given key. Errors are
+// logged and empty state is returned.
+func getState(key string) PRState {
+	var s PRState
+	if _, err := loadDocument(key, &s); err != nil {
+		log.Printf("cannot load state: %s", err)
+	}
+	return s
+}
+
+// updateState modifies state of the pull request with given key and writes
+// it to the state store.
+func updateState(key string, update func(*PRState)) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	var s PRState
+	if _, err := loadDocument(key, &s); err != nil {
+		return err
+	}
+	update(&s)
+	return saveDocument(key, s)
+}
+
+// updateStates modifies states of pull requests with given keys, like
+// updateState, and writes those update returned true for to the state store
+// at once.
+func updateStates(keys []string, update func(key string, s *PRState) bool) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	docs := map[string][]byte{}
+	for _, key := range keys {
+		var s PRState
+		if _, err := loadDocument(key, &s); err != nil {
+			return err
+		}
+		if !update(key, &s) {
+			continue
+		}
+		b, err := json.Marshal(s)
+		if err != nil {
+			return fmt.Errorf("cannot encode %s: %s", key, err)
+		}
+		docs[key] = b
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := setMany(store, docs); err != nil {
+		return fmt.Errorf("cannot write states: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// scanStats counts what the current scan did. Fields are updated
+// atomically, scans run concurrent goroutines.
+type scanStats struct {
+	Scanned  int64
+	Stale    int64
+	Assigned int64
+	Reminded int64
+	// Errors counts pull requests and reports the scan failed to handle.
+	Errors    int64
+	APIErrors int64
+}
+
+// stats are the counters of the current scan, reset when it starts.
+var stats scanStats
+
+// countStat increments given counter of the current scan.
+func countStat(counter *int64) {
+	atomic.AddInt64(counter, 1)
+}
+
+// resetStats zeroes all counters of the current scan.
+func resetStats() {
+	atomic.StoreInt64(&stats.Scanned, 0)
+	atomic.StoreInt64(&stats.Stale, 0)
+	atomic.StoreInt64(&stats.Assigned, 0)
+	atomic.StoreInt64(&stats.Reminded, 0)
+	atomic.StoreInt64(&stats.Errors, 0)
+	atomic.StoreInt64(&stats.APIErrors, 0)
+}
+
+// currentStats returns copy of the counters of the current scan.
+func currentStats() scanStats {
+	return scanStats{
+		Scanned:   atomic.LoadInt64(&stats.Scanned),
+		Stale:     atomic.LoadInt64(&stats.Stale),
+		Assigned:  atomic.LoadInt64(&stats.Assigned),
+		Reminded:  atomic.LoadInt64(&stats.Reminded),
+		Errors:    atomic.LoadInt64(&stats.Errors),
+		APIErrors: atomic.LoadInt64(&stats.APIErrors),
+	}
+}
+
+// errorCountingTransport counts failed outbound requests. Not found
+// responses are not counted, the bot uses them to check for existence.
+type errorCountingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *errorCountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || (resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
+		countStat(&stats.APIErrors)
+	}
+	return resp, err
+}
+
+// sendStatsd sends metrics of the finished scan to the StatsD server at
+// -statsd, with DogStatsD tags of -statsd-tags.
+func sendStatsd(s scanStats, duration time.Duration, scanErr error) error {
+	conn, err := net.Dial("udp", *statsdFl)
+	if err != nil {
+		return fmt.Errorf("cannot connect to StatsD: %s", err)
+	}
+	defer conn.Close()
+
+	tags := ""
+	if *statsdTagsFl != "" {
+		tags = "|#" + strings.Replace(*statsdTagsFl, " ", "", -1)
+	}
+	failed := 0
+	if scanErr != nil {
+		failed = 1
+	}
+	metrics := []string{
+		fmt.Sprintf("stale_prs:%d|g", s.Stale),
+		fmt.Sprintf("scanned_prs:%d|g", s.Scanned),
+		fmt.Sprintf("assignments:%d|c", s.Assigned),
+		fmt.Sprintf("reminders:%d|c", s.Reminded),
+		fmt.Sprintf("errors:%d|c", s.Errors),
+		fmt.Sprintf("api_errors:%d|c", s.APIErrors),
+		fmt.Sprintf("run_failures:%d|c", failed),
+		fmt.Sprintf("run_duration:%d|ms", duration/time.Millisecond),
+	}
+	var b strings.Builder
+	for _, m := range metrics {
+		b.WriteString(*statsdPrefixFl + m + tags + "\n")
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return fmt.Errorf("cannot send metrics: %s", err)
+	}
+	return nil
+}
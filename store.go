@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Store keeps the bot's state as JSON documents under string keys. Stores
+// other than the in-memory one survive restarts, Redis and Postgres stores
+// are also shared between instances.
+type Store interface {
+	// Get returns document with given key, or nil if there is none.
+	Get(key string) ([]byte, error)
+	// Set replaces document with given key.
+	Set(key string, value []byte) error
+}
+
+// batchStore is a Store able to write several documents at once.
+type batchStore interface {
+	// SetMany replaces documents with given keys.
+	SetMany(docs map[string][]byte) error
+}
+
+// setMany writes given documents to given store, at once if it supports
+// it.
+func setMany(s Store, docs map[string][]byte) error {
+	if b, ok := s.(batchStore); ok {
+		return b.SetMany(docs)
+	}
+	for key, value := range docs {
+		if err := s.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store is the configured state store.
+var store Store = newMemoryStore()
+
+// storeBackends maps URL schemes of state stores to their constructors.
+// Stores of databases whose drivers are not in the standard library
+// register themselves when built with the build tag of the same name.
+var storeBackends = map[string]func(rawurl string) (Store, error){
+	"redis": func(rawurl string) (Store, error) {
+		c, err := newRedisClient(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		return &redisStore{client: c, prefix: "github-stale-pr-bot:"}, nil
+	},
+	"postgres": newSQLStore,
+	"sqlite":   newSQLStore,
+}
+
+// newStore returns store for given URL, for example "redis://...". Without
+// URL, state is kept in the state file if given, or in memory otherwise.
+func newStore(rawurl, file string) (Store, error) {
+	switch {
+	case rawurl != "":
+		scheme := rawurl
+		if i := strings.Index(rawurl, "://"); i >= 0 {
+			scheme = rawurl[:i]
+		}
+		backend, ok := storeBackends[scheme]
+		if !ok {
+			return nil, fmt.Errorf("unsupported state store %q, expected redis, postgres, bolt or sqlite URL", redact(rawurl))
+		}
+		return backend(rawurl)
+	case file != "":
+		return newFileStore(file)
+	}
+	return newMemoryStore(), nil
+}
+
+// memoryStore keeps documents in memory only.
+type memoryStore struct {
+	mu   sync.Mutex
+	docs map[string]json.RawMessage
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{docs: map[string]json.RawMessage{}}
+}
+
+func (s *memoryStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.docs[key], nil
+}
+
+func (s *memoryStore) Set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[key] = value
+	return nil
+}
+
+func (s *memoryStore) SetMany(docs map[string][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, value := range docs {
+		s.docs[key] = value
+	}
+	return nil
+}
+
+// fileStore keeps documents in memory and writes all of them to a single
+// JSON file on every change.
+type fileStore struct {
+	memoryStore
+	path string
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	s := &fileStore{memoryStore: memoryStore{docs: map[string]json.RawMessage{}}, path: path}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read state: %s", err)
+	}
+	if err := json.Unmarshal(b, &s.docs); err != nil {
+		return nil, fmt.Errorf("cannot decode state: %s", err)
+	}
+	return s, nil
+}
+
+func (s *fileStore) Set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[key] = value
+	return s.write()
+}
+
+func (s *fileStore) SetMany(docs map[string][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, value := range docs {
+		s.docs[key] = value
+	}
+	return s.write()
+}
+
+// write writes all documents to the file. The caller must hold the lock.
+func (s *fileStore) write() error {
+	b, err := json.MarshalIndent(s.docs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode state: %s", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("cannot write state: %s", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("cannot write state: %s", err)
+	}
+	return nil
+}
+
+// redisStore keeps every document as a separate Redis key.
+type redisStore struct {
+	client *redisClient
+	prefix string
+}
+
+func (s *redisStore) Get(key string) ([]byte, error) {
+	reply, err := s.client.Do("GET", s.prefix+key)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	return []byte(reply.(string)), nil
+}
+
+func (s *redisStore) Set(key string, value []byte) error {
+	_, err := s.client.Do("SET", s.prefix+key, string(value))
+	return err
+}
+
+func (s *redisStore) SetMany(docs map[string][]byte) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	args := []string{"MSET"}
+	for key, value := range docs {
+		args = append(args, s.prefix+key, string(value))
+	}
+	_, err := s.client.Do(args...)
+	return err
+}
+
+// loadDocument decodes document with given key into v. It returns false if
+// there is no such document.
+func loadDocument(key string, v interface{}) (bool, error) {
+	b, err := store.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("cannot read %s: %s", key, err)
+	}
+	if b == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("cannot decode %s: %s", key, err)
+	}
+	return true, nil
+}
+
+// saveDocument encodes v as document with given key.
+func saveDocument(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cannot encode %s: %s", key, err)
+	}
+	if err := store.Set(key, b); err != nil {
+		return fmt.Errorf("cannot write %s: %s", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewStore(t *testing.T) {
+	tests := []struct {
+		url string
+		err string
+	}{
+		{url: "", err: ""},
+		// drivers are linked in only with their build tags
+		{url: "postgres://bot@localhost/bot", err: "build with -tags postgres"},
+		{url: "sqlite:///var/lib/bot/state.db", err: "build with -tags sqlite"},
+		{url: "mysql://bot@localhost/bot", err: "unsupported state store"},
+	}
+	for _, tt := range tests {
+		_, err := newStore(tt.url, "")
+		if tt.err == "" && err != nil {
+			t.Errorf("newStore(%q) error = %v", tt.url, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("newStore(%q) error = %v, want %q", tt.url, err, tt.err)
+		}
+	}
+}
+
+func TestSQLPlaceholders(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{driver: "postgres", want: "SELECT value FROM bot_state WHERE key = $1 AND value = $2"},
+		{driver: "sqlite", want: "SELECT value FROM bot_state WHERE key = ? AND value = ?"},
+	}
+	for _, tt := range tests {
+		got := sqlPlaceholders(tt.driver, "SELECT value FROM bot_state WHERE key = ? AND value = ?")
+		if got != tt.want {
+			t.Errorf("sqlPlaceholders(%s) = %q, want %q", tt.driver, got, tt.want)
+		}
+	}
+}
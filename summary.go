@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// summaryText returns one line summary of the finished scan.
+func summaryText(s scanStats, duration time.Duration, scanErr error) string {
+	if scanErr != nil {
+		return tr(nil, "summary_failed", duration.Round(time.Second), scanErr)
+	}
+	return tr(nil, "summary", s.Scanned, duration.Round(time.Second), s.Stale, s.Assigned, s.Reminded, s.Errors, s.APIErrors)
+}
+
+// reportScan logs summary of the finished scan, posts it to
+// -summary-channel if the scan did anything, and sends its metrics.
+func reportScan(duration time.Duration, scanErr error) {
+	s := currentStats()
+	text := summaryText(s, duration, scanErr)
+	log.Print(text)
+	logBudget()
+	if *summaryChannelFl != "" && (scanErr != nil || s.Assigned > 0 || s.Reminded > 0 || s.Errors > 0) {
+		msg := map[string]interface{}{
+			"channel":    *summaryChannelFl,
+			"text":       text,
+			"username":   "github-pr",
+			"icon_emoji": ":octocat:",
+		}
+		if err := slackCall("chat.postMessage", msg, nil); err != nil {
+			log.Printf("cannot post scan summary: %s", err)
+		}
+	}
+	if *statsdFl != "" {
+		if err := sendStatsd(s, duration, scanErr); err != nil {
+			log.Printf("cannot send StatsD metrics: %s", err)
+		}
+	}
+}
+
+// exitCode returns exit status of a single scan: 1 if it failed, 2 if it
+// could not handle some pull requests and 0 otherwise.
+func exitCode(scanErr error) int {
+	switch {
+	case scanErr != nil:
+		return 1
+	case currentStats().Errors > 0:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// telegramAPIURL is the base URL of Telegram Bot API.
+const telegramAPIURL = "https://api.telegram.org/bot"
+
+// telegramMaxLength is the maximum length of a Telegram message.
+const telegramMaxLength = 4096
+
+// telegramNotifier posts messages to Telegram chats as a bot.
+type telegramNotifier struct {
+	token string
+	// chats are IDs of the chats messages are posted to, unless the
+	// repository policy routes them elsewhere.
+	chats []string
+}
+
+// telegramEscaper escapes characters reserved in MarkdownV2.
+var telegramEscaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// telegramFormat converts Slack formatted text to Telegram MarkdownV2.
+var telegramFormat = chatFormat{
+	Link: func(url, label string) string {
+		url = strings.NewReplacer(`\`, `\\`, ")", `\)`).Replace(url)
+		return fmt.Sprintf("[%s](%s)", label, url)
+	},
+	Mention: func(login string) string {
+		if name := config.Members[login].Telegram; name != "" {
+			return telegramEscaper.Replace("@" + name)
+		}
+		return ""
+	},
+	Broadcast: func(name string) string {
+		// Telegram cannot notify the whole chat
+		return ""
+	},
+	Escape: telegramEscaper.Replace,
+}
+
+func (n *telegramNotifier) Notify(msg slackMessage) error {
+	chats := n.chats
+	if p := messagePolicy(msg); p != nil && p.TelegramChat != "" {
+		chats = []string{p.TelegramChat}
+	}
+	parts := splitMessage(strings.TrimSpace(msg.Text), telegramMaxLength, telegramFormat.convert)
+	// a chat failing must not keep the others from being notified
+	var errs []string
+	for _, chat := range chats {
+		for _, part := range parts {
+			if err := n.send(chat, part); err != nil {
+				errs = append(errs, err.Error())
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot notify %d of %d Telegram chats: %s", len(errs), len(chats), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// send posts given MarkdownV2 text to chat with given ID.
+func (n *telegramNotifier) send(chat, text string) error {
+	b, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  chat,
+		"text":                     text,
+		"parse_mode":               "MarkdownV2",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot encode payload: %s", err)
+	}
+	resp, err := httpClient.Post(telegramAPIURL+n.token+"/sendMessage", "application/json", bytes.NewReader(b))
+	if err != nil {
+		// the error includes the URL with the token
+		return fmt.Errorf("cannot POST to Telegram: %s", redact(err.Error()))
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("cannot read response: %s", err)
+	}
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("cannot decode response: %s", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("sendMessage to %s failed: %s", chat, result.Description)
+	}
+	return nil
+}
+
+// splitMessage converts text with given function and splits it into parts
+// of at most given number of characters, so that long digests fit message
+// size limits. Text is split at line breaks, and lines too long on their own
+// at character boundaries, before converting, so that no escape sequence or
+// link is cut in half.
+func splitMessage(text string, max int, convert func(string) string) []string {
+	fits := func(s string) bool {
+		return utf8.RuneCountInString(convert(s)) <= max
+	}
+	var parts []string
+	current := ""
+	for _, line := range strings.Split(text, "\n") {
+		candidate := line
+		if current != "" {
+			candidate = current + "\n" + line
+		}
+		if fits(candidate) {
+			current = candidate
+			continue
+		}
+		if current != "" {
+			parts = append(parts, convert(current))
+		}
+		runes := []rune(line)
+		for !fits(string(runes)) {
+			// longest prefix that still fits
+			n := sort.Search(len(runes), func(i int) bool {
+				return !fits(string(runes[:i+1]))
+			})
+			if n == 0 {
+				n = 1
+			}
+			parts = append(parts, convert(string(runes[:n])))
+			runes = runes[n:]
+		}
+		current = string(runes)
+	}
+	if current != "" || len(parts) == 0 {
+		parts = append(parts, convert(current))
+	}
+	return parts
+}
+
+// parseChats returns chat IDs given as comma separated list.
+func parseChats(s string) []string {
+	var chats []string
+	for _, chat := range strings.Split(s, ",") {
+		if chat = strings.TrimSpace(chat); chat != "" {
+			chats = append(chats, chat)
+		}
+	}
+	return chats
+}
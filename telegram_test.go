@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	same := func(s string) string { return s }
+	escape := strings.NewReplacer(".", `\.`).Replace
+	tests := []struct {
+		text    string
+		max     int
+		convert func(string) string
+		want    []string
+	}{
+		{text: "", max: 10, convert: same, want: []string{""}},
+		{text: "a\nb", max: 10, convert: same, want: []string{"a\nb"}},
+		{text: "aaaa\nbbbb", max: 5, convert: same, want: []string{"aaaa", "bbbb"}},
+		{text: "aa\nbb\ncc", max: 5, convert: same, want: []string{"aa\nbb", "cc"}},
+		{text: "abcdefgh", max: 3, convert: same, want: []string{"abc", "def", "gh"}},
+		{text: "äöüß", max: 2, convert: same, want: []string{"äö", "üß"}},
+		// escape sequences are never cut in half
+		{text: "a.b.c", max: 4, convert: escape, want: []string{`a\.b`, `\.c`}},
+	}
+	for _, tt := range tests {
+		if got := splitMessage(tt.text, tt.max, tt.convert); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitMessage(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Tenant is an independent team served by the same daemon, with its own
+// credentials, configuration, state and schedule.
+type Tenant struct {
+	// Name identifies the tenant in logs and prefixes its state keys.
+	Name string `json:"name"`
+	// Flags maps flag names to values overriding the daemon's flags
+	// during scans of the tenant, for example "organization" or
+	// "slack-token". Secret references are resolved.
+	Flags map[string]string `json:"flags"`
+	// Config is the path to the tenant's configuration file.
+	Config string `json:"config"`
+	// Interval is the time between scans of the tenant, -interval by
+	// default.
+	Interval Duration `json:"interval"`
+}
+
+// prefixStore keeps documents in another store under prefixed keys.
+type prefixStore struct {
+	store  Store
+	prefix string
+}
+
+func (s *prefixStore) Get(key string) ([]byte, error) {
+	return s.store.Get(s.prefix + key)
+}
+
+func (s *prefixStore) Set(key string, value []byte) error {
+	return s.store.Set(s.prefix+key, value)
+}
+
+func (s *prefixStore) SetMany(docs map[string][]byte) error {
+	prefixed := make(map[string][]byte, len(docs))
+	for key, value := range docs {
+		prefixed[s.prefix+key] = value
+	}
+	return setMany(s.store, prefixed)
+}
+
+// tenantScope returns prefix unique to the tenant being scanned, empty for
+// the daemon's own team. Caches of data of a team are keyed by it.
+func tenantScope() string {
+	if p, ok := store.(*prefixStore); ok {
+		return p.prefix
+	}
+	return ""
+}
+
+// daemonFlags are flags of the HTTP server, shared by all tenants. Requests
+// are verified with the daemon's own values, so tenants cannot override
+// them.
+var daemonFlags = []string{"listen", "api-token", "public-dashboard", "slack-signing-secret", "github-webhook-secret"}
+
+// checkTenants verifies names and flags of the configured tenants.
+func checkTenants() error {
+	names := map[string]bool{}
+	for _, t := range config.Tenants {
+		if t.Name == "" {
+			return errors.New("tenant without name")
+		}
+		if names[t.Name] {
+			return fmt.Errorf("duplicate tenant %q", t.Name)
+		}
+		names[t.Name] = true
+		for name := range t.Flags {
+			if flag.Lookup(name) == nil {
+				return fmt.Errorf("tenant %s: unknown flag -%s", t.Name, name)
+			}
+			for _, daemon := range daemonFlags {
+				if name == daemon {
+					return fmt.Errorf("tenant %s: -%s is shared by all tenants", t.Name, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scheduleTenants starts scans of every tenant at its own interval. Tenants
+// without interval are only scanned on demand.
+func scheduleTenants() {
+	for _, t := range config.Tenants {
+		interval := time.Duration(t.Interval)
+		if interval == 0 {
+			interval = *intervalFl
+		}
+		if interval <= 0 {
+			continue
+		}
+		go func(t Tenant) {
+			for {
+				if err := runTenantScan(t); err != nil {
+					log.Printf("scan of tenant %s failed: %s", t.Name, err)
+				}
+				if !sleepUnlessShutdown(interval) {
+					return
+				}
+			}
+		}(t)
+	}
+}
+
+// globalsMu is held by tenant scans, which swap in globals of the tenant,
+// and by configuration reloads, and read locked by background work of HTTP
+// requests, so that it never sees globals of a tenant nor a configuration
+// being replaced. Handlers themselves do not lock it, they answer from
+// daemonAuth and the last scanView. Scans are serialized with both by
+// scanMu. It is locked before scanMu.
+var globalsMu sync.RWMutex
+
+// runTenantScan runs scan with flags, configuration and state of given
+// tenant, restoring the daemon's own afterwards.
+func runTenantScan(t Tenant) error {
+	globalsMu.Lock()
+	defer globalsMu.Unlock()
+	scanMu.Lock()
+	defer scanMu.Unlock()
+	if shuttingDown() {
+		return errShuttingDown
+	}
+
+	c, err := readConfig(t.Config)
+	if err != nil {
+		return err
+	}
+	previous := map[string]string{}
+	defer func() {
+		for name, value := range previous {
+			flag.Lookup(name).Value.Set(value)
+		}
+	}()
+	for name, value := range t.Flags {
+		f := flag.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("unknown flag -%s", name)
+		}
+		resolved, _, err := resolveSecret(value)
+		if err != nil {
+			return fmt.Errorf("-%s: %s", name, err)
+		}
+		previous[name] = f.Value.String()
+		if err := f.Value.Set(resolved); err != nil {
+			return fmt.Errorf("invalid -%s value: %s", name, err)
+		}
+	}
+
+	// preflight may disable features of the tenant by changing any flag
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, ok := previous[f.Name]; !ok {
+			previous[f.Name] = f.Value.String()
+		}
+	})
+
+	savedConfig, savedStore, savedForge, savedPagers, savedNotifiers, savedDestination := config, store, forge, pagers, notifiers, reportDestination
+	defer func() {
+		config, store, forge, pagers, notifiers, reportDestination = savedConfig, savedStore, savedForge, savedPagers, savedNotifiers, savedDestination
+		if err := loadHolidays(); err != nil {
+			log.Printf("cannot load holidays: %s", err)
+		}
+		resetLocations()
+	}()
+	config = c
+	store = &prefixStore{store: savedStore, prefix: "tenant/" + t.Name + "/"}
+	if forge, err = newProvider(*providerFl); err != nil {
+		return fmt.Errorf("cannot create provider: %s", err)
+	}
+	pagers = newPagers()
+	notifiers = newNotifiers()
+	if err := loadHolidays(); err != nil {
+		return fmt.Errorf("cannot load holidays: %s", err)
+	}
+	resetLocations()
+	// the tenant's token may lack grants the daemon's own has
+	preflight()
+
+	log.Printf("scanning tenant %s", t.Name)
+	return measuredScan()
+}
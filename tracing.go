@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds as defined by OpenTelemetry.
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+)
+
+// span is a single OpenTelemetry span. Nil span is valid and does nothing,
+// which is what startSpan returns when tracing is disabled.
+type span struct {
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    map[string]interface{}
+	err      string
+}
+
+var (
+	tracingMu sync.Mutex
+	// finishedSpans are spans waiting to be exported.
+	finishedSpans []*span
+	// scanSpan is the root span of the running scan.
+	scanSpan *span
+	// otlpClient sends spans without tracing itself.
+	otlpClient = &http.Client{Transport: http.DefaultTransport, Timeout: 30 * time.Second}
+)
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startSpan starts span with given name as a child of given parent, or a new
+// trace if parent is nil.
+func startSpan(parent *span, name string, kind int) *span {
+	if *otlpEndpointFl == "" {
+		return nil
+	}
+	s := &span{
+		traceID: randomHex(16),
+		spanID:  randomHex(8),
+		name:    name,
+		kind:    kind,
+		start:   time.Now(),
+		attrs:   map[string]interface{}{},
+	}
+	if parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	}
+	return s
+}
+
+// setAttr sets attribute of the span.
+func (s *span) setAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.attrs[key] = value
+}
+
+// finish ends the span, marking it as failed if err is not nil, and queues
+// it for export.
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	tracingMu.Lock()
+	finishedSpans = append(finishedSpans, s)
+	tracingMu.Unlock()
+}
+
+// traceScan runs fn within the root span of a scan, and exports all
+// finished spans when it returns.
+func traceScan(fn func() error) error {
+	s := startSpan(nil, "scan", spanKindInternal)
+	tracingMu.Lock()
+	scanSpan = s
+	tracingMu.Unlock()
+
+	err := fn()
+
+	s.finish(err)
+	tracingMu.Lock()
+	scanSpan = nil
+	tracingMu.Unlock()
+	if s != nil {
+		flushSpans()
+	}
+	return err
+}
+
+// currentScanSpan returns root span of the running scan.
+func currentScanSpan() *span {
+	tracingMu.Lock()
+	defer tracingMu.Unlock()
+	return scanSpan
+}
+
+// tracingTransport records a client span for every HTTP request. Requests
+// are children of the running scan span.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s := startSpan(currentScanSpan(), req.Method+" "+req.URL.Host, spanKindClient)
+	s.setAttr("http.request.method", req.Method)
+	s.setAttr("url.full", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+	s.setAttr("server.address", req.URL.Host)
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		s.setAttr("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			err = fmt.Errorf("unexpected response: %d", resp.StatusCode)
+		}
+		s.finish(err)
+		return resp, nil
+	}
+	s.finish(err)
+	return resp, err
+}
+
+// setupTracing enables HTTP instrumentation if an OTLP endpoint is set.
+func setupTracing() {
+	if *otlpEndpointFl == "" {
+		return
+	}
+	otlpClient.Transport = http.DefaultTransport
+	http.DefaultTransport = &tracingTransport{base: http.DefaultTransport}
+}
+
+// otlpValue encodes attribute value as OTLP AnyValue.
+func otlpValue(v interface{}) map[string]interface{} {
+	switch v := v.(type) {
+	case int:
+		return map[string]interface{}{"intValue": fmt.Sprint(v)}
+	case int64:
+		return map[string]interface{}{"intValue": fmt.Sprint(v)}
+	case bool:
+		return map[string]interface{}{"boolValue": v}
+	}
+	return map[string]interface{}{"stringValue": fmt.Sprint(v)}
+}
+
+func otlpAttributes(attrs map[string]interface{}) []interface{} {
+	list := []interface{}{}
+	for k, v := range attrs {
+		list = append(list, map[string]interface{}{"key": k, "value": otlpValue(v)})
+	}
+	return list
+}
+
+// flushSpans exports all finished spans using OTLP over HTTP with JSON
+// encoding. Errors are logged and the spans are dropped.
+func flushSpans() {
+	tracingMu.Lock()
+	spans := finishedSpans
+	finishedSpans = nil
+	tracingMu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	list := make([]interface{}, 0, len(spans))
+	for _, s := range spans {
+		status := map[string]interface{}{"code": 1}
+		if s.err != "" {
+			status = map[string]interface{}{"code": 2, "message": s.err}
+		}
+		list = append(list, map[string]interface{}{
+			"traceId":           s.traceID,
+			"spanId":            s.spanID,
+			"parentSpanId":      s.parentID,
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": fmt.Sprint(s.start.UnixNano()),
+			"endTimeUnixNano":   fmt.Sprint(s.end.UnixNano()),
+			"attributes":        otlpAttributes(s.attrs),
+			"status":            status,
+		})
+	}
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{
+						"service.name": *otlpServiceFl,
+					}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "github-stale-pr-bot"},
+						"spans": list,
+					},
+				},
+			},
+		},
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("cannot encode spans: %s", err)
+		return
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(*otlpEndpointFl, "/")+"/v1/traces", bytes.NewReader(b))
+	if err != nil {
+		log.Printf("cannot create POST request: %s", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, kv := range strings.Split(*otlpHeadersFl, ",") {
+		if i := strings.Index(kv, "="); i > 0 {
+			req.Header.Set(strings.TrimSpace(kv[:i]), strings.TrimSpace(kv[i+1:]))
+		}
+	}
+	resp, err := otlpClient.Do(req)
+	if err != nil {
+		log.Printf("cannot export spans: %s", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("cannot export spans: %d, %s", resp.StatusCode, body)
+	}
+}
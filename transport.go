@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// httpClient is the client of all outbound requests.
+var httpClient = http.DefaultClient
+
+// userAgentTransport sets User-Agent header of requests that have none.
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		// round trippers must not modify the request
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", userAgent())
+	}
+	return t.base.RoundTrip(req)
+}
+
+// userAgent returns User-Agent header value of outbound requests.
+func userAgent() string {
+	return "github-stale-pr-bot/" + version
+}
+
+// setupHTTP configures the default transport and the shared client. Client
+// uses the default transport, so that it includes instrumentation added
+// later. Exchanges are recorded or replayed according to -record and
+// -replay, github API calls are budgeted, and exchanges are logged with
+// -debug-http.
+func setupHTTP() error {
+	t, err := newTransport()
+	if err != nil {
+		return err
+	}
+	rt, err := wrapRecording(t)
+	if err != nil {
+		return err
+	}
+	rt = &errorCountingTransport{base: rt}
+	if rt, err = newBudgetTransport(rt); err != nil {
+		return err
+	}
+	if *debugHTTPFl {
+		rt = &debugTransport{base: rt, body: *debugHTTPBodyFl}
+	}
+	http.DefaultTransport = &userAgentTransport{base: rt}
+	httpClient = &http.Client{Timeout: *httpTimeoutFl}
+	return nil
+}
+
+// tlsVersions maps -tls-min-version values to TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTransport returns HTTP transport configured by proxy and TLS flags.
+// Without -proxy, HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables
+// are used.
+func newTransport() (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   *connectTimeoutFl,
+		KeepAlive: *keepAliveFl,
+	}
+	t.DialContext = dialer.DialContext
+	t.TLSHandshakeTimeout = *connectTimeoutFl
+	t.DisableKeepAlives = *keepAliveFl < 0
+	if *proxyFl != "" {
+		u, err := url.Parse(*proxyFl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %s", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	version, ok := tlsVersions[*tlsMinVersionFl]
+	if !ok {
+		return nil, fmt.Errorf("invalid TLS version %q, expected one of: 1.0, 1.1, 1.2, 1.3", *tlsMinVersionFl)
+	}
+	t.TLSClientConfig = &tls.Config{MinVersion: version}
+
+	if *caBundleFl != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(*caBundleFl)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA bundle: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", *caBundleFl)
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+
+	if *clientCertFl != "" || *clientKeyFl != "" {
+		cert, err := tls.LoadX509KeyPair(*clientCertFl, *clientKeyFl)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %s", err)
+		}
+		t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	return t, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Vacation is a period of absence of a member. Both dates are inclusive and
+// in "2006-01-02" format, evaluated in member's timezone.
+type Vacation struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// onVacation returns true if member with given login is on vacation at
+// given time.
+func onVacation(login string, now time.Time) bool {
+	vacations := config.Members[login].Vacations
+	if len(vacations) == 0 {
+		return false
+	}
+	today := now.In(memberLocation(login)).Format("2006-01-02")
+	for _, v := range vacations {
+		// dates in this format compare correctly as strings
+		if v.From <= today && today <= v.To {
+			return true
+		}
+	}
+	return false
+}
+
+// knownMembersKey is the state store key of logins of everybody ever
+// listed as a team member.
+const knownMembersKey = "members/known"
+
+var knownMembersMu sync.Mutex
+
+// rememberMembers adds given members to the known members.
+func rememberMembers(members []User) {
+	knownMembersMu.Lock()
+	defer knownMembersMu.Unlock()
+	known := map[string]bool{}
+	if _, err := loadDocument(knownMembersKey, &known); err != nil {
+		log.Printf("cannot load known members: %s", err)
+		return
+	}
+	changed := false
+	for _, m := range members {
+		if !known[m.Login] {
+			known[m.Login] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := saveDocument(knownMembersKey, known); err != nil {
+		log.Printf("cannot save known members: %s", err)
+	}
+}
+
+// wasMember returns true if given login was listed as a team member
+// before.
+func wasMember(login string) bool {
+	knownMembersMu.Lock()
+	defer knownMembersMu.Unlock()
+	known := map[string]bool{}
+	if _, err := loadDocument(knownMembersKey, &known); err != nil {
+		log.Printf("cannot load known members: %s", err)
+		return false
+	}
+	return known[login]
+}
+
+// unavailableReason returns ID of the message telling why member with given
+// login cannot keep pull requests assigned, or empty string if the member
+// is available. Only
+// people who were team members before count as having left, others, like
+// experts, pool members and triagers, were assigned on purpose.
+func unavailableReason(login string, now time.Time) string {
+	if onVacation(login, now) {
+		return "on_vacation"
+	}
+	members, err := listMembers()
+	if err != nil {
+		log.Printf("cannot list members: %s", err)
+		return ""
+	}
+	for _, m := range members {
+		if m.Login == login {
+			return ""
+		}
+	}
+	if !wasMember(login) {
+		return ""
+	}
+	return "left_team"
+}
+
+// checkVacations verifies dates of vacations of given members.
+func checkVacations(members map[string]Member) error {
+	for login, m := range members {
+		for _, v := range m.Vacations {
+			from, err := time.Parse("2006-01-02", v.From)
+			if err != nil {
+				return fmt.Errorf("invalid vacation start %q of %s, expected 2006-01-02", v.From, login)
+			}
+			to, err := time.Parse("2006-01-02", v.To)
+			if err != nil {
+				return fmt.Errorf("invalid vacation end %q of %s, expected 2006-01-02", v.To, login)
+			}
+			if to.Before(from) {
+				return fmt.Errorf("vacation of %s ends %s before it starts %s", login, v.To, v.From)
+			}
+		}
+	}
+	return nil
+}
+
+// reassignUnavailable assigns different member to given pull request if its
+// assignee is on vacation or left the team, explaining why in a comment.
+// It returns true if the pull request was reassigned.
+func reassignUnavailable(issue *Issue, now time.Time) bool {
+	previous := issue.Assignee.Login
+	id := unavailableReason(previous, now)
+	if id == "" {
+		return false
+	}
+	reason := tr(issue, id)
+	user, err := pickReviewer(issue)
+	if err != nil {
+		log.Printf("cannot pick user for %d: %s", issue.ID, err)
+		return false
+	}
+	if err := forge.Assign(issue, &user); err != nil {
+		log.Printf("cannot assign %q to %d: %s", user.Login, issue.ID, err)
+		releaseAssignment(user.Login)
+		return false
+	}
+	log.Printf("Reassigned PR #%d from %s, who %s, to %s", issue.Number, previous, reason, user.Login)
+	comment := tr(issue, "vacation_comment", previous, reason, user.Login)
+	if err := forge.Comment(issue, comment); err != nil {
+		log.Printf("cannot comment on #%d pull request: %s", issue.Number, err)
+	}
+	issue.Assignee = &user
+	return true
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// repoScopes are OAuth scopes needed to work with pull requests, any of
+// which is sufficient.
+var repoScopes = []string{"repo", "public_repo"}
+
+// orgScopes are OAuth scopes needed to list members of organization teams,
+// any of which is sufficient.
+var orgScopes = []string{"read:org", "write:org", "admin:org"}
+
+// requiredScopes returns OAuth scopes the token needs. Each entry is a list
+// of alternatives, any of which is sufficient. Personal accounts have no
+// teams, so they need no organization scope.
+func requiredScopes() [][]string {
+	if userAccount() {
+		return [][]string{repoScopes}
+	}
+	return [][]string{repoScopes, orgScopes}
+}
+
+// checkGithubScopes verifies that the OAuth token has all required scopes.
+// Tokens that do not report scopes, like fine-grained tokens and App
+// installation tokens, are not checked.
+func checkGithubScopes() error {
+	if err := githubOnly(); err != nil {
+		return nil
+	}
+	granted, err := oauthScopes()
+	if err != nil || granted == nil {
+		return err
+	}
+	var missing []string
+	for _, alternatives := range requiredScopes() {
+		found := false
+		for _, scope := range alternatives {
+			if granted[scope] {
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, alternatives[0])
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("token is missing scopes: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkOrganization verifies that the organization is reachable.
+func checkOrganization() error {
+	if err := githubOnly(); err != nil {
+		return nil
+	}
+	url := fmt.Sprintf("%s/orgs/%s", *ghAPIFl, *ghOrgFl)
+	if userAccount() {
+		url = fmt.Sprintf("%s/users/%s", *ghAPIFl, *ghOrgFl)
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		if userAccount() {
+			return fmt.Errorf("account %q not found", *ghOrgFl)
+		}
+		return fmt.Errorf("organization %q not found", *ghOrgFl)
+	}
+	return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+}
+
+// checkFlags verifies values of flags that accept a fixed set of values.
+func checkFlags() error {
+	enums := []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{"provider", *providerFl, []string{"github", "gitlab", "gitea", "bitbucket"}},
+		{"fetcher", *fetcherFl, []string{"rest", "graphql"}},
+		{"strategy", *strategyFl, []string{"round-robin", "expertise", "blame"}},
+		{"assign-as", *assignAsFl, []string{"assignee", "reviewer", "both"}},
+		{"critical-mention", *criticalMentionFl, []string{"here", "channel", "none"}},
+		{"stale-from", *staleFromFl, []string{"created", "activity"}},
+		{"visibility", *visibilityFl, []string{"all", "public", "private"}},
+		{"owner-type", *ownerTypeFl, []string{"org", "user"}},
+		{"teammates", *teammatesFl, []string{"any", "avoid", "prefer"}},
+	}
+	for _, e := range enums {
+		ok := false
+		for _, a := range e.allowed {
+			if e.value == a {
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("invalid -%s value %q, expected one of: %s", e.name, e.value, strings.Join(e.allowed, ", "))
+		}
+	}
+	if err := checkQuietHours(); err != nil {
+		return err
+	}
+	if *remindAtFl != "" {
+		if _, err := parseClock(*remindAtFl); err != nil {
+			return err
+		}
+	}
+	if _, err := regexp.Compile(*requireLinkFl); err != nil {
+		return fmt.Errorf("invalid -require-link: %s", err)
+	}
+	if err := checkLocale(*localeFl); err != nil {
+		return err
+	}
+	if _, err := parseQuotas(*apiQuotasFl); err != nil {
+		return err
+	}
+	if *maxAPICallsFl < 0 || *apiReserveFl < 0 {
+		return fmt.Errorf("-max-api-calls-per-run and -api-reserve cannot be negative")
+	}
+	if _, err := parseSwitches(*commentFl, commentDefaults); err != nil {
+		return fmt.Errorf("invalid -comment: %s", err)
+	}
+	if _, err := parseSwitches(*notifyFl, notifyDefaults); err != nil {
+		return fmt.Errorf("invalid -notify: %s", err)
+	}
+	if *rampUpCapacityFl <= 0 || *rampUpCapacityFl > 1 {
+		return fmt.Errorf("-ramp-up-capacity must be greater than 0 and at most 1")
+	}
+	if jiraEnabled() && strings.TrimSpace(*jiraProjectsFl) == "" {
+		return fmt.Errorf("-jira-url requires -jira-projects")
+	}
+	if *requiredApprovalsFl && *providerFl != "github" && *providerFl != "gitlab" {
+		return fmt.Errorf("-required-approvals is not supported with -provider=%s", *providerFl)
+	}
+	if *workersFl < 1 {
+		return fmt.Errorf("-workers must be at least 1")
+	}
+	if *reviewersPerPRFl < 1 {
+		return fmt.Errorf("-reviewers-per-pr must be at least 1")
+	}
+	if *checkRunFl && !appEnabled() {
+		return fmt.Errorf("-check-run requires github App authentication")
+	}
+	if userAccount() && *collaboratorsFl == "" {
+		return fmt.Errorf("-owner-type=user requires -collaborators")
+	}
+	if (userAccount() || *reposFl != "") && *fetcherFl == "graphql" {
+		return fmt.Errorf("-fetcher=graphql requires an organization without -repos")
+	}
+	if *managerLongestFl < 0 {
+		return fmt.Errorf("-manager-longest must not be negative")
+	}
+	if *managerChannelsFl != "" && (*slackTokenFl == "" || !*batchRemindersFl) {
+		return fmt.Errorf("-manager-channels requires -slack-token and -batch-reminders")
+	}
+	if *summaryChannelFl != "" && *slackTokenFl == "" {
+		return fmt.Errorf("-summary-channel requires -slack-token")
+	}
+	if *telegramTokenFl != "" && *telegramChatsFl == "" {
+		return fmt.Errorf("-telegram-token requires -telegram-chats")
+	}
+	if *mattermostTokenFl != "" && (*mattermostURLFl == "" || *mattermostChannelFl == "") {
+		return fmt.Errorf("-mattermost-token requires -mattermost-url and -mattermost-channel")
+	}
+	return nil
+}
+
+// oauthScopes returns OAuth scopes granted to the token, nil if the token
+// does not report scopes.
+func oauthScopes() (map[string]bool, error) {
+	req, err := http.NewRequest("GET", *ghAPIFl+"/user", nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GET request: %s", err)
+	}
+	addAuthentication(req)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot do request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("credentials not accepted: %d", resp.StatusCode)
+	}
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return nil, nil
+	}
+	granted := map[string]bool{}
+	for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+		granted[strings.TrimSpace(scope)] = true
+	}
+	return granted, nil
+}
+
+// validationChecks are the checks run by the validate subcommand and on
+// startup.
+var validationChecks = []check{
+	{"flags", checkFlags},
+	{"github token", checkGithubScopes},
+	{"organization", checkOrganization},
+	{"team", checkTeam},
+	{"slack", checkSlack},
+}
+
+// validate runs all validation checks, logs their results and returns
+// false if any of them failed.
+func validate() bool {
+	ok := true
+	for _, c := range validationChecks {
+		if err := c.Fn(); err != nil {
+			log.Printf("%s: FAILED: %s", c.Name, err)
+			ok = false
+		} else {
+			log.Printf("%s: ok", c.Name)
+		}
+	}
+	return ok
+}
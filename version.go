@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// Build information, set at build time with
+// -ldflags "-X main.version=<version> -X main.commit=<sha> -X main.buildDate=<date>".
+// Commit and date fall back to version control information embedded by the
+// go tool.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// releasesURL is the github API endpoint of the latest release of the bot.
+const releasesURL = "https://api.github.com/repos/optiopay/github-stale-pr-bot/releases/latest"
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if commit == "" {
+				commit = s.Value
+			}
+		case "vcs.time":
+			if buildDate == "" {
+				buildDate = s.Value
+			}
+		}
+	}
+}
+
+// versionString returns human readable version with build information.
+func versionString() string {
+	s := version
+	if commit != "" {
+		short := commit
+		if len(short) > 12 {
+			short = short[:12]
+		}
+		s += " (" + short
+		if buildDate != "" {
+			s += ", built " + buildDate
+		}
+		s += ")"
+	}
+	return s
+}
+
+// checkUpdate logs if a newer release of the bot is available. Development
+// builds are never checked.
+func checkUpdate() {
+	if version == "dev" {
+		return
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	// the public API is used regardless of -github-api, without
+	// credentials that may belong to a different github instance
+	resp, err := httpClient.Get(releasesURL)
+	if err != nil {
+		log.Printf("cannot check for updates: %s", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("cannot check for updates: unexpected response: %d", resp.StatusCode)
+		return
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		log.Printf("cannot check for updates: cannot decode response: %s", err)
+		return
+	}
+	if newerVersion(release.TagName, version) {
+		log.Printf("version %s is available, running %s: %s", release.TagName, version, release.HTMLURL)
+	}
+}
+
+// newerVersion returns true if semantic version a is newer than b. Leading
+// "v" is ignored.
+func newerVersion(a, b string) bool {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		var na, nb int
+		fmt.Sscanf(pa[i], "%d", &na)
+		fmt.Sscanf(pb[i], "%d", &nb)
+		if na != nb {
+			return na > nb
+		}
+	}
+	return len(pa) > len(pb)
+}
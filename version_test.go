@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "v1.2.0", b: "v1.1.9", want: true},
+		{a: "1.10.0", b: "v1.9.0", want: true},
+		{a: "v2.0.0", b: "v1.99.99", want: true},
+		{a: "v1.2.1", b: "v1.2", want: true},
+		{a: "v1.2.0", b: "v1.2.0", want: false},
+		{a: "v1.2", b: "v1.2.1", want: false},
+		{a: "v1.1.9", b: "v1.2.0", want: false},
+	}
+	for _, tt := range tests {
+		if got := newerVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("newerVersion(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookPayload is the body posted to outbound webhooks.
+type webhookPayload struct {
+	Event       string    `json:"event"`
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	PullRequest struct {
+		Repo   string `json:"repo"`
+		Number int    `json:"number"`
+		URL    string `json:"url,omitempty"`
+		Title  string `json:"title,omitempty"`
+		Author string `json:"author,omitempty"`
+	} `json:"pull_request"`
+	Assignee string `json:"assignee,omitempty"`
+	// Age is the age of the pull request in seconds.
+	Age int64 `json:"age,omitempty"`
+}
+
+// webhookPublisher posts events to an URL. When secret is set, body is
+// signed with HMAC-SHA256 and the signature is sent in X-Signature-256
+// header as "sha256=<hex>", same as github does for its webhooks.
+type webhookPublisher struct {
+	url    string
+	secret string
+}
+
+func (p *webhookPublisher) Publish(e *Event) error {
+	var payload webhookPayload
+	payload.Event = string(e.Data.Phase)
+	payload.ID = e.ID
+	payload.Time = e.Time
+	payload.PullRequest.Repo = e.Data.Repo
+	payload.PullRequest.Number = e.Data.Number
+	payload.PullRequest.URL = e.Data.URL
+	payload.PullRequest.Title = e.Data.Title
+	payload.PullRequest.Author = e.Data.Author
+	payload.Assignee = e.Data.Assignee
+	payload.Age = e.Data.Age
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot encode payload: %s", err)
+	}
+	req, err := http.NewRequest("POST", p.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("cannot create POST request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event", payload.Event)
+	if p.secret != "" {
+		mac := hmac.New(sha256.New, []byte(p.secret))
+		mac.Write(b)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot do request: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+	return nil
+}